@@ -0,0 +1,89 @@
+package errors
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// failedCountKey holds the number of failed items of an aggregate error.
+	failedCountKey = "failed_count"
+	// totalCountKey holds the total number of items of an aggregate error.
+	totalCountKey = "total_count"
+)
+
+// codeSeverity ranks the gRPC codes from the least severe to the most severe,
+// to pick the worst code of the errors of an aggregate. Codes not listed rank lowest.
+var codeSeverity = map[codes.Code]int{
+	codes.Canceled:           1,
+	codes.NotFound:           2,
+	codes.AlreadyExists:      3,
+	codes.InvalidArgument:    4,
+	codes.OutOfRange:         5,
+	codes.FailedPrecondition: 6,
+	codes.Aborted:            7,
+	codes.Unauthenticated:    8,
+	codes.PermissionDenied:   9,
+	codes.ResourceExhausted:  10,
+	codes.DeadlineExceeded:   11,
+	codes.Unavailable:        12,
+	codes.Unimplemented:      13,
+	codes.Unknown:            14,
+	codes.Internal:           15,
+	codes.DataLoss:           16,
+}
+
+// aggregateError joins the errors of the failed items of a batch.
+type aggregateError struct {
+	msg  string
+	errs []error
+}
+
+func (a *aggregateError) Error() string {
+	return a.msg
+}
+
+// Unwrap returns the errors of the failed items.
+func (a *aggregateError) Unwrap() []error {
+	return a.errs
+}
+
+// Aggregate returns a single error summarizing a batch of total items, of which errs failed.
+// Its message reads like "3 of 10 items failed", it carries the failed_count and total_count metadata,
+// and its code is the worst code among the errors, the errors carrying no code counting as codes.Internal,
+// as they are unexpected failures, so a client error never hides a failure of the infrastructure.
+// The errors are joined, so they can be matched with errors.Is and errors.As,
+// and their metadata is part of GetMetadata, the later errors taking precedence.
+// Nil errors are skipped, and a nil error is returned if no error is left.
+// The total is raised to the number of errors if it's lower.
+func Aggregate(total int, errs []error) error {
+	var failed []error
+	var code codes.Code
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		c, ok := explicitCode(err)
+		if !ok {
+			c = codes.Internal
+		}
+		if len(failed) == 0 || codeSeverity[c] > codeSeverity[code] {
+			code = c
+		}
+		failed = append(failed, err)
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	total = max(total, len(failed))
+	return newErrWithMetadata(errWithMetadata{
+		err: &aggregateError{
+			msg:  fmt.Sprintf("%d of %d items failed", len(failed), total),
+			errs: failed,
+		},
+		metadata: []any{failedCountKey, len(failed), totalCountKey, total},
+		code:     code,
+		hasCode:  true,
+	})
+}
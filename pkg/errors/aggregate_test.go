@@ -0,0 +1,100 @@
+package errors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAggregate(t *testing.T) {
+	testCases := []struct {
+		name            string
+		total           int
+		errs            []error
+		expectedMessage string
+		expectedCode    codes.Code
+	}{
+		{
+			name:            "errors without code",
+			total:           10,
+			errs:            []error{errors.New("foo"), nil, errors.New("bar")},
+			expectedMessage: "2 of 10 items failed",
+			expectedCode:    codes.Internal,
+		},
+		{
+			name:  "worst code wins",
+			total: 3,
+			errs: []error{
+				status.Error(codes.NotFound, "not found"),
+				WithCode(errors.New("foo"), codes.Unavailable),
+				status.Error(codes.InvalidArgument, "invalid"),
+			},
+			expectedMessage: "3 of 3 items failed",
+			expectedCode:    codes.Unavailable,
+		},
+		{
+			name:            "single code",
+			total:           5,
+			errs:            []error{status.Error(codes.NotFound, "not found"), nil},
+			expectedMessage: "1 of 5 items failed",
+			expectedCode:    codes.NotFound,
+		},
+		{
+			name:            "errors without code count as internal",
+			total:           2,
+			errs:            []error{WithCode(errors.New("foo"), codes.NotFound), errors.New("db down")},
+			expectedMessage: "2 of 2 items failed",
+			expectedCode:    codes.Internal,
+		},
+		{
+			name:            "worse code than internal",
+			total:           2,
+			errs:            []error{errors.New("db down"), status.Error(codes.DataLoss, "corrupted")},
+			expectedMessage: "2 of 2 items failed",
+			expectedCode:    codes.DataLoss,
+		},
+		{
+			name:            "total lower than the number of errors",
+			total:           0,
+			errs:            []error{errors.New("foo")},
+			expectedMessage: "1 of 1 items failed",
+			expectedCode:    codes.Internal,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Aggregate(tc.total, tc.errs)
+			require.EqualError(t, err, tc.expectedMessage)
+			require.Equal(t, tc.expectedCode, CodeOf(err))
+			st, ok := status.FromError(err)
+			require.True(t, ok)
+			require.Equal(t, tc.expectedCode, st.Code())
+			require.Equal(t, tc.expectedMessage, st.Message())
+		})
+	}
+}
+
+func TestAggregateNil(t *testing.T) {
+	require.NoError(t, Aggregate(10, nil))
+	require.NoError(t, Aggregate(10, []error{nil, nil}))
+}
+
+func TestAggregateChildren(t *testing.T) {
+	sentinel := errors.New("sentinel")
+	err := Aggregate(10, []error{
+		WithMetadata(errors.New("foo"), "item", 1, "foo_key", "foo_value"),
+		WithMetadata(sentinel, "item", 2),
+	})
+
+	require.ErrorIs(t, err, sentinel)
+	require.Equal(t, map[string]any{
+		"item":         2,
+		"foo_key":      "foo_value",
+		"failed_count": 2,
+		"total_count":  10,
+	}, GetMetadataMap(err))
+	require.Equal(t, []any{1, 2}, GetMetadataValues(err, "item"))
+}
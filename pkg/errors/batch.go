@@ -0,0 +1,49 @@
+package errors
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// GetMetadataAll returns the metadata of each error of the slice, as returned by GetMetadataMap,
+// at the same index. The map of a nil error is nil.
+func GetMetadataAll(errs []error) []map[string]any {
+	all := make([]map[string]any, len(errs))
+	for i, err := range errs {
+		if err != nil {
+			all[i] = GetMetadataMap(err)
+		}
+	}
+	return all
+}
+
+// GetMetadataMerged returns the metadata of all the errors of the slice merged into a single map,
+// e.g. to log the failures of a batch compactly. A key whose value is the same for all the errors carrying it,
+// compared with reflect.DeepEqual, is kept as is, while the values of a conflicting key are namespaced
+// by the index of their error in the slice, e.g. "0.collection" and "2.collection". Nil errors are skipped.
+// It returns an empty map if no error carries metadata.
+func GetMetadataMerged(errs []error) map[string]any {
+	all := GetMetadataAll(errs)
+	conflicting := make(map[string]bool)
+	merged := make(map[string]any)
+	for _, metadata := range all {
+		for key, value := range metadata {
+			if existing, ok := merged[key]; ok && !reflect.DeepEqual(existing, value) {
+				conflicting[key] = true
+				continue
+			}
+			merged[key] = value
+		}
+	}
+	for key := range conflicting {
+		delete(merged, key)
+	}
+	for i, metadata := range all {
+		for key, value := range metadata {
+			if conflicting[key] {
+				merged[strconv.Itoa(i)+"."+key] = value
+			}
+		}
+	}
+	return merged
+}
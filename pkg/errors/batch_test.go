@@ -0,0 +1,47 @@
+package errors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetMetadataAll(t *testing.T) {
+	require.Empty(t, GetMetadataAll(nil))
+
+	errs := []error{
+		WithMetadata(errors.New("foo"), "collection", "a", "shard", 1),
+		nil,
+		errors.New("bar"),
+		WithMetadata(status.Error(codes.NotFound, "not found"), "collection", "b"),
+	}
+	require.Equal(t, []map[string]any{
+		{"collection": "a", "shard": 1},
+		nil,
+		{},
+		{"collection": "b"},
+	}, GetMetadataAll(errs))
+}
+
+func TestGetMetadataMerged(t *testing.T) {
+	require.Empty(t, GetMetadataMerged(nil))
+
+	errs := []error{
+		WithMetadata(errors.New("foo"), "collection", "a", "shard", 1, "ids", []int{1}),
+		nil,
+		WithMetadata(errors.New("bar"), "shard", 1, "ids", []int{1}),
+		WithMetadata(status.Error(codes.NotFound, "not found"), "collection", "b", "shard", 1, "only", true),
+		WithMetadata(errors.New("baz"), "collection", "a"),
+	}
+	require.Equal(t, map[string]any{
+		"0.collection": "a",
+		"3.collection": "b",
+		"4.collection": "a",
+		"shard":        1,
+		"ids":          []int{1},
+		"only":         true,
+	}, GetMetadataMerged(errs))
+}
@@ -0,0 +1,144 @@
+package errors
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"google.golang.org/grpc/codes"
+)
+
+// binaryError is the serialized form of an error used by MarshalBinary and UnmarshalBinary.
+type binaryError struct {
+	Message  string
+	Code     uint32
+	Metadata []binaryEntry
+}
+
+// binaryEntry is a serialized metadata key value pair.
+// Kind records the kind of the original value, so it can be restored with the same type.
+type binaryEntry struct {
+	Key    string
+	Kind   reflect.Kind
+	String string
+	Int    int64
+	Uint   uint64
+	Float  float64
+	Bool   bool
+}
+
+// MarshalBinary serializes the message, gRPC code and metadata of the error,
+// so it can be passed to another process and restored with UnmarshalBinary.
+// Values of kind bool, string, and any integer or float kind keep their type,
+// other values are serialized using fmt.Sprint. Keys that are not strings are skipped.
+func MarshalBinary(err error) ([]byte, error) {
+	if err == nil {
+		return nil, errors.New("cannot marshal a nil error")
+	}
+	be := binaryError{
+		Message: err.Error(),
+		Code:    uint32(CodeOf(err)),
+	}
+	metadata := GetMetadata(err)
+	for i := 0; i+1 < len(metadata); i += 2 {
+		key, ok := metadata[i].(string)
+		if !ok {
+			continue
+		}
+		be.Metadata = append(be.Metadata, newBinaryEntry(key, metadata[i+1]))
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(be); err != nil {
+		return nil, fmt.Errorf("failed to marshal error: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary restores an error serialized with MarshalBinary.
+// The restored error has the same message, code (CodeOf) and metadata (GetMetadata) as the original one,
+// however it doesn't preserve the original error types.
+// The second return value reports a failure to unmarshal the data.
+func UnmarshalBinary(data []byte) (error, error) {
+	var be binaryError
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&be); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal error: %w", err)
+	}
+	metadata := make([]any, 0, len(be.Metadata)*2)
+	for _, entry := range be.Metadata {
+		metadata = append(metadata, entry.Key, entry.value())
+	}
+	return newErrWithMetadata(errWithMetadata{
+		err:      errors.New(be.Message),
+		metadata: metadata,
+		code:     codes.Code(be.Code),
+		hasCode:  true,
+	}), nil
+}
+
+func newBinaryEntry(key string, value any) binaryEntry {
+	entry := binaryEntry{Key: key}
+	if value == nil {
+		entry.Kind = reflect.Invalid
+		return entry
+	}
+	v := reflect.ValueOf(value)
+	entry.Kind = v.Kind()
+	switch v.Kind() {
+	case reflect.Bool:
+		entry.Bool = v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		entry.Int = v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		entry.Uint = v.Uint()
+	case reflect.Float32, reflect.Float64:
+		entry.Float = v.Float()
+	case reflect.String:
+		entry.String = v.String()
+	default:
+		entry.Kind = reflect.String
+		entry.String = fmt.Sprint(value)
+	}
+	return entry
+}
+
+// binaryKindTypes maps the serialized kinds to the types the values are restored with.
+var binaryKindTypes = map[reflect.Kind]reflect.Type{
+	reflect.Bool:    reflect.TypeFor[bool](),
+	reflect.Int:     reflect.TypeFor[int](),
+	reflect.Int8:    reflect.TypeFor[int8](),
+	reflect.Int16:   reflect.TypeFor[int16](),
+	reflect.Int32:   reflect.TypeFor[int32](),
+	reflect.Int64:   reflect.TypeFor[int64](),
+	reflect.Uint:    reflect.TypeFor[uint](),
+	reflect.Uint8:   reflect.TypeFor[uint8](),
+	reflect.Uint16:  reflect.TypeFor[uint16](),
+	reflect.Uint32:  reflect.TypeFor[uint32](),
+	reflect.Uint64:  reflect.TypeFor[uint64](),
+	reflect.Uintptr: reflect.TypeFor[uintptr](),
+	reflect.Float32: reflect.TypeFor[float32](),
+	reflect.Float64: reflect.TypeFor[float64](),
+	reflect.String:  reflect.TypeFor[string](),
+}
+
+func (e binaryEntry) value() any {
+	t, ok := binaryKindTypes[e.Kind]
+	if !ok {
+		return nil
+	}
+	v := reflect.New(t).Elem()
+	switch e.Kind {
+	case reflect.Bool:
+		v.SetBool(e.Bool)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(e.Int)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v.SetUint(e.Uint)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(e.Float)
+	default:
+		v.SetString(e.String)
+	}
+	return v.Interface()
+}
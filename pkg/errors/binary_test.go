@@ -0,0 +1,81 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMarshalBinary(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+	}{
+		{
+			name: "plain error",
+			err:  errors.New("foo"),
+		},
+		{
+			name: "error with code",
+			err:  WithCode(errors.New("foo"), codes.NotFound),
+		},
+		{
+			name: "gRPC status error",
+			err:  status.Error(codes.Aborted, "aborted"),
+		},
+		{
+			name: "error with typed metadata",
+			err: WithMetadata(
+				WithCode(fmt.Errorf("bar: %w", errors.New("foo")), codes.Internal),
+				"string", "value",
+				"int", 42,
+				"int64", int64(-7),
+				"uint8", uint8(3),
+				"float", 1.5,
+				"bool", true,
+				"nil", nil,
+			),
+		},
+		{
+			name: "error with reused key in metadata",
+			err:  WithMetadata(WithMetadata(errors.New("foo"), "reused_key", "inner_value"), "reused_key", "outer_value"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := MarshalBinary(tc.err)
+			require.NoError(t, err)
+
+			restored, err := UnmarshalBinary(data)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.err.Error(), restored.Error())
+			require.Equal(t, CodeOf(tc.err), CodeOf(restored))
+			require.Equal(t, GetMetadataMap(tc.err), GetMetadataMap(restored))
+		})
+	}
+}
+
+func TestMarshalBinaryStringifiesOtherValues(t *testing.T) {
+	data, err := MarshalBinary(WithMetadata(errors.New("foo"), "price", money{cents: 1250, currency: "EUR"}, 1, "non-string key"))
+	require.NoError(t, err)
+
+	restored, err := UnmarshalBinary(data)
+	require.NoError(t, err)
+
+	require.Equal(t, []any{"price", "12.50 EUR"}, GetMetadata(restored))
+}
+
+func TestMarshalBinaryNilError(t *testing.T) {
+	_, err := MarshalBinary(nil)
+	require.Error(t, err)
+}
+
+func TestUnmarshalBinaryInvalidData(t *testing.T) {
+	_, err := UnmarshalBinary([]byte("not an error"))
+	require.Error(t, err)
+}
@@ -0,0 +1,86 @@
+package errors
+
+import (
+	"cmp"
+	"slices"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// metadataDroppedBytesKey holds the number of bytes of metadata dropped by GRPCStatus to honor MaxMetadataBytes.
+const metadataDroppedBytesKey = "metadata_dropped_bytes"
+
+// defaultMetadataPriority lists the metadata keys dropped last to honor MaxMetadataBytes, by default.
+var defaultMetadataPriority = []string{
+	"request_id",
+	publicMessageKey,
+	severityKey,
+	expectedKey,
+	externalKey,
+	tenantKey,
+	operationKey,
+	operationMillisKey,
+	attemptKey,
+}
+
+// metadataSize estimates the serialized size of the metadata detail and its type hints.
+func metadataSize(metadata map[string]any) int {
+	values, hints := newMetadataStructs(metadata)
+	size := proto.Size(values)
+	if hints != nil {
+		size += proto.Size(hints)
+	}
+	return size
+}
+
+// fitMetadata drops metadata keys, the least important first, until the serialized size of the metadata
+// is at most maxBytes, and reports the number of dropped bytes under metadataDroppedBytesKey.
+// The keys reported by GRPCStatus itself are never dropped.
+func fitMetadata(metadata map[string]any, maxBytes int, priority []string) {
+	original := metadataSize(metadata)
+	if original <= maxBytes {
+		return
+	}
+	ranks := make(map[string]int, len(priority))
+	for i, key := range priority {
+		if _, ok := ranks[key]; !ok {
+			ranks[key] = i
+		}
+	}
+	sizes := make(map[string]int, len(metadata))
+	var keys []string
+	for key, value := range metadata {
+		if key == detailsTruncatedKey {
+			continue
+		}
+		sizes[key] = proto.Size(&structpb.Struct{Fields: map[string]*structpb.Value{key: toWireValue(value)}})
+		keys = append(keys, key)
+	}
+	// Sort the keys in the order they are dropped: the keys without priority first,
+	// the largest first, followed by the keys with priority, the least important first.
+	slices.SortFunc(keys, func(a, b string) int {
+		rankA, priorityA := ranks[a]
+		rankB, priorityB := ranks[b]
+		switch {
+		case priorityA && priorityB:
+			return cmp.Compare(rankB, rankA)
+		case priorityA:
+			return 1
+		case priorityB:
+			return -1
+		}
+		return cmp.Or(cmp.Compare(sizes[b], sizes[a]), cmp.Compare(a, b))
+	})
+	for i, key := range keys {
+		delete(metadata, key)
+		metadata[metadataDroppedBytesKey] = 0
+		if metadataSize(metadata) <= maxBytes || i == len(keys)-1 {
+			break
+		}
+		delete(metadata, metadataDroppedBytesKey)
+	}
+	delete(metadata, metadataDroppedBytesKey)
+	// The number of dropped bytes is an integer, sent with a type hint, its size doesn't depend on its value.
+	metadata[metadataDroppedBytesKey] = original - metadataSize(metadata)
+}
@@ -0,0 +1,78 @@
+package errors
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// metadataDetailsSize returns the serialized size of the metadata detail of the status and its type hints.
+func metadataDetailsSize(t *testing.T, err error) int {
+	t.Helper()
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	size := 0
+	for _, detail := range st.Details() {
+		if s, ok := detail.(*structpb.Struct); ok && (isMetadataStruct(s) || isTypeHintsStruct(s)) {
+			size += proto.Size(s)
+		}
+	}
+	return size
+}
+
+func TestMaxMetadataBytes(t *testing.T) {
+	payload := strings.Repeat("x", 1000)
+	err := WithMetadata(errors.New("foo"), "request_id", "r1", "payload", payload, "small", "s", "attempt", 2)
+
+	testCases := []struct {
+		name             string
+		opts             []Option
+		expectedMetadata map[string]any
+	}{
+		{
+			name:             "no limit",
+			expectedMetadata: map[string]any{"request_id": "r1", "payload": payload, "small": "s", "attempt": 2},
+		},
+		{
+			name:             "within the limit",
+			opts:             []Option{MaxMetadataBytes(2000)},
+			expectedMetadata: map[string]any{"request_id": "r1", "payload": payload, "small": "s", "attempt": 2},
+		},
+		{
+			name:             "largest keys without priority dropped first",
+			opts:             []Option{MaxMetadataBytes(300)},
+			expectedMetadata: map[string]any{"request_id": "r1", "small": "s", "attempt": 2, "metadata_dropped_bytes": 1018},
+		},
+		{
+			name:             "keys with priority dropped last",
+			opts:             []Option{MaxMetadataBytes(160)},
+			expectedMetadata: map[string]any{"request_id": "r1", "metadata_dropped_bytes": 1105},
+		},
+		{
+			name:             "custom priority",
+			opts:             []Option{MaxMetadataBytes(160), MetadataPriority("small", "payload")},
+			expectedMetadata: map[string]any{"small": "s", "metadata_dropped_bytes": 1111},
+		},
+		{
+			name:             "too small limit",
+			opts:             []Option{MaxMetadataBytes(1)},
+			expectedMetadata: map[string]any{"metadata_dropped_bytes": 1125},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			Configure(tc.opts...)
+			t.Cleanup(func() { Configure() })
+
+			require.Equal(t, tc.expectedMetadata, GetMetadataMap(roundTrip(t, err)))
+			if maxBytes := getOptions().maxMetadataBytes; maxBytes > 1 {
+				require.LessOrEqual(t, metadataDetailsSize(t, err), maxBytes)
+			}
+		})
+	}
+}
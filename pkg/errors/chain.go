@@ -0,0 +1,58 @@
+package errors
+
+// Chain runs a linear pipeline of steps, stopping at the first failure, to avoid repeating
+// the error checks between sequential operations, e.g.:
+//
+//	err := errhelper.Do(load, "stage", "load").
+//		Do(validate, "stage", "validate").
+//		Do(store, "stage", "store").
+//		Err()
+//
+// The steps run sequentially, in the calling goroutine, each one only if the previous ones succeeded.
+// The zero value is a chain with no steps, whose Err returns nil. A Chain is a value, each Do returns
+// a new one, so a chain can be branched, however it must not be shared between goroutines while branched.
+type Chain struct {
+	// err is the wrapped error of the failed step, if any.
+	err error
+	// steps counts the steps run so far.
+	steps int
+	// metadata accumulates the metadata of the steps run so far.
+	metadata []any
+}
+
+// Do returns a chain running fn as its first step, see Chain.Do.
+func Do(fn func() error, keyValues ...any) Chain {
+	return Chain{}.Do(fn, keyValues...)
+}
+
+// Do runs fn as the next step of the chain, unless a previous step failed, and returns the resulting chain.
+// The key value pairs describe the step, e.g. its name, they are accumulated along the chain. If fn fails,
+// its error is wrapped with the accumulated metadata, the pairs of the later steps taking precedence,
+// and with the index of the step, starting at 0, under the "step" key. A nil fn is a step which succeeds.
+func (c Chain) Do(fn func() error, keyValues ...any) Chain {
+	if c.err != nil {
+		return c
+	}
+	step := c.steps
+	c.steps++
+	if len(keyValues) > 0 {
+		// Clip the accumulated metadata, so that branched chains don't share their appends.
+		c.metadata = append(c.metadata[:len(c.metadata):len(c.metadata)], flattenKeyValues(keyValues)...)
+		c.metadata = addPaddingForMissingValue(c.metadata)
+	}
+	if fn == nil {
+		return c
+	}
+	if err := fn(); err != nil {
+		metadata := make([]any, 0, len(c.metadata)+2)
+		metadata = append(metadata, c.metadata...)
+		metadata = append(metadata, stepKey, step)
+		c.err = WithMetadata(err, metadata...)
+	}
+	return c
+}
+
+// Err returns the error of the failed step wrapped with its context, or nil if all the steps succeeded.
+func (c Chain) Err() error {
+	return c.err
+}
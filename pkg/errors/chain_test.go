@@ -0,0 +1,43 @@
+package errors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestChain(t *testing.T) {
+	var ran []int
+	step := func(i int, err error) func() error {
+		return func() error {
+			ran = append(ran, i)
+			return err
+		}
+	}
+
+	require.NoError(t, Chain{}.Err())
+	require.NoError(t, Do(step(0, nil), "stage", "load").Do(nil).Do(step(2, nil)).Err())
+	require.Equal(t, []int{0, 2}, ran)
+
+	ran = nil
+	failure := WithCode(errors.New("invalid"), codes.InvalidArgument)
+	err := Do(step(0, nil), "stage", "load", "source").
+		Do(step(1, failure), "stage", "validate").
+		Do(step(2, nil), "stage", "store").
+		Err()
+	require.Equal(t, []int{0, 1}, ran)
+	require.ErrorIs(t, err, failure)
+	require.Equal(t, codes.InvalidArgument, CodeOf(err))
+	require.Equal(t, map[string]any{"stage": "validate", "source": "<missing>", "step": 1}, GetMetadataMap(err))
+
+	// Branched chains don't share their metadata.
+	ran = nil
+	base := Do(step(0, nil), "a", 1)
+	first := base.Do(step(1, errors.New("first")), "b", 2)
+	second := base.Do(step(2, errors.New("second")), "c", 3)
+	require.Equal(t, []int{0, 1, 2}, ran)
+	require.Equal(t, []any{"a", 1, "b", 2, "step", 1}, GetMetadata(first.Err()))
+	require.Equal(t, []any{"a", 1, "c", 3, "step", 1}, GetMetadata(second.Err()))
+}
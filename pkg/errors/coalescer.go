@@ -0,0 +1,131 @@
+package errors
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// defaultCoalescerSize is the number of fingerprints tracked by a Coalescer created with a non-positive size.
+const defaultCoalescerSize = 1024
+
+// Coalescer limits the logging of repeated identical errors, as identified by Fingerprint,
+// to once per time window, counting the suppressed occurrences, e.g. to reduce the log spam of hot error paths:
+//
+//	if shouldLog, suppressed := coalescer.Observe(err); shouldLog {
+//		logger.Error("request failed", slogadapter.Attrs(errhelper.WithMetadata(err, "suppressed_count", suppressed))...)
+//	}
+//
+// The occurrences suppressed after the last occurrence of a fingerprint are only reported by Flush,
+// which should be called periodically, e.g. with a time.Ticker, so they are not lost when the error stops recurring.
+// Only the most recently observed fingerprints are tracked, so the memory used is bounded.
+// It is safe for concurrent use.
+type Coalescer struct {
+	window time.Duration
+	size   int
+	// now returns the current time, it's replaced in tests.
+	now func() time.Time
+
+	mu sync.Mutex
+	// entries holds the tracked fingerprints, the most recently observed first.
+	entries *list.List
+	// byFingerprint indexes the elements of entries by fingerprint.
+	byFingerprint map[string]*list.Element
+}
+
+// coalescerEntry is the state of a fingerprint tracked by a Coalescer.
+type coalescerEntry struct {
+	fingerprint string
+	// loggedAt is when the error was last reported to be logged.
+	loggedAt time.Time
+	// suppressed counts the occurrences since then.
+	suppressed int
+	// last is the last suppressed occurrence.
+	last error
+}
+
+// SuppressedError is an error whose occurrences were suppressed by a Coalescer, as reported by Flush.
+type SuppressedError struct {
+	// Err is the last suppressed occurrence of the error.
+	Err error
+	// Count is the number of suppressed occurrences.
+	Count int
+}
+
+// NewCoalescer returns a Coalescer reporting an error to be logged once per window,
+// tracking at most size fingerprints, the least recently observed being forgotten first.
+// A non-positive size tracks 1024 fingerprints.
+func NewCoalescer(window time.Duration, size int) *Coalescer {
+	if size <= 0 {
+		size = defaultCoalescerSize
+	}
+	return &Coalescer{
+		window:        window,
+		size:          size,
+		now:           time.Now,
+		entries:       list.New(),
+		byFingerprint: make(map[string]*list.Element),
+	}
+}
+
+// Observe records an occurrence of the error and reports whether it should be logged: the first occurrence
+// of a fingerprint is, as well as the first occurrence after the window elapsed since it was last logged,
+// along with the number of occurrences suppressed in between, to be logged, e.g. under the "suppressed_count" key.
+// A forgotten fingerprint is logged again as if it was new. Nil errors are never logged.
+func (c *Coalescer) Observe(err error) (shouldLog bool, suppressedSince int) {
+	if err == nil {
+		return false, 0
+	}
+	fingerprint := Fingerprint(err)
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.byFingerprint[fingerprint]; ok {
+		c.entries.MoveToFront(elem)
+		entry := elem.Value.(*coalescerEntry)
+		if now.Sub(entry.loggedAt) < c.window {
+			entry.suppressed++
+			entry.last = err
+			return false, 0
+		}
+		suppressed := entry.suppressed
+		entry.loggedAt, entry.suppressed, entry.last = now, 0, nil
+		return true, suppressed
+	}
+	c.byFingerprint[fingerprint] = c.entries.PushFront(&coalescerEntry{fingerprint: fingerprint, loggedAt: now})
+	if c.entries.Len() > c.size {
+		oldest := c.entries.Back()
+		c.entries.Remove(oldest)
+		delete(c.byFingerprint, oldest.Value.(*coalescerEntry).fingerprint)
+	}
+	return true, 0
+}
+
+// Flush returns the errors whose window elapsed since they were last logged with the number of their occurrences
+// suppressed since then, the least recently observed first, and forgets their fingerprints, so the next occurrence
+// is logged as if it was new, e.g. to log a summary of the errors which stopped recurring:
+//
+//	for _, s := range coalescer.Flush() {
+//		logger.Error("request failed", slogadapter.Attrs(errhelper.WithMetadata(s.Err, "suppressed_count", s.Count))...)
+//	}
+//
+// The fingerprints whose window elapsed without suppressed occurrences are forgotten as well.
+func (c *Coalescer) Flush() []SuppressedError {
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var flushed []SuppressedError
+	for elem := c.entries.Back(); elem != nil; {
+		prev := elem.Prev()
+		entry := elem.Value.(*coalescerEntry)
+		if now.Sub(entry.loggedAt) >= c.window {
+			if entry.suppressed > 0 {
+				flushed = append(flushed, SuppressedError{Err: entry.last, Count: entry.suppressed})
+			}
+			c.entries.Remove(elem)
+			delete(c.byFingerprint, entry.fingerprint)
+		}
+		elem = prev
+	}
+	return flushed
+}
@@ -0,0 +1,95 @@
+package errors
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoalescer(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewCoalescer(time.Minute, 2)
+	c.now = func() time.Time { return now }
+
+	observe := func(err error) []any {
+		shouldLog, suppressed := c.Observe(err)
+		return []any{shouldLog, suppressed}
+	}
+	errA := WithMetadata(errors.New("a"), "attempt", 1)
+	errB := errors.New("b")
+	errC := errors.New("c")
+
+	require.Equal(t, []any{false, 0}, observe(nil))
+	require.Equal(t, []any{true, 0}, observe(errA))
+	require.Equal(t, []any{false, 0}, observe(WithMetadata(errors.New("a"), "attempt", 2)))
+	require.Equal(t, []any{false, 0}, observe(errA))
+	require.Equal(t, []any{true, 0}, observe(errB))
+
+	// Once the window elapsed, the error is logged with the number of suppressed occurrences.
+	now = now.Add(30 * time.Second)
+	require.Equal(t, []any{false, 0}, observe(errA))
+	now = now.Add(30 * time.Second)
+	require.Equal(t, []any{true, 3}, observe(errA))
+	require.Equal(t, []any{false, 0}, observe(errA))
+
+	// The least recently observed fingerprint is forgotten.
+	require.Equal(t, []any{true, 0}, observe(errC))
+	require.Equal(t, []any{false, 0}, observe(errA))
+	require.Equal(t, []any{true, 0}, observe(errB))
+}
+
+func TestCoalescerFlush(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewCoalescer(time.Minute, 0)
+	c.now = func() time.Time { return now }
+
+	errA := WithMetadata(errors.New("a"), "attempt", 1)
+	lastA := WithMetadata(errors.New("a"), "attempt", 2)
+	errB := errors.New("b")
+	errC := errors.New("c")
+	c.Observe(errA)
+	c.Observe(errB)
+	c.Observe(errA)
+	c.Observe(lastA)
+	c.Observe(errB)
+	require.Empty(t, c.Flush())
+
+	now = now.Add(30 * time.Second)
+	c.Observe(errC)
+	now = now.Add(30 * time.Second)
+	// The window of c hasn't elapsed, a and b are reported and forgotten.
+	require.Equal(t, []SuppressedError{{Err: lastA, Count: 2}, {Err: errB, Count: 1}}, c.Flush())
+	require.Empty(t, c.Flush())
+	shouldLog, suppressed := c.Observe(errA)
+	require.True(t, shouldLog)
+	require.Zero(t, suppressed)
+
+	// c had no suppressed occurrences, it's forgotten without being reported.
+	now = now.Add(30 * time.Second)
+	require.Empty(t, c.Flush())
+	shouldLog, _ = c.Observe(errC)
+	require.True(t, shouldLog)
+}
+
+func TestCoalescerConcurrent(t *testing.T) {
+	c := NewCoalescer(time.Hour, 0)
+	var mu sync.Mutex
+	logged := 0
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			for range 100 {
+				if shouldLog, _ := c.Observe(errors.New("foo")); shouldLog {
+					mu.Lock()
+					logged++
+					mu.Unlock()
+				}
+			}
+		})
+	}
+	wg.Wait()
+	require.Equal(t, 1, logged)
+}
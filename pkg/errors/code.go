@@ -0,0 +1,167 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"google.golang.org/grpc/codes"
+)
+
+// WithCode returns the provided error wrapped with the provided gRPC code.
+// The code is used by GRPCStatus instead of the code of the wrapped error,
+// while the message and details of the wrapped error are preserved.
+func WithCode(err error, code codes.Code) error {
+	if err == nil {
+		return nil
+	}
+	wrapped := newErrWithMetadata(errWithMetadata{
+		err:     err,
+		code:    code,
+		hasCode: true,
+	})
+	notifyWrap(wrapped, nil)
+	return wrapped
+}
+
+// WithCodeIfUnset returns the provided error wrapped with the provided gRPC code,
+// only if the error chain carries no code, either set with WithCode or coming from a gRPC status.
+// Otherwise, it returns the error unchanged, so that outer layers don't override a more specific code.
+func WithCodeIfUnset(err error, code codes.Code) error {
+	if _, ok := explicitCode(err); ok {
+		return err
+	}
+	return WithCode(err, code)
+}
+
+// CodeOf returns the gRPC code of the error.
+// It is the code set by the outermost WithCode, or the code of the outermost gRPC status in the chain.
+// It returns codes.OK for a nil error and codes.Unknown if the chain carries no code.
+func CodeOf(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+	code, _ := explicitCode(err)
+	return code
+}
+
+// IsCode reports whether the gRPC code of the error (see CodeOf) is the provided code.
+// A nil error has codes.OK, as well as a non-nil error whose chain carries a gRPC status reporting codes.OK,
+// use HasGRPCStatus to tell them apart.
+func IsCode(err error, code codes.Code) bool {
+	return CodeOf(err) == code
+}
+
+// IsAnyCode reports whether the gRPC code of the error (see CodeOf) is one of the provided codes,
+// following the same logic as IsCode, e.g.:
+//
+//	if errhelper.IsAnyCode(err, errhelper.TransientCodes()...) {
+//		// retry
+//	}
+func IsAnyCode(err error, candidates ...codes.Code) bool {
+	return slices.Contains(candidates, CodeOf(err))
+}
+
+// TransientCodes returns the gRPC codes of the errors worth retrying, as the condition causing them
+// is likely to be temporary: Unavailable, DeadlineExceeded, ResourceExhausted and Aborted.
+// It returns a new slice on every call, so it can be modified freely.
+func TransientCodes() []codes.Code {
+	return []codes.Code{codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted}
+}
+
+// HasGRPCStatus reports whether the error chain carries a code, either set with WithCode or coming from
+// a gRPC status, rather than the codes.Unknown assumed for plain errors. It reports false for a nil error.
+//
+// A non-nil error whose chain carries a gRPC status reporting codes.OK has the codes.OK code and a status,
+// so both IsCode(err, codes.OK) and HasGRPCStatus(err) report true. Such an error is still an error:
+// WithMetadata wraps it as usual, preserving its message, while GRPCStatus reports it with codes.Unknown,
+// as a status with codes.OK would be received as a success by gRPC clients.
+func HasGRPCStatus(err error) bool {
+	_, ok := explicitCode(err)
+	return ok
+}
+
+// CodeName returns the canonical name of the gRPC code of the error (see CodeOf), e.g. "NotFound",
+// for log fields. It returns "OK" for a nil error and "Unknown" if the chain carries no code.
+func CodeName(err error) string {
+	return CodeOf(err).String()
+}
+
+// explicitCode returns the code set by the outermost WithCode or gRPC status in the error chain,
+// and whether such a code was found. It returns codes.Unknown if no code was found.
+func explicitCode(err error) (codes.Code, bool) {
+	for u := err; u != nil; u = errors.Unwrap(u) {
+		if e, isOurType := u.(*errWithMetadata); isOurType { // nolint: errorlint // errors.As should not be used here
+			if e.hasCode {
+				return e.code, true
+			}
+			continue
+		}
+		if st, ok := foreignStatus(u); ok {
+			return st.Code(), true
+		}
+	}
+	return codes.Unknown, false
+}
+
+// IsCanceled reports whether the error was caused by a cancellation.
+// It traverses the whole chain, looking for context.Canceled as well as
+// any gRPC status or code set with WithCode equal to codes.Canceled.
+func IsCanceled(err error) bool {
+	return errors.Is(err, context.Canceled) || chainHasCode(err, codes.Canceled)
+}
+
+// IsDeadlineExceeded reports whether the error was caused by an exceeded deadline.
+// It traverses the whole chain, looking for context.DeadlineExceeded as well as
+// any gRPC status or code set with WithCode equal to codes.DeadlineExceeded.
+func IsDeadlineExceeded(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || chainHasCode(err, codes.DeadlineExceeded)
+}
+
+// IsNotFound reports whether the gRPC code of the error (see CodeOf) is codes.NotFound.
+// Like the other predicates below, it's equivalent to IsCode, and reports false for a nil error.
+func IsNotFound(err error) bool {
+	return IsCode(err, codes.NotFound)
+}
+
+// IsInvalidArgument reports whether the gRPC code of the error (see CodeOf) is codes.InvalidArgument.
+func IsInvalidArgument(err error) bool {
+	return IsCode(err, codes.InvalidArgument)
+}
+
+// IsAlreadyExists reports whether the gRPC code of the error (see CodeOf) is codes.AlreadyExists.
+func IsAlreadyExists(err error) bool {
+	return IsCode(err, codes.AlreadyExists)
+}
+
+// IsPermissionDenied reports whether the gRPC code of the error (see CodeOf) is codes.PermissionDenied.
+func IsPermissionDenied(err error) bool {
+	return IsCode(err, codes.PermissionDenied)
+}
+
+// IsUnavailable reports whether the gRPC code of the error (see CodeOf) is codes.Unavailable.
+func IsUnavailable(err error) bool {
+	return IsCode(err, codes.Unavailable)
+}
+
+// IsInternal reports whether the gRPC code of the error (see CodeOf) is codes.Internal.
+func IsInternal(err error) bool {
+	return IsCode(err, codes.Internal)
+}
+
+// chainHasCode reports whether any error in the chain carries the provided code,
+// either set with WithCode or coming from a gRPC status.
+func chainHasCode(err error, code codes.Code) bool {
+	for u := err; u != nil; u = errors.Unwrap(u) {
+		if e, isOurType := u.(*errWithMetadata); isOurType { // nolint: errorlint // errors.As should not be used here
+			if e.hasCode && e.code == code {
+				return true
+			}
+			continue
+		}
+		if st, ok := foreignStatus(u); ok && st.Code() == code {
+			return true
+		}
+	}
+	return false
+}
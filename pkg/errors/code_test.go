@@ -0,0 +1,258 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCodeOf(t *testing.T) {
+	grpcErr := status.Error(codes.NotFound, "item not found")
+
+	testCases := []struct {
+		name     string
+		err      error
+		expected codes.Code
+	}{
+		{
+			name:     "nil error",
+			err:      nil,
+			expected: codes.OK,
+		},
+		{
+			name:     "plain error",
+			err:      errors.New("foo"),
+			expected: codes.Unknown,
+		},
+		{
+			name:     "plain error wrapped with metadata",
+			err:      WithMetadata(errors.New("foo"), "key", "value"),
+			expected: codes.Unknown,
+		},
+		{
+			name:     "gRPC status error",
+			err:      grpcErr,
+			expected: codes.NotFound,
+		},
+		{
+			name:     "gRPC status error wrapped with custom message and metadata",
+			err:      WithMetadata(fmt.Errorf("foo: %w", grpcErr), "key", "value"),
+			expected: codes.NotFound,
+		},
+		{
+			name:     "plain error wrapped with code",
+			err:      WithCode(errors.New("foo"), codes.Internal),
+			expected: codes.Internal,
+		},
+		{
+			name:     "error with code wrapped with metadata and custom message",
+			err:      fmt.Errorf("foo: %w", WithMetadata(WithCode(errors.New("foo"), codes.Internal), "key", "value")),
+			expected: codes.Internal,
+		},
+		{
+			name:     "gRPC status error wrapped with code",
+			err:      WithCode(grpcErr, codes.Internal),
+			expected: codes.Internal,
+		},
+		{
+			name:     "outermost code wins",
+			err:      WithCode(WithCode(errors.New("foo"), codes.NotFound), codes.Internal),
+			expected: codes.Internal,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, CodeOf(tc.err))
+			require.Equal(t, tc.expected.String(), CodeName(tc.err))
+		})
+	}
+}
+
+func TestWithCode(t *testing.T) {
+	require.NoError(t, WithCode(nil, codes.Internal))
+
+	err := WithMetadata(WithCode(errors.New("foo"), codes.Internal), "key", "value")
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.Internal, st.Code())
+	require.Equal(t, "foo", st.Message())
+	require.Equal(t, "foo", err.Error())
+	require.Len(t, st.Details(), 1)
+
+	// Overriding the code of a gRPC status error preserves its message.
+	st = status.Convert(WithCode(status.Error(codes.NotFound, "item not found"), codes.Internal))
+	require.Equal(t, codes.Internal, st.Code())
+	require.Equal(t, "item not found", st.Message())
+}
+
+func TestWithCodeIfUnset(t *testing.T) {
+	require.NoError(t, WithCodeIfUnset(nil, codes.Internal))
+
+	// A plain error gets the default code.
+	err := WithCodeIfUnset(WithMetadata(errors.New("foo"), "key", "value"), codes.Internal)
+	require.Equal(t, codes.Internal, CodeOf(err))
+
+	// An inner NotFound set with WithCode survives the outer default.
+	inner := WithMetadata(WithCode(errors.New("foo"), codes.NotFound), "key", "value")
+	err = WithCodeIfUnset(fmt.Errorf("bar: %w", inner), codes.Internal)
+	require.Equal(t, codes.NotFound, CodeOf(err))
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	// An inner NotFound gRPC status survives the outer default.
+	grpcErr := WithMetadata(status.Error(codes.NotFound, "item not found"), "key", "value")
+	err = WithCodeIfUnset(grpcErr, codes.Internal)
+	require.Equal(t, grpcErr, err)
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestIsCanceledAndDeadlineExceeded(t *testing.T) {
+	testCases := []struct {
+		name             string
+		err              error
+		canceled         bool
+		deadlineExceeded bool
+	}{
+		{
+			name: "nil error",
+			err:  nil,
+		},
+		{
+			name: "plain error",
+			err:  errors.New("foo"),
+		},
+		{
+			name:     "context canceled",
+			err:      context.Canceled,
+			canceled: true,
+		},
+		{
+			name:     "context canceled wrapped with metadata and custom message",
+			err:      WithMetadata(fmt.Errorf("foo: %w", context.Canceled), "key", "value"),
+			canceled: true,
+		},
+		{
+			name:             "context deadline exceeded wrapped with metadata",
+			err:              fmt.Errorf("foo: %w", WithMetadata(context.DeadlineExceeded, "key", "value")),
+			deadlineExceeded: true,
+		},
+		{
+			name:     "gRPC canceled status",
+			err:      WithMetadata(status.Error(codes.Canceled, "canceled"), "key", "value"),
+			canceled: true,
+		},
+		{
+			name:             "gRPC deadline exceeded status wrapped with another code",
+			err:              WithCode(fmt.Errorf("foo: %w", status.Error(codes.DeadlineExceeded, "deadline")), codes.Internal),
+			deadlineExceeded: true,
+		},
+		{
+			name:     "canceled code set with WithCode",
+			err:      WithMetadata(WithCode(errors.New("foo"), codes.Canceled), "key", "value"),
+			canceled: true,
+		},
+		{
+			name: "other gRPC status",
+			err:  status.Error(codes.NotFound, "item not found"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.canceled, IsCanceled(tc.err))
+			require.Equal(t, tc.deadlineExceeded, IsDeadlineExceeded(tc.err))
+		})
+	}
+}
+
+// okStatusError is an error whose GRPCStatus implementation reports codes.OK.
+type okStatusError struct{}
+
+func (okStatusError) Error() string {
+	return "not really ok"
+}
+
+func (okStatusError) GRPCStatus() *status.Status {
+	return status.New(codes.OK, "")
+}
+
+func TestHasGRPCStatus(t *testing.T) {
+	require.False(t, HasGRPCStatus(nil))
+	require.False(t, HasGRPCStatus(errors.New("foo")))
+	require.False(t, HasGRPCStatus(WithMetadata(errors.New("foo"), "key", "value")))
+	require.True(t, HasGRPCStatus(status.Error(codes.NotFound, "foo")))
+	require.True(t, HasGRPCStatus(WithCode(errors.New("foo"), codes.Internal)))
+	require.True(t, HasGRPCStatus(fmt.Errorf("foo: %w", status.Error(codes.NotFound, "foo"))))
+
+	require.True(t, IsCode(nil, codes.OK))
+	require.True(t, IsCode(errors.New("foo"), codes.Unknown))
+	require.True(t, IsCode(WithCode(errors.New("foo"), codes.Internal), codes.Internal))
+	require.False(t, IsCode(WithCode(errors.New("foo"), codes.Internal), codes.Unknown))
+}
+
+func TestWrapOKStatus(t *testing.T) {
+	err := WithMetadata(okStatusError{}, "key", "value")
+	require.Error(t, err)
+	require.EqualError(t, err, "not really ok")
+	require.True(t, IsCode(err, codes.OK))
+	require.True(t, HasGRPCStatus(err))
+
+	// The status is not reported as a success, but it keeps the metadata.
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.Unknown, st.Code())
+	require.Equal(t, "not really ok", st.Message())
+	require.Error(t, st.Err())
+	require.Equal(t, map[string]any{"key": "value"}, GetMetadataMap(st.Err()))
+
+	// A code explicitly set to codes.OK is not reported as a success either.
+	st, ok = status.FromError(WithCode(errors.New("foo"), codes.OK))
+	require.True(t, ok)
+	require.Equal(t, codes.Unknown, st.Code())
+	require.Equal(t, "foo", st.Message())
+
+	// Any other code set explicitly is kept.
+	st, ok = status.FromError(WithCode(okStatusError{}, codes.NotFound))
+	require.True(t, ok)
+	require.Equal(t, codes.NotFound, st.Code())
+	require.Equal(t, "not really ok", st.Message())
+}
+
+func TestIsAnyCode(t *testing.T) {
+	require.False(t, IsAnyCode(errors.New("foo")))
+	require.True(t, IsAnyCode(nil, codes.OK))
+	require.True(t, IsAnyCode(status.Error(codes.Unavailable, "foo"), TransientCodes()...))
+	require.True(t, IsAnyCode(fmt.Errorf("foo: %w", WithCode(errors.New("foo"), codes.Aborted)), TransientCodes()...))
+	require.False(t, IsAnyCode(WithCode(errors.New("foo"), codes.Internal), TransientCodes()...))
+	require.False(t, IsAnyCode(errors.New("foo"), TransientCodes()...))
+	require.True(t, IsAnyCode(errors.New("foo"), codes.NotFound, codes.Unknown))
+
+	transient := TransientCodes()
+	transient[0] = codes.Internal
+	require.Equal(t, codes.Unavailable, TransientCodes()[0])
+}
+
+func TestCodePredicates(t *testing.T) {
+	predicates := map[codes.Code]func(error) bool{
+		codes.NotFound:         IsNotFound,
+		codes.InvalidArgument:  IsInvalidArgument,
+		codes.AlreadyExists:    IsAlreadyExists,
+		codes.PermissionDenied: IsPermissionDenied,
+		codes.Unavailable:      IsUnavailable,
+		codes.Internal:         IsInternal,
+	}
+	for code, predicate := range predicates {
+		t.Run(code.String(), func(t *testing.T) {
+			require.False(t, predicate(nil))
+			require.False(t, predicate(errors.New("foo")))
+			require.True(t, predicate(status.Error(code, "foo")))
+			require.True(t, predicate(fmt.Errorf("foo: %w", WithMetadata(status.Error(code, "foo"), "key", "value"))))
+			require.True(t, predicate(WithMetadata(WithCode(errors.New("foo"), code), "key", "value")))
+			require.True(t, predicate(roundTrip(t, fmt.Errorf("foo: %w", WithCode(errors.New("foo"), code)))))
+			require.False(t, predicate(WithCode(status.Error(code, "foo"), codes.DataLoss)))
+		})
+	}
+}
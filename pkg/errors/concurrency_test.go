@@ -0,0 +1,51 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestConcurrentReads reads the same error from many goroutines, it's meant to be run with -race.
+func TestConcurrentReads(t *testing.T) {
+	shared := WithSeverity(WithTenant(WithMetadata(
+		fmt.Errorf("lookup: %w", WithMetadata(WithCode(status.Error(codes.NotFound, "item not found"), codes.Unavailable), "id", 42, "cause", errors.New("bar"))),
+		"list", []any{"a", "b"}, "map", map[string]any{"key": "value"}), "tenant-a"), SeverityWarning)
+	received := roundTrip(t, shared)
+
+	var wg sync.WaitGroup
+	for range 16 {
+		for _, err := range []error{shared, received} {
+			wg.Go(func() {
+				_ = err.Error()
+				_ = GetMetadata(err)
+				_ = GetMetadataMap(err)
+				_ = GetMetadataValues(err, "id")
+				for range All(err) {
+				}
+				_, _ = GRPCStatusOf(err)
+				_ = status.Convert(err).Proto()
+				_ = CodeOf(err)
+				_ = CodeName(err)
+				_ = HasGRPCStatus(err)
+				_, _ = SeverityOf(err)
+				_, _ = TenantOf(err)
+				_ = Logfmt(err)
+				_ = CheckWireSafe(err)
+				_ = Summary(err)
+				_ = Sanitize(err)
+				_ = StripMessages(err)
+				_, _ = MarshalBinary(err)
+				var metadataErr MetadataError
+				if errors.As(err, &metadataErr) {
+					_ = metadataErr.Metadata()
+				}
+			})
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,58 @@
+package errors
+
+import "reflect"
+
+// ConflictingKeys returns the metadata keys attached several times with different values across the error chain,
+// along with all their values, from the lowest to the highest precedence, e.g. to assert in tests that
+// no layer accidentally reuses a key set by another one:
+//
+//	require.Empty(t, errhelper.ConflictingKeys(err))
+//
+// Unlike GetMetadataMap, which silently keeps the outermost value, it reports every value of such keys,
+// including repeated ones. A key attached several times with equal values, compared with reflect.DeepEqual,
+// is an intentional override and is not reported, as well as the reserved keys set by the helpers
+// of this package, such as the log count of MarkLogged or the IDs of WithCorrelation. The whole tree
+// is traversed, including the branches of multi-errors and the metadata received in gRPC status details,
+// while the global metadata is ignored.
+// It returns nil if there is no conflict.
+func ConflictingKeys(err error) map[string][]any {
+	values := make(map[string][]any)
+	var keys []string
+	var visited visitedErrors
+	for _, layer := range appendLayers(nil, err, &visited) {
+		for i := 0; i+1 < len(layer); i += 2 {
+			key, ok := layer[i].(string)
+			if !ok {
+				continue
+			}
+			if _, reserved := reservedKeys[key]; reserved {
+				continue
+			}
+			if _, seen := values[key]; !seen {
+				keys = append(keys, key)
+			}
+			values[key] = append(values[key], layer[i+1])
+		}
+	}
+	var conflicts map[string][]any
+	for _, key := range keys {
+		if !hasDistinctValues(values[key]) {
+			continue
+		}
+		if conflicts == nil {
+			conflicts = make(map[string][]any)
+		}
+		conflicts[key] = values[key]
+	}
+	return conflicts
+}
+
+// hasDistinctValues reports whether the values are not all deeply equal.
+func hasDistinctValues(values []any) bool {
+	for _, v := range values[1:] {
+		if !reflect.DeepEqual(v, values[0]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestConflictingKeys(t *testing.T) {
+	SetGlobalMetadata("service", "global")
+	t.Cleanup(func() { SetGlobalMetadata() })
+
+	received := roundTrip(t, WithMetadata(status.Error(codes.NotFound, "not found"), "collection", "a", "shard", 1))
+
+	testCases := []struct {
+		name     string
+		err      error
+		expected map[string][]any
+	}{
+		{
+			name: "nil error",
+			err:  nil,
+		},
+		{
+			name: "no conflict",
+			err:  WithMetadata(WithMetadata(errors.New("foo"), "a", 1), "b", 2, "service", "local"),
+		},
+		{
+			name: "equal values",
+			err:  WithMetadata(fmt.Errorf("bar: %w", WithMetadata(errors.New("foo"), "ids", []int{1, 2})), "ids", []int{1, 2}),
+		},
+		{
+			name: "reserved keys",
+			err: WithCorrelation(
+				MarkLogged(WithCorrelation(MarkLogged(errors.New("foo")), map[string]string{"trace_id": "t1"})),
+				map[string]string{"request_id": "r1"},
+			),
+		},
+		{
+			name: "different values",
+			err: WithMetadata(
+				WithMetadata(WithMetadata(errors.New("foo"), "a", 1, "b", 1), "a", 2, "b", 1),
+				"a", 1, "c", 3,
+			),
+			expected: map[string][]any{"a": {1, 2, 1}},
+		},
+		{
+			name:     "gRPC details",
+			err:      WithMetadata(received, "collection", "b", "shard", 1),
+			expected: map[string][]any{"collection": {"a", "b"}},
+		},
+		{
+			name:     "multi-error branches",
+			err:      errors.Join(WithMetadata(errors.New("foo"), "a", 1), WithMetadata(errors.New("bar"), "a", "1")),
+			expected: map[string][]any{"a": {1, "1"}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, ConflictingKeys(tc.err))
+		})
+	}
+}
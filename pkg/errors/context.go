@@ -0,0 +1,193 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	// contextExtractors holds the functions registered with RegisterContextExtractor,
+	// it's only written under extractorsMu.
+	contextExtractors atomic.Pointer[[]func(context.Context) []any]
+	extractorsMu      sync.Mutex
+)
+
+// RegisterContextExtractor registers a function returning key value pairs from a context, such as
+// the trace or request ID stored by a library under its own context key, that WithMetadataContext
+// attaches to errors. It lets each library register its extractor once, at init time.
+// Nil functions are ignored.
+//
+// It is part of the global configuration, see the package documentation.
+func RegisterContextExtractor(fn func(context.Context) []any) {
+	if fn == nil {
+		return
+	}
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	extractors := append(slices.Clip(getContextExtractors()), fn)
+	contextExtractors.Store(&extractors)
+}
+
+// getContextExtractors returns the functions registered with RegisterContextExtractor.
+// The returned slice must not be modified.
+func getContextExtractors() []func(context.Context) []any {
+	if extractors := contextExtractors.Load(); extractors != nil {
+		return *extractors
+	}
+	return nil
+}
+
+// WithMetadataContext returns the provided error wrapped with the key value pairs, like WithMetadata,
+// along with the pairs returned for the context by the extractors registered with RegisterContextExtractor.
+// The extracted pairs have the lowest precedence: they are skipped for keys already set in the error chain
+// or in keyValues, and when several extractors return the same key, the extractor registered last wins.
+// It returns nil for a nil error.
+func WithMetadataContext(ctx context.Context, err error, keyValues ...any) error {
+	if err == nil {
+		return nil
+	}
+	extractors := getContextExtractors()
+	if len(extractors) == 0 {
+		return WithMetadata(err, keyValues...)
+	}
+	var extracted []any
+	for _, extract := range extractors {
+		extracted = append(extracted, addPaddingForMissingValue(flattenKeyValues(extract(ctx)))...)
+	}
+	if len(extracted) == 0 {
+		return WithMetadata(err, keyValues...)
+	}
+	// Only the keys of the error chain are considered, the global metadata has a lower precedence.
+	set := make(map[string]struct{})
+	var visited visitedErrors
+	for _, layer := range appendLayers(nil, err, &visited) {
+		for i := 0; i < len(layer); i += 2 {
+			if key, ok := layer[i].(string); ok {
+				set[key] = struct{}{}
+			}
+		}
+	}
+	explicit := addPaddingForMissingValue(flattenKeyValues(keyValues))
+	for i := 0; i < len(explicit); i += 2 {
+		if key, ok := explicit[i].(string); ok {
+			set[key] = struct{}{}
+		}
+	}
+	metadata := make([]any, 0, len(extracted)+len(explicit))
+	for i := 0; i < len(extracted); i += 2 {
+		if key, ok := extracted[i].(string); ok {
+			if _, exists := set[key]; exists {
+				continue
+			}
+		}
+		metadata = append(metadata, extracted[i], extracted[i+1])
+	}
+	return WithMetadata(err, append(metadata, explicit...)...)
+}
+
+// WrapCtx returns the provided error wrapped with the key value pairs, like WithMetadata, taking into account
+// the context the operation ran with. If the context is done, because it was canceled or its deadline exceeded,
+// the error is also given codes.Canceled or codes.DeadlineExceeded, and the message of the context error
+// under the "ctx_err" key, as the operation was most likely abandoned because of it.
+// A code more specific than codes.Unknown already present in the error chain is kept.
+// A nil context is treated as an active one. It returns nil for a nil error.
+func WrapCtx(ctx context.Context, err error, keyValues ...any) error {
+	if err == nil {
+		return nil
+	}
+	if ctx == nil {
+		return WithMetadata(err, keyValues...)
+	}
+	ctxErr := ctx.Err()
+	var code codes.Code
+	switch {
+	case errors.Is(ctxErr, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	case errors.Is(ctxErr, context.Canceled):
+		code = codes.Canceled
+	default:
+		return WithMetadata(err, keyValues...)
+	}
+	if existing, ok := explicitCode(err); !ok || existing == codes.Unknown {
+		err = WithCode(err, code)
+	}
+	metadata := make([]any, 0, len(keyValues)+2)
+	metadata = append(metadata, ctxErrKey, ctxErr.Error())
+	metadata = append(metadata, keyValues...)
+	return WithMetadata(err, metadata...)
+}
+
+// contextMetadataKey is the context key of the metadata stored with ContextWithMetadata.
+type contextMetadataKey struct{}
+
+// ContextWithMetadata returns a copy of ctx carrying the key value pairs as ambient metadata, e.g. the request ID
+// set by a middleware, appended to the metadata already carried by ctx. Slices and maps provided in place of a key
+// are expanded the same way WithMetadata does. Use GetMetadataContext to log it along with the metadata of an error.
+func ContextWithMetadata(ctx context.Context, keyValues ...any) context.Context {
+	added := addPaddingForMissingValue(flattenKeyValues(keyValues))
+	if len(added) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, contextMetadataKey{}, slices.Concat(MetadataFromContext(ctx), added))
+}
+
+// MetadataFromContext returns the metadata stored in ctx with ContextWithMetadata, or nil.
+// The returned slice must not be modified.
+func MetadataFromContext(ctx context.Context) []any {
+	if ctx == nil {
+		return nil
+	}
+	metadata, _ := ctx.Value(contextMetadataKey{}).([]any)
+	return metadata
+}
+
+// GetMetadataContext returns the metadata of the error, as returned by GetMetadata, merged with the metadata
+// stored in ctx with ContextWithMetadata, to log both at once:
+//
+//	slog.Error("request failed", errhelper.GetMetadataContext(ctx, err)...)
+//
+// The metadata of the error takes precedence over the metadata of ctx, which takes precedence over
+// the global metadata: the pairs come in this order, from the lowest to the highest precedence.
+// A nil ctx carries no metadata, and a nil error only returns the metadata of ctx.
+// The returned slice is always freshly allocated.
+func GetMetadataContext(ctx context.Context, err error) []any {
+	ambient := MetadataFromContext(ctx)
+	if err == nil {
+		return slices.Clone(ambient)
+	}
+	var visited visitedErrors
+	layers := appendLayers(nil, err, &visited)
+	metadata := slices.Concat(getGlobalMetadata(), ambient)
+	for _, layer := range layers {
+		metadata = append(metadata, layer...)
+	}
+	return metadata
+}
+
+// DeferContext returns a metadata value reading the metadata stored in ctx with ContextWithMetadata
+// when the metadata of the error is read, e.g. when it's logged, rather than when it's attached:
+//
+//	err = errhelper.WithMetadata(err, "request", errhelper.DeferContext(ctx))
+//
+// The value is the map of the keys and values of the ambient metadata, the last value of a key winning,
+// or nil if ctx carries none. It is read again on every read of the metadata, including GetMetadata,
+// GetMetadataMap, All, and the status built by GRPCStatus.
+//
+// Reading it is safe after ctx is canceled or its deadline exceeded, as the values of a context outlive
+// its cancellation. Every read takes a snapshot of the ambient metadata into a new map, so the rendered values
+// don't change afterwards. The cancellation itself is not reported, use WrapCtx for that.
+// A nil ctx carries no metadata.
+func DeferContext(ctx context.Context) any {
+	return lazyValue(func() any {
+		ambient := MetadataFromContext(ctx)
+		if len(ambient) == 0 {
+			return nil
+		}
+		return dedupMetadata(ambient)
+	})
+}
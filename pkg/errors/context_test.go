@@ -0,0 +1,193 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type contextKey string
+
+func registerContextExtractor(t *testing.T, key contextKey) {
+	t.Helper()
+	RegisterContextExtractor(func(ctx context.Context) []any {
+		if v, ok := ctx.Value(key).(string); ok {
+			return []any{string(key), v, "source", string(key)}
+		}
+		return nil
+	})
+	t.Cleanup(ClearContextExtractors)
+}
+
+func TestWithMetadataContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), contextKey("trace_id"), "t1")
+	require.NoError(t, WithMetadataContext(ctx, nil, "key", "value"))
+
+	// Without extractors, it behaves like WithMetadata.
+	err := WithMetadataContext(ctx, errors.New("foo"), "key", "value")
+	require.Equal(t, []any{"key", "value"}, GetMetadata(err))
+
+	registerContextExtractor(t, "trace_id")
+	registerContextExtractor(t, "request_id")
+	RegisterContextExtractor(nil)
+
+	err = WithMetadataContext(ctx, errors.New("foo"), "key", "value")
+	require.Equal(t, map[string]any{"trace_id": "t1", "source": "trace_id", "key": "value"}, GetMetadataMap(err))
+
+	// The extractor registered last wins.
+	ctx = context.WithValue(ctx, contextKey("request_id"), "r1")
+	err = WithMetadataContext(ctx, errors.New("foo"))
+	require.Equal(t, map[string]any{"trace_id": "t1", "request_id": "r1", "source": "request_id"}, GetMetadataMap(err))
+
+	// Extracted pairs have the lowest precedence.
+	SetGlobalMetadata("trace_id", "global")
+	t.Cleanup(func() { SetGlobalMetadata() })
+	err = WithMetadataContext(ctx, WithMetadata(errors.New("foo"), "trace_id", "inner"), "source", "explicit")
+	require.Equal(t, map[string]any{"trace_id": "inner", "request_id": "r1", "source": "explicit"}, GetMetadataMap(err))
+	err = WithMetadataContext(ctx, errors.New("foo"))
+	require.Equal(t, map[string]any{"trace_id": "t1", "request_id": "r1", "source": "request_id"}, GetMetadataMap(err))
+}
+
+func TestWrapCtx(t *testing.T) {
+	require.NoError(t, WrapCtx(context.Background(), nil, "key", "value"))
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	testCases := []struct {
+		name             string
+		ctx              context.Context
+		err              error
+		expectedCode     codes.Code
+		expectedMetadata map[string]any
+	}{
+		{
+			name:             "active context",
+			ctx:              context.Background(),
+			err:              errors.New("foo"),
+			expectedCode:     codes.Unknown,
+			expectedMetadata: map[string]any{"key": "value"},
+		},
+		{
+			name:             "nil context",
+			ctx:              nil,
+			err:              errors.New("foo"),
+			expectedCode:     codes.Unknown,
+			expectedMetadata: map[string]any{"key": "value"},
+		},
+		{
+			name:             "canceled context",
+			ctx:              canceled,
+			err:              errors.New("foo"),
+			expectedCode:     codes.Canceled,
+			expectedMetadata: map[string]any{"ctx_err": "context canceled", "key": "value"},
+		},
+		{
+			name:             "expired context",
+			ctx:              expired,
+			err:              status.Error(codes.Unknown, "foo"),
+			expectedCode:     codes.DeadlineExceeded,
+			expectedMetadata: map[string]any{"ctx_err": "context deadline exceeded", "key": "value"},
+		},
+		{
+			name:             "more specific code",
+			ctx:              expired,
+			err:              WithCode(errors.New("foo"), codes.Unavailable),
+			expectedCode:     codes.Unavailable,
+			expectedMetadata: map[string]any{"ctx_err": "context deadline exceeded", "key": "value"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := WrapCtx(tc.ctx, tc.err, "key", "value")
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.expectedCode, CodeOf(err))
+			require.Equal(t, tc.expectedMetadata, GetMetadataMap(err))
+		})
+	}
+}
+
+func TestGetMetadataContext(t *testing.T) {
+	ctx := ContextWithMetadata(context.Background(), "request_id", "r1", "tenant", "t1")
+	ctx = ContextWithMetadata(ctx, []any{"route", "/search"})
+	require.Equal(t, ctx, ContextWithMetadata(ctx))
+	require.Equal(t, []any{"request_id", "r1", "tenant", "t1", "route", "/search"}, MetadataFromContext(ctx))
+
+	// Branches don't share their metadata.
+	branch := ContextWithMetadata(ctx, "branch", 1)
+	require.Equal(t, []any{"request_id", "r1", "tenant", "t1", "route", "/search"}, MetadataFromContext(ctx))
+	require.Len(t, MetadataFromContext(branch), 8)
+
+	SetGlobalMetadata("version", "1.0", "tenant", "global")
+	t.Cleanup(func() { SetGlobalMetadata() })
+	err := WithMetadata(errors.New("foo"), "tenant", "t2", "key", "value")
+	require.Equal(t, []any{
+		"version", "1.0", "tenant", "global",
+		"request_id", "r1", "tenant", "t1", "route", "/search",
+		"tenant", "t2", "key", "value",
+	}, GetMetadataContext(ctx, err))
+	require.Equal(t, map[string]any{
+		"version": "1.0", "request_id": "r1", "route": "/search", "tenant": "t2", "key": "value",
+	}, toMetadataMap(GetMetadataContext(ctx, err)))
+
+	// Nil context and error.
+	require.Equal(t, GetMetadata(err), GetMetadataContext(nil, err)) // nolint: staticcheck // a nil context is handled
+	require.Equal(t, MetadataFromContext(ctx), GetMetadataContext(ctx, nil))
+	require.Empty(t, GetMetadataContext(context.Background(), nil))
+}
+
+// populatedContext carries ambient metadata populated after it's created, e.g. by a later middleware.
+type populatedContext struct {
+	context.Context
+	metadata []any
+}
+
+func (c *populatedContext) Value(key any) any {
+	if _, ok := key.(contextMetadataKey); ok {
+		return c.metadata
+	}
+	return c.Context.Value(key)
+}
+
+func TestDeferContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	populated := &populatedContext{Context: ctx}
+	err := WithMetadata(errors.New("foo"), "request", DeferContext(populated), "key", "value")
+	require.Equal(t, []any{"request", nil, "key", "value"}, GetMetadata(err))
+
+	// The metadata is read when the error is rendered.
+	populated.metadata = []any{"request_id", "r1", "route", "/search", "request_id", "r2"}
+	cancel()
+	expected := map[string]any{"request_id": "r2", "route": "/search"}
+	require.Equal(t, []any{"request", expected, "key", "value"}, GetMetadata(err))
+	require.Equal(t, expected, GetMetadataMap(err)["request"])
+	for key, value := range All(err) {
+		if key == "request" {
+			require.Equal(t, expected, value)
+		}
+	}
+	require.Equal(t, []any{"request_id", "r1", "route", "/search", "request_id", "r2", "request", expected, "key", "value"},
+		GetMetadataContext(populated, err))
+	received := status.FromProto(status.Convert(err).Proto()).Err()
+	require.Equal(t, map[string]any{"request_id": "r2", "route": "/search"}, GetMetadataMap(received)["request"])
+
+	// The readers of single layers see the resolved value as well.
+	var metadataErr MetadataError
+	require.ErrorAs(t, err, &metadataErr)
+	require.Equal(t, []any{"request", expected, "key", "value"}, metadataErr.Metadata())
+	require.Equal(t, []any{"request", expected, "key", "value"}, Layers(err)[0].Metadata)
+	require.Empty(t, ConflictingKeys(WithMetadata(err, "request", DeferContext(populated))))
+
+	// Every read takes a new snapshot.
+	GetMetadataMap(err)["request"].(map[string]any)["route"] = "modified"
+	require.Equal(t, expected, GetMetadataMap(err)["request"])
+
+	require.Equal(t, []any{"request", nil}, GetMetadata(WithMetadata(errors.New("foo"), "request", DeferContext(nil)))) // nolint: staticcheck // a nil context is handled
+}
@@ -0,0 +1,49 @@
+package errors
+
+import (
+	"fmt"
+	"maps"
+)
+
+// WithCorrelation returns the provided error wrapped with correlation IDs, such as the trace, span, request
+// and session IDs, grouped under a single nested "correlation" object rather than as flat keys.
+// The IDs are merged with the ones already attached to the error, the provided ones taking precedence,
+// so the outermost correlation object holds the union of all of them. It's sent over gRPC
+// as a nested struct. It returns nil for a nil error, and the error unchanged if there are no IDs.
+func WithCorrelation(err error, ids map[string]string) error {
+	if err == nil || len(ids) == 0 {
+		return err
+	}
+	correlation := CorrelationOf(err)
+	if correlation == nil {
+		correlation = make(map[string]string, len(ids))
+	}
+	maps.Copy(correlation, ids)
+	return WithMetadata(err, correlationKey, correlation)
+}
+
+// CorrelationOf returns a copy of the correlation IDs attached to the error with WithCorrelation, or nil if none.
+// Values received over gRPC as other types are formatted with fmt.Sprint.
+func CorrelationOf(err error) map[string]string {
+	v, ok := lookup(err, correlationKey)
+	if !ok {
+		return nil
+	}
+	switch ids := v.(type) {
+	case map[string]string:
+		return maps.Clone(ids)
+	case map[string]any:
+		// Nested maps are received over gRPC as map[string]any.
+		correlation := make(map[string]string, len(ids))
+		for key, id := range ids {
+			if s, ok := id.(string); ok {
+				correlation[key] = s
+			} else {
+				correlation[key] = fmt.Sprint(id)
+			}
+		}
+		return correlation
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,41 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWithCorrelation(t *testing.T) {
+	require.NoError(t, WithCorrelation(nil, map[string]string{"trace": "t1"}))
+	base := errors.New("foo")
+	require.Equal(t, base, WithCorrelation(base, nil))
+	require.Nil(t, CorrelationOf(base))
+	require.Nil(t, CorrelationOf(WithMetadata(base, "correlation", "not an object")))
+
+	ids := map[string]string{"trace": "t1", "span": "s1"}
+	err := WithCorrelation(base, ids)
+	require.Equal(t, ids, CorrelationOf(fmt.Errorf("bar: %w", err)))
+	require.Equal(t, map[string]any{"correlation": ids}, GetMetadataMap(err))
+
+	// The provided IDs are not aliased.
+	ids["trace"] = "changed"
+	require.Equal(t, map[string]string{"trace": "t1", "span": "s1"}, CorrelationOf(err))
+	CorrelationOf(err)["trace"] = "changed"
+	require.Equal(t, map[string]string{"trace": "t1", "span": "s1"}, CorrelationOf(err))
+
+	// The IDs are merged, the outer ones taking precedence.
+	err = WithCorrelation(err, map[string]string{"span": "s2", "request": "r1"})
+	require.Equal(t, map[string]string{"trace": "t1", "span": "s2", "request": "r1"}, CorrelationOf(err))
+
+	// The IDs survive a gRPC round trip as a nested struct.
+	received := roundTrip(t, WithCorrelation(status.Error(codes.NotFound, "not found"), map[string]string{"trace": "t1"}))
+	require.Equal(t, map[string]any{"trace": "t1"}, GetMetadataMap(received)["correlation"])
+	require.Equal(t, map[string]string{"trace": "t1"}, CorrelationOf(received))
+	err = WithCorrelation(received, map[string]string{"session": "x"})
+	require.Equal(t, map[string]string{"trace": "t1", "session": "x"}, CorrelationOf(err))
+}
@@ -0,0 +1,73 @@
+package errors
+
+import (
+	"reflect"
+	"slices"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+)
+
+// detailsTruncatedKey holds the number of details dropped by GRPCStatus to honor MaxDetails.
+const detailsTruncatedKey = "details_truncated"
+
+// detailPriority ranks the details of a status, lower is more important.
+func detailPriority(detail proto.Message) int {
+	switch detail.(type) {
+	case *errdetails.ErrorInfo:
+		return 0
+	case *errdetails.RetryInfo:
+		return 1
+	case *errdetails.QuotaFailure:
+		return 2
+	case *errdetails.BadRequest:
+		return 3
+	case *errdetails.PreconditionFailure:
+		return 4
+	case *errdetails.ResourceInfo:
+		return 5
+	case *errdetails.LocalizedMessage:
+		return 6
+	case *errdetails.Help:
+		return 7
+	case *errdetails.RequestInfo:
+		return 8
+	case *errdetails.DebugInfo:
+		return 9
+	default:
+		return 10
+	}
+}
+
+// truncateDetails keeps the n most important details, in their original order,
+// and returns the number of dropped details.
+func truncateDetails(details []proto.Message, n int) ([]proto.Message, int) {
+	n = max(n, 0)
+	if len(details) <= n {
+		return details, 0
+	}
+	indexes := make([]int, len(details))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	slices.SortStableFunc(indexes, func(a, b int) int {
+		return detailPriority(details[a]) - detailPriority(details[b])
+	})
+	kept := indexes[:n]
+	slices.Sort(kept)
+	truncated := make([]proto.Message, 0, n)
+	for _, i := range kept {
+		truncated = append(truncated, details[i])
+	}
+	return truncated, len(details) - n
+}
+
+// needsTypeHints reports whether the metadata has values sent with a type hint.
+func needsTypeHints(metadata map[string]any) bool {
+	for _, v := range metadata {
+		if _, ok := typeHints[reflect.TypeOf(v)]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package errors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestMaxDetails(t *testing.T) {
+	debugInfo := &errdetails.DebugInfo{Detail: "stack"}
+	errorInfo := &errdetails.ErrorInfo{Reason: "REASON", Domain: "qdrant.tech"}
+	badRequest := &errdetails.BadRequest{}
+	retryInfo := &errdetails.RetryInfo{RetryDelay: durationpb.New(0)}
+	localizedMessage := &errdetails.LocalizedMessage{Locale: "en", Message: "message"}
+	st, err := status.New(codes.InvalidArgument, "invalid").WithDetails(debugInfo, errorInfo, badRequest, retryInfo, localizedMessage)
+	require.NoError(t, err)
+	grpcErr := st.Err()
+
+	testCases := []struct {
+		name             string
+		maxDetails       int
+		err              error
+		expectedDetails  []proto.Message
+		expectedMetadata map[string]any
+	}{
+		{
+			name:             "no limit",
+			err:              WithMetadata(grpcErr, "key", "value"),
+			expectedDetails:  []proto.Message{debugInfo, errorInfo, badRequest, retryInfo, localizedMessage},
+			expectedMetadata: map[string]any{"key": "value"},
+		},
+		{
+			name:             "within the limit",
+			maxDetails:       6,
+			err:              WithMetadata(grpcErr, "key", "value"),
+			expectedDetails:  []proto.Message{debugInfo, errorInfo, badRequest, retryInfo, localizedMessage},
+			expectedMetadata: map[string]any{"key": "value"},
+		},
+		{
+			name:             "least important details dropped",
+			maxDetails:       4,
+			err:              WithMetadata(grpcErr, "key", "value"),
+			expectedDetails:  []proto.Message{errorInfo, retryInfo},
+			expectedMetadata: map[string]any{"key": "value", "details_truncated": 3},
+		},
+		{
+			name:             "without metadata",
+			maxDetails:       3,
+			err:              WithCode(grpcErr, codes.Internal),
+			expectedDetails:  []proto.Message{errorInfo},
+			expectedMetadata: map[string]any{"details_truncated": 4},
+		},
+		{
+			name:             "metadata is always kept",
+			maxDetails:       1,
+			err:              WithMetadata(grpcErr, "key", "value"),
+			expectedDetails:  []proto.Message{},
+			expectedMetadata: map[string]any{"key": "value", "details_truncated": 5},
+		},
+		{
+			name:             "no details to drop",
+			maxDetails:       1,
+			err:              WithMetadata(errors.New("foo"), "count", 1),
+			expectedDetails:  []proto.Message{},
+			expectedMetadata: map[string]any{"count": 1},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			Configure(MaxDetails(tc.maxDetails))
+			t.Cleanup(func() { Configure() })
+
+			received, ok := status.FromError(roundTrip(t, tc.err))
+			require.True(t, ok)
+			var details []proto.Message
+			for _, detail := range received.Details() {
+				if s, ok := detail.(*structpb.Struct); ok && (isMetadataStruct(s) || isTypeHintsStruct(s)) {
+					continue
+				}
+				details = append(details, detail.(proto.Message))
+			}
+			require.Len(t, details, len(tc.expectedDetails))
+			for i := range details {
+				require.True(t, proto.Equal(tc.expectedDetails[i], details[i]))
+			}
+			require.Equal(t, tc.expectedMetadata, GetMetadataMap(received.Err()))
+			if tc.maxDetails > 1 {
+				require.LessOrEqual(t, len(received.Details()), tc.maxDetails)
+			}
+		})
+	}
+}
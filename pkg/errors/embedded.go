@@ -0,0 +1,113 @@
+package errors
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ParseEmbedded returns the provided error wrapped with the metadata embedded as JSON by legacy producers,
+// to migrate them to proper metadata without rewriting them first. The embedded metadata is a JSON object
+// held by a field named jsonKey of a JSON object found in the message of the error or in a string metadata value,
+// e.g. `query failed: {"context": {"collection": "a", "shard": 1}}`, or a JSON object held as a string
+// under the jsonKey metadata key. Its fields are promoted to metadata, the keys already set in the error chain
+// being kept, while the keys of the global metadata are overridden as usual. Integers are decoded as int64, other numbers as float64.
+// The first embedded object found is used, the message being searched first.
+// It returns the error unchanged if no embedded JSON is found.
+func ParseEmbedded(err error, jsonKey string) error {
+	if err == nil {
+		return nil
+	}
+	fields, ok := findEmbedded(err.Error(), jsonKey)
+	if !ok {
+		for key, value := range All(err) {
+			s, isString := value.(string)
+			if !isString {
+				continue
+			}
+			if key == jsonKey {
+				fields, ok = decodeEmbedded([]byte(strings.TrimSpace(s)))
+			}
+			if !ok {
+				fields, ok = findEmbedded(s, jsonKey)
+			}
+			if ok {
+				break
+			}
+		}
+	}
+	if !ok {
+		return err
+	}
+	existing := chainKeys(err)
+	metadata := make([]any, 0, len(fields)*2)
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		if _, exists := existing[key]; !exists {
+			metadata = append(metadata, key, fields[key])
+		}
+	}
+	return WithMetadata(err, metadata...)
+}
+
+// findEmbedded returns the fields of the object held by the jsonKey field of the first JSON object of s having one.
+func findEmbedded(s, jsonKey string) (map[string]any, bool) {
+	for i := strings.IndexByte(s, '{'); i >= 0; {
+		var object map[string]json.RawMessage
+		dec := json.NewDecoder(strings.NewReader(s[i:]))
+		next := i + 1
+		if dec.Decode(&object) == nil {
+			if raw, ok := object[jsonKey]; ok {
+				if fields, ok := decodeEmbedded(raw); ok {
+					return fields, true
+				}
+			}
+			next = i + int(dec.InputOffset())
+		}
+		j := strings.IndexByte(s[next:], '{')
+		if j < 0 {
+			break
+		}
+		i = next + j
+	}
+	return nil, false
+}
+
+// decodeEmbedded decodes a JSON object, integers being decoded as int64 and other numbers as float64.
+func decodeEmbedded(data []byte) (map[string]any, bool) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil || fields == nil {
+		return nil, false
+	}
+	for key, value := range fields {
+		fields[key] = fromJSONNumbers(value)
+	}
+	return fields, true
+}
+
+// fromJSONNumbers converts the json.Number values, nested or not, to int64 or float64.
+func fromJSONNumbers(v any) any {
+	switch x := v.(type) {
+	case json.Number:
+		if n, err := x.Int64(); err == nil {
+			return n
+		}
+		f, _ := x.Float64()
+		return f
+	case map[string]any:
+		for key, value := range x {
+			x[key] = fromJSONNumbers(value)
+		}
+		return x
+	case []any:
+		for i, value := range x {
+			x[i] = fromJSONNumbers(value)
+		}
+		return x
+	default:
+		return v
+	}
+}
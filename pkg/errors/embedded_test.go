@@ -0,0 +1,85 @@
+package errors
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEmbedded(t *testing.T) {
+	require.NoError(t, ParseEmbedded(nil, "context"))
+
+	testCases := []struct {
+		name     string
+		err      error
+		expected map[string]any
+	}{
+		{
+			name:     "no JSON",
+			err:      errors.New("query failed {not json"),
+			expected: map[string]any{},
+		},
+		{
+			name:     "JSON without the key",
+			err:      errors.New(`query failed: {"other": {"a": 1}}`),
+			expected: map[string]any{},
+		},
+		{
+			name:     "key not holding an object",
+			err:      errors.New(`query failed: {"context": "a"}`),
+			expected: map[string]any{},
+		},
+		{
+			name: "message",
+			err:  errors.New(`query failed: {"other": 1} {"context": {"collection": "a", "shard": 1, "ratio": 0.5, "ids": [1, 2], "nested": {"n": 3}}} trailing`),
+			expected: map[string]any{
+				"collection": "a", "shard": int64(1), "ratio": 0.5,
+				"ids": []any{int64(1), int64(2)}, "nested": map[string]any{"n": int64(3)},
+			},
+		},
+		{
+			name:     "nested in a JSON object",
+			err:      errors.New(`{"error": {"context": {"collection": "a"}}, "context": {"collection": "b"}}`),
+			expected: map[string]any{"collection": "b"},
+		},
+		{
+			name:     "metadata value",
+			err:      WithMetadata(errors.New("query failed"), "details", `{"context": {"collection": "a"}}`),
+			expected: map[string]any{"details": `{"context": {"collection": "a"}}`, "collection": "a"},
+		},
+		{
+			name:     "metadata value under the key",
+			err:      WithMetadata(errors.New("query failed"), "context", ` {"collection": "a"} `),
+			expected: map[string]any{"context": ` {"collection": "a"} `, "collection": "a"},
+		},
+		{
+			name:     "existing keys are kept",
+			err:      WithMetadata(errors.New(`query failed: {"context": {"collection": "a", "shard": 1}}`), "collection", "b"),
+			expected: map[string]any{"collection": "b", "shard": int64(1)},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ParseEmbedded(tc.err, "context")
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.expected, GetMetadataMap(err))
+		})
+	}
+
+	// No-op without embedded JSON.
+	err := errors.New("foo")
+	require.Equal(t, err, ParseEmbedded(err, "context"))
+}
+
+func TestParseEmbeddedGlobals(t *testing.T) {
+	t.Cleanup(ResetGlobals)
+	SetGlobalMetadata("collection", "global")
+	SetKeyTransformer(strings.ToUpper)
+
+	// Only the keys of the chain are kept, compared before their transformation.
+	err := ParseEmbedded(WithMetadata(errors.New(`query failed: {"context": {"collection": "a", "shard": 1}}`), "shard", 2), "context")
+	require.Equal(t, []any{"global", "a"}, GetMetadataValues(err, "collection"))
+	require.Equal(t, []any{2}, GetMetadataValues(err, "shard"))
+}
@@ -0,0 +1,186 @@
+package errors
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	// encodedTypeKey is a special key used to identify a structpb.Struct value
+	// produced by a registered encoder. It holds the name of the original Go type.
+	encodedTypeKey = "__qdrant_type__"
+	// encodedValueKey holds the encoded value next to encodedTypeKey.
+	encodedValueKey = "value"
+	// maxWireDepth is the number of nested maps and slices converted into protobuf structs and lists,
+	// deeper values are sent as their string representation.
+	maxWireDepth = 16
+)
+
+// EncoderFunc converts a metadata value into a value that can be represented
+// as a protobuf Struct value (nil, bool, numbers, string, []any or map[string]any).
+type EncoderFunc func(v any) (any, error)
+
+// DecoderFunc reconstructs a metadata value from the value produced by the matching EncoderFunc.
+type DecoderFunc func(v any) (any, error)
+
+// codecRegistry holds the registered encoders and decoders.
+type codecRegistry struct {
+	mu       sync.RWMutex
+	encoders map[reflect.Type]EncoderFunc
+	decoders map[string]DecoderFunc
+}
+
+var codecs = &codecRegistry{
+	encoders: map[reflect.Type]EncoderFunc{},
+	decoders: map[string]DecoderFunc{},
+}
+
+// RegisterEncoder registers a function used to encode metadata values of type t
+// when the error is converted to a gRPC status.
+// Encoders are looked up by the exact dynamic type of the value and are consulted
+// before the default coercion, which uses the native protobuf representation if
+// there is one, or fmt.Sprint otherwise. If the encoder fails, the default coercion is used.
+// Registering an encoder for a type that already has one replaces it.
+//
+// The registry is part of the global configuration, see the package documentation.
+func RegisterEncoder(t reflect.Type, fn EncoderFunc) {
+	codecs.mu.Lock()
+	defer codecs.mu.Unlock()
+	codecs.encoders[t] = fn
+}
+
+// RegisterDecoder registers a function used by GetMetadata to reconstruct metadata values
+// of type t received in a gRPC status, which were produced by an encoder registered for the same type.
+// If no decoder is registered, or the decoder fails, the encoded value is returned as is.
+// Registering a decoder for a type that already has one replaces it.
+//
+// The registry is part of the global configuration, see the package documentation.
+func RegisterDecoder(t reflect.Type, fn DecoderFunc) {
+	codecs.mu.Lock()
+	defer codecs.mu.Unlock()
+	codecs.decoders[typeName(t)] = fn
+}
+
+func (r *codecRegistry) encoder(t reflect.Type) (EncoderFunc, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fn, ok := r.encoders[t]
+	return fn, ok
+}
+
+func (r *codecRegistry) decoder(name string) (DecoderFunc, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fn, ok := r.decoders[name]
+	return fn, ok
+}
+
+// typeName returns a name identifying the type on the wire.
+func typeName(t reflect.Type) string {
+	if t.Name() != "" && t.PkgPath() != "" {
+		return t.PkgPath() + "." + t.Name()
+	}
+	return t.String()
+}
+
+// toWireValue converts a metadata value into a protobuf Struct value.
+// Values with a registered encoder are encoded and tagged with their type name,
+// so the matching decoder can reconstruct them on the receiving side.
+func toWireValue(v any) *structpb.Value {
+	if v != nil {
+		t := reflect.TypeOf(v)
+		if enc, ok := codecs.encoder(t); ok {
+			if encoded, err := enc(v); err == nil {
+				return structpb.NewStructValue(&structpb.Struct{
+					Fields: map[string]*structpb.Value{
+						encodedTypeKey:  structpb.NewStringValue(typeName(t)),
+						encodedValueKey: coerceWireValue(encoded),
+					},
+				})
+			}
+		}
+	}
+	return coerceWireValue(v)
+}
+
+// coerceWireValue converts the value using its native protobuf representation,
+// falling back to its string representation. Maps with string keys and slices of any type
+// are converted into protobuf structs and lists, their elements being converted the same way,
+// down to maxWireDepth levels of nesting.
+func coerceWireValue(v any) *structpb.Value {
+	return coerceNestedWireValue(v, 0)
+}
+
+func coerceNestedWireValue(v any, depth int) *structpb.Value {
+	if pv, err := structpb.NewValue(v); err == nil {
+		return pv
+	}
+	if depth < maxWireDepth {
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Map:
+			if rv.Type().Key().Kind() == reflect.String {
+				fields := make(map[string]*structpb.Value, rv.Len())
+				for iter := rv.MapRange(); iter.Next(); {
+					fields[iter.Key().String()] = coerceNestedWireValue(iter.Value().Interface(), depth+1)
+				}
+				return structpb.NewStructValue(&structpb.Struct{Fields: fields})
+			}
+		case reflect.Slice, reflect.Array:
+			values := make([]*structpb.Value, rv.Len())
+			for i := range values {
+				values[i] = coerceNestedWireValue(rv.Index(i).Interface(), depth+1)
+			}
+			return structpb.NewListValue(&structpb.ListValue{Values: values})
+		}
+	}
+	return structpb.NewStringValue(fmt.Sprint(v))
+}
+
+// fromWireValue converts a protobuf Struct value back into a metadata value,
+// decoding values tagged by a registered encoder.
+func fromWireValue(v *structpb.Value) any {
+	fields := v.GetStructValue().GetFields()
+	tag, tagged := fields[encodedTypeKey]
+	encoded, hasValue := fields[encodedValueKey]
+	if !tagged || !hasValue || len(fields) != 2 {
+		return v.AsInterface()
+	}
+	if dec, ok := codecs.decoder(tag.GetStringValue()); ok {
+		if decoded, err := dec(encoded.AsInterface()); err == nil {
+			return decoded
+		}
+	}
+	return encoded.AsInterface()
+}
+
+// newMetadataStructs builds the protobuf Struct, tagged with our marker, carrying the provided metadata,
+// along with the Struct holding the type hints of its values, tagged with the type hints marker.
+// The type hints are nil if no value needs one.
+func newMetadataStructs(metadata map[string]any) (values, hints *structpb.Struct) {
+	fields := make(map[string]*structpb.Value, len(metadata)+1)
+	var hintFields map[string]*structpb.Value
+	for key, val := range metadata {
+		if _, ok := codecs.encoder(reflect.TypeOf(val)); !ok {
+			if wireValue, hint, ok := toHintedWireValue(val); ok {
+				fields[key] = wireValue
+				if hintFields == nil {
+					hintFields = map[string]*structpb.Value{typeHintsMarker: structpb.NewBoolValue(true)}
+				}
+				hintFields[key] = structpb.NewStringValue(hint)
+				continue
+			}
+		}
+		fields[key] = toWireValue(val)
+	}
+	// Add our marker to identify this struct as our own.
+	fields[qdrantMetadataMarker] = structpb.NewBoolValue(true)
+	values = &structpb.Struct{Fields: fields}
+	if hintFields != nil {
+		hints = &structpb.Struct{Fields: hintFields}
+	}
+	return values, hints
+}
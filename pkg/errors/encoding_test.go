@@ -0,0 +1,168 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type money struct {
+	cents    int64
+	currency string
+}
+
+func (m money) String() string {
+	return fmt.Sprintf("%d.%02d %s", m.cents/100, m.cents%100, m.currency)
+}
+
+func registerMoneyCodec(t *testing.T) {
+	t.Helper()
+	moneyType := reflect.TypeOf(money{})
+	RegisterEncoder(moneyType, func(v any) (any, error) {
+		m := v.(money)
+		return fmt.Sprintf("%d %s", m.cents, m.currency), nil
+	})
+	RegisterDecoder(moneyType, func(v any) (any, error) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.New("money must be encoded as string")
+		}
+		amount, currency, found := strings.Cut(s, " ")
+		if !found {
+			return nil, errors.New("invalid money encoding")
+		}
+		cents, err := strconv.ParseInt(amount, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return money{cents: cents, currency: currency}, nil
+	})
+	t.Cleanup(func() {
+		codecs.mu.Lock()
+		defer codecs.mu.Unlock()
+		delete(codecs.encoders, moneyType)
+		delete(codecs.decoders, typeName(moneyType))
+	})
+}
+
+// roundTrip simulates sending the error over the wire and receiving it on the other side.
+func roundTrip(t *testing.T, err error) error {
+	t.Helper()
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	return status.FromProto(st.Proto()).Err()
+}
+
+func TestEncoderRegistry(t *testing.T) {
+	registerMoneyCodec(t)
+	price := money{cents: 1250, currency: "EUR"}
+
+	received := roundTrip(t, WithMetadata(status.Error(codes.Aborted, "payment failed"), "price", price, "item", "book"))
+
+	require.ElementsMatch(t, []any{"price", price, "item", "book"}, GetMetadata(received))
+}
+
+func TestEncoderWithoutDecoder(t *testing.T) {
+	moneyType := reflect.TypeOf(money{})
+	RegisterEncoder(moneyType, func(v any) (any, error) {
+		return v.(money).cents, nil
+	})
+	t.Cleanup(func() {
+		codecs.mu.Lock()
+		defer codecs.mu.Unlock()
+		delete(codecs.encoders, moneyType)
+	})
+
+	received := roundTrip(t, WithMetadata(errors.New("foo"), "price", money{cents: 100, currency: "EUR"}))
+
+	// Without a decoder the encoded value is returned.
+	require.Equal(t, []any{"price", float64(100)}, GetMetadata(received))
+}
+
+func TestDefaultCoercion(t *testing.T) {
+	// Values without an encoder that protobuf cannot represent are converted using fmt.Sprint,
+	// rather than dropping the whole metadata.
+	received := roundTrip(t, WithMetadata(errors.New("foo"), "price", money{cents: 1250, currency: "EUR"}, "key", "value"))
+
+	require.ElementsMatch(t, []any{"price", "12.50 EUR", "key", "value"}, GetMetadata(received))
+}
+
+func TestFailingEncoderFallsBack(t *testing.T) {
+	moneyType := reflect.TypeOf(money{})
+	RegisterEncoder(moneyType, func(any) (any, error) {
+		return nil, errors.New("boom")
+	})
+	t.Cleanup(func() {
+		codecs.mu.Lock()
+		defer codecs.mu.Unlock()
+		delete(codecs.encoders, moneyType)
+	})
+
+	received := roundTrip(t, WithMetadata(errors.New("foo"), "price", money{cents: 1250, currency: "EUR"}))
+
+	require.Equal(t, []any{"price", "12.50 EUR"}, GetMetadata(received))
+}
+
+func TestNestedWireValues(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    any
+		expected any
+	}{
+		{
+			name:     "two levels",
+			value:    map[string]any{"inner": map[string]any{"key": "value", "enabled": true}},
+			expected: map[string]any{"inner": map[string]any{"key": "value", "enabled": true}},
+		},
+		{
+			name:     "three levels",
+			value:    map[string]any{"inner": map[string]any{"list": []any{"a", map[string]any{"key": "value"}}}},
+			expected: map[string]any{"inner": map[string]any{"list": []any{"a", map[string]any{"key": "value"}}}},
+		},
+		{
+			name:     "typed maps",
+			value:    map[string]map[string]string{"inner": {"key": "value"}},
+			expected: map[string]any{"inner": map[string]any{"key": "value"}},
+		},
+		{
+			name:     "typed maps nested in native maps",
+			value:    map[string]any{"inner": map[string][]string{"list": {"a", "b"}}},
+			expected: map[string]any{"inner": map[string]any{"list": []any{"a", "b"}}},
+		},
+		{
+			name:     "typed slices",
+			value:    [][]int{{1, 2}, {3}},
+			expected: []any{[]any{1.0, 2.0}, []any{3.0}},
+		},
+		{
+			name:     "custom type nested in a map",
+			value:    map[string]any{"price": money{cents: 100, currency: "EUR"}},
+			expected: map[string]any{"price": "1.00 EUR"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			received := roundTrip(t, WithMetadata(errors.New("foo"), "details", tc.value))
+			require.Equal(t, map[string]any{"details": tc.expected}, GetMetadataMap(received))
+		})
+	}
+}
+
+func TestWireValueDepth(t *testing.T) {
+	var value any = "leaf"
+	for range maxWireDepth + 1 {
+		value = map[string]map[string]any{"inner": {"value": value}}
+	}
+	wireValue := coerceWireValue(value)
+	for range maxWireDepth / 2 {
+		wireValue = wireValue.GetStructValue().GetFields()["inner"].GetStructValue().GetFields()["value"]
+	}
+	require.Contains(t, wireValue.GetStringValue(), "leaf")
+}
@@ -0,0 +1,89 @@
+package errors
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxEnvValueBytes is the maximum length of the values returned by MetadataEnv, in bytes.
+const maxEnvValueBytes = 1024
+
+// MetadataEnv returns the metadata of the error as environment variables, e.g. to hand the context
+// of a failure to a diagnostic subprocess:
+//
+//	cmd := exec.Command("diagnose")
+//	cmd.Env = append(os.Environ(), errhelper.MetadataEnv(err, "QDRANT_ERR")...)
+//
+// Each key becomes PREFIX_KEY=value, the variables being sorted. Metadata is deduplicated the same way
+// as GetMetadataMap, error values being expanded and values redacted like Logfmt does. Names are uppercased, and the characters
+// other than ASCII letters, digits and underscores are replaced with underscores, empty keys becoming an underscore,
+// and an underscore is prepended to the names starting with a digit. When several keys end up with the same name,
+// the first key in sorted order wins.
+// Values are formatted with fmt.Sprint, their control characters, including newlines, are replaced with spaces,
+// and they are truncated to 1024 bytes. It returns nil for a nil error.
+func MetadataEnv(err error, prefix string) []string {
+	if err == nil {
+		return nil
+	}
+	metadata := toMetadataMap(expandErrorValues(GetMetadata(err)))
+	redactMap(metadata)
+	seen := make(map[string]struct{}, len(metadata))
+	env := make([]string, 0, len(metadata))
+	for _, key := range slices.Sorted(maps.Keys(metadata)) {
+		name := envName(key)
+		if prefix != "" {
+			name = envName(prefix) + "_" + name
+		}
+		if name[0] >= '0' && name[0] <= '9' {
+			name = "_" + name
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		env = append(env, name+"="+envValue(metadata[key]))
+	}
+	slices.Sort(env)
+	return env
+}
+
+// envName replaces the characters of the key that are not allowed in environment variable names,
+// an empty key becoming an underscore.
+func envName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+// envValue formats the value on a single line, truncated to maxEnvValueBytes at a rune boundary.
+func envValue(value any) string {
+	s := strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, fmt.Sprint(value))
+	if len(s) <= maxEnvValueBytes {
+		return s
+	}
+	cut := maxEnvValueBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
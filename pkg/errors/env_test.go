@@ -0,0 +1,38 @@
+package errors
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadataEnv(t *testing.T) {
+	require.Nil(t, MetadataEnv(nil, "ERR"))
+	require.Empty(t, MetadataEnv(errors.New("foo"), "ERR"))
+
+	err := WithMetadata(errors.New("foo"),
+		"collection", "books",
+		"shard.id", 3,
+		"shard_id", 4,
+		"2fa", true,
+		"", "empty",
+		"query", "line1\nline2\r\x00",
+		"cause", WithMetadata(errors.New("bar"), "attempt", 2),
+		"large", strings.Repeat("é", maxEnvValueBytes),
+	)
+	// "shard.id" comes before "shard_id" in sorted order, so it wins.
+	require.Equal(t, []string{
+		"QDRANT_ERR_2FA=true",
+		"QDRANT_ERR_CAUSE=bar",
+		"QDRANT_ERR_CAUSE_ATTEMPT=2",
+		"QDRANT_ERR_COLLECTION=books",
+		"QDRANT_ERR_LARGE=" + strings.Repeat("é", maxEnvValueBytes/2),
+		"QDRANT_ERR_QUERY=line1 line2  ",
+		"QDRANT_ERR_SHARD_ID=3",
+		"QDRANT_ERR__=empty",
+	}, MetadataEnv(err, "qdrant-err"))
+
+	require.Equal(t, []string{"_2FA=true"}, MetadataEnv(WithMetadata(errors.New("foo"), "2fa", true), ""))
+}
@@ -1,12 +1,31 @@
 // Package errors introduces utilities to wrap error with additional metadata
 // so that the error has proper context when it is logged.
 // The idea was borrowed from: https://medium.com/@oberonus/context-matters-advanced-error-handling-techniques-in-go-b470f763c7ec
+//
+// The errors returned by this package are immutable: wrapping an error never modifies it, and the metadata
+// is copied when it's attached. All the read operations, such as Error, GetMetadata, GetMetadataMap, All,
+// GRPCStatus, CodeOf and the other accessors, return values that are not shared with the error,
+// so an error can be read from many goroutines at once, as long as the values of its metadata,
+// e.g. maps and slices, aren't modified concurrently by their owner.
+//
+// # Global configuration
+//
+// A few settings are process-wide: the options set with Configure, the global metadata, the key transformer,
+// the metadata markers, the wrap observer, the encoders and decoders, the context extractors, the value redactors,
+// the default messages and the strict mode. Their setters are safe for concurrent use, however they are meant
+// to be called once at startup, e.g. in main or at init time, before errors are created or converted:
+// the errors handled while a setting changes may see either value. Services exchanging errors over gRPC
+// should agree on the settings affecting what is sent, such as the encoders, the decoders and the markers.
+// Tests changing these settings should restore the defaults with ResetGlobals.
 package errors
 
 import (
 	"errors"
+	"maps"
 	"reflect"
+	"slices"
 
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
@@ -23,6 +42,22 @@ type errWithMetadata struct {
 	err error
 	// metadata is the container for error context
 	metadata []any
+	// scope is the scope of the metadata, set with WithScopedMetadata
+	scope Scope
+	// sequence is the creation order of the layer, set if the SequenceLayers option is enabled
+	sequence uint64
+	// verbatim is set by FromStatus, to report the wrapped status unchanged
+	verbatim bool
+	// keyPrefix is the prefix of the keys of the metadata of the wrapped error, set with WithPrefixedMetadata
+	keyPrefix string
+	// highCardinality marks the keys of the metadata as unfit for metric labels, set with WithCardinalKey
+	highCardinality bool
+	// code is the gRPC code explicitly set for the error, valid only if hasCode is true
+	code    codes.Code
+	hasCode bool
+	// grpcMessage is the message of the gRPC status set for the error, valid only if hasGRPCMessage is true
+	grpcMessage    string
+	hasGRPCMessage bool
 }
 
 // Error returns the original error message,
@@ -34,179 +69,438 @@ func (w *errWithMetadata) Error() string {
 // GRPCStatus returns the gRPC status of the wrapped error, if it exists.
 // This makes errWithMetadata compatible with gRPC's error handling,
 // allowing it to preserve the original status code and message while
-// carrying additional metadata.
+// carrying additional metadata. The message is replaced by the one set with WithGRPCMessage, if any.
 // It achieves this by embedding the metadata into the status Details field
-// as a protobuf Struct.
+// as a protobuf Struct. Values are converted using the encoders registered with
+// RegisterEncoder, their native protobuf representation, or fmt.Sprint, in that order.
+// Maps with string keys and slices, nested or not, are converted into protobuf structs and lists,
+// and received as map[string]any and []any.
+// The number of details is capped by the MaxDetails option, and the size of the metadata
+// by the MaxMetadataBytes option, if configured.
 func (w *errWithMetadata) GRPCStatus() *status.Status {
 	// Get the underlying status. If the wrapped error is not a gRPC status,
 	// it will be converted to one with codes.Unknown.
 	// We need to inspect the error chain to find a potential gRPC status error,
 	// as it might be wrapped by other errors (e.g., using fmt.Errorf).
-	var grpcStatusError error
-	u := w.err
-	for u != nil {
-		// Check if the error can provide a gRPC status.
-		if _, ok := u.(interface{ GRPCStatus() *status.Status }); ok {
-			// To avoid recursion with our own type, we skip errWithMetadata
-			// and continue unwrapping. We are looking for the original gRPC status.
-			if _, isOurType := u.(*errWithMetadata); !isOurType { // nolint: errorlint // errors.As should not be used here
-				grpcStatusError = u
-				break
+	// While doing so, we look for a code explicitly set with WithCode, the outermost one wins.
+	// The message set with WithGRPCMessage is looked up the same way.
+	if w.verbatim {
+		if st, ok := foreignStatus(w.err); ok {
+			return st
+		}
+	}
+	var baseStatus *status.Status
+	code, hasCode := w.code, w.hasCode
+	msg, hasMsg := w.grpcMessage, w.hasGRPCMessage
+	for u := w.err; u != nil; u = errors.Unwrap(u) {
+		// To avoid recursion with our own type, we skip errWithMetadata
+		// and continue unwrapping. We are looking for the original gRPC status.
+		if e, isOurType := u.(*errWithMetadata); isOurType { // nolint: errorlint // errors.As should not be used here
+			if e.hasCode && !hasCode {
+				code, hasCode = e.code, true
 			}
+			if e.hasGRPCMessage && !hasMsg {
+				msg, hasMsg = e.grpcMessage, true
+			}
+			continue
+		}
+		// Check if the error can provide a gRPC status.
+		if st, ok := foreignStatus(u); ok {
+			baseStatus = st
+			break
 		}
-		u = errors.Unwrap(u)
 	}
-	// Check which error to use to get the Status
-	errToConvert := w.err
-	if grpcStatusError != nil {
-		errToConvert = grpcStatusError
+	if baseStatus == nil {
+		baseStatus = status.New(codes.Unknown, w.err.Error())
+	}
+	// A status with codes.OK would be received as a success, while we are reporting an error.
+	// The message of such a status is usually empty, the message of the error is used instead.
+	if baseStatus.Code() == codes.OK {
+		if baseStatus.Message() == "" {
+			stProto := baseStatus.Proto()
+			stProto.Message = w.err.Error()
+			baseStatus = status.FromProto(stProto)
+		}
+		if !hasCode {
+			code, hasCode = codes.Unknown, true
+		}
+	}
+	if hasCode && code == codes.OK {
+		code = codes.Unknown
+	}
+	// Override the code and the message if they were explicitly set, preserving the details.
+	if (hasCode && baseStatus.Code() != code) || (hasMsg && baseStatus.Message() != msg) {
+		stProto := baseStatus.Proto()
+		if hasCode {
+			stProto.Code = int32(code)
+		}
+		if hasMsg {
+			stProto.Message = msg
+		}
+		baseStatus = status.FromProto(stProto)
 	}
-	baseStatus := status.Convert(errToConvert)
 	// Collect all metadata from the entire error chain, starting from the current error.
-	allMetadata := GetMetadata(w)
-	// If there's no metadata, just return the status.
-	if len(allMetadata) == 0 {
-		return baseStatus
+	// Error values are expanded, as protobuf can't represent them.
+	allMetadata := expandErrorValues(getScopedMetadata(w, getOptions().statusScope))
+	// Convert our metadata slice into a map for structpb, skipping keys that are not strings.
+	// The slice is ordered from the lowest to the highest precedence, so the later pairs overwrite
+	// the earlier ones, and a repeated key gets its outermost value, as reported by GetMetadataMap.
+	metadataMap := dedupMetadata(allMetadata)
+	if getOptions().redactStatus {
+		redactMap(metadataMap)
 	}
-	// Convert our metadata slice into a map for structpb.
-	metadataMap := make(map[string]any)
-	for i := 0; i < len(allMetadata); i += 2 {
-		key, ok := allMetadata[i].(string)
-		if !ok {
-			// Keys must be strings for structpb.
+	// To preserve other details and avoid duplicating metadata, we'll rebuild the details.
+	// First, collect any details that are not our marked metadata struct.
+	var details []proto.Message
+	for _, detail := range baseStatus.Details() {
+		if s, ok := detail.(*structpb.Struct); ok && (isMetadataStruct(s) || isTypeHintsStruct(s)) {
 			continue
 		}
-		if i+1 >= len(allMetadata) {
-			break
+		if p, ok := detail.(proto.Message); ok {
+			details = append(details, p)
 		}
-		metadataMap[key] = allMetadata[i+1]
-	}
-	// If we successfully converted some metadata, create a struct.
-	if len(metadataMap) > 0 {
-		// Add our marker to identify this struct as our own.
-		metadataMap[qdrantMetadataMarker] = true
-		metadataStruct, err := structpb.NewStruct(metadataMap)
-		if err == nil {
-			// To preserve other details and avoid duplicating metadata, we'll rebuild the details
-			stProto := status.New(baseStatus.Code(), baseStatus.Message()).Proto()
-			// First, collect any details that are not our marked metadata struct.
-			for _, detail := range baseStatus.Details() {
-				isOurMetadata := false
-				if s, ok := detail.(*structpb.Struct); ok {
-					if _, exists := s.GetFields()[qdrantMetadataMarker]; exists {
-						isOurMetadata = true
-					}
-				}
-				// Only add if it's not our data
-				if !isOurMetadata {
-					if p, ok := detail.(proto.Message); ok {
-						anyRef, err := anypb.New(p)
-						if err == nil {
-							stProto.Details = append(stProto.Details, anyRef)
-						}
-					}
-				}
+	}
+	// Then, drop the least important details if there are too many of them,
+	// keeping room for our metadata struct and its type hints.
+	truncated := 0
+	if maxDetails := getOptions().maxDetails; maxDetails > 0 {
+		reserved := 0
+		if len(metadataMap) > 0 {
+			reserved = 1
+			if needsTypeHints(metadataMap) {
+				reserved = 2
 			}
-			// Now, append our new, consolidated metadata struct.
-			if anyRef, err := anypb.New(metadataStruct); err == nil {
+		}
+		if len(details) > max(maxDetails-reserved, 0) {
+			// The number of dropped details is an integer, sent with a type hint.
+			details, truncated = truncateDetails(details, maxDetails-2)
+			metadataMap[detailsTruncatedKey] = truncated
+		}
+	}
+	// Then, drop the least important metadata if it's too large.
+	if o := getOptions(); o.maxMetadataBytes > 0 {
+		priority := o.metadataPriority
+		if priority == nil {
+			priority = defaultMetadataPriority
+		}
+		fitMetadata(metadataMap, o.maxMetadataBytes, priority)
+	}
+	// If there's no metadata to attach and nothing was dropped, just return the status.
+	if len(metadataMap) == 0 {
+		return baseStatus
+	}
+	stProto := status.New(baseStatus.Code(), baseStatus.Message()).Proto()
+	for _, detail := range details {
+		if anyRef, err := newAny(detail); err == nil {
+			stProto.Details = append(stProto.Details, anyRef)
+		}
+	}
+	// Now, append our new, consolidated metadata struct, followed by its type hints.
+	metadataStruct, typeHints := newMetadataStructs(metadataMap)
+	if anyRef, err := newAny(metadataStruct); err == nil {
+		stProto.Details = append(stProto.Details, anyRef)
+		if typeHints != nil {
+			if anyRef, err := newAny(typeHints); err == nil {
 				stProto.Details = append(stProto.Details, anyRef)
 			}
-			return status.FromProto(stProto)
 		}
 	}
-	// Fallback to returning the original status if metadata couldn't be attached.
-	return baseStatus
+	return status.FromProto(stProto)
 }
 
 // Unwrap returns the original error that was wrapped with errWithMetadata instance
 // It makes the errWithMetadata compatible with the standard error unwrapping mechanism
+//
+// A type can't implement both Unwrap() error and Unwrap() []error, so wrapping a multi-error,
+// such as the one returned by errors.Join, keeps the single-error form: errors.Is, errors.As and
+// GetMetadata reach all the branches through the wrapped multi-error, which is returned as is.
 func (w *errWithMetadata) Unwrap() error {
 	return w.err
 }
 
+// Is reports whether the wrapped error chain contains a gRPC status error with the same
+// code and message as target, when target is a gRPC status error.
+// gRPC status errors only match when their whole status, including details, is equal,
+// so a status received with metadata in its details would not match a plain sentinel status.
+// For any other target it returns false, leaving the matching to the standard unwrapping mechanism.
+func (w *errWithMetadata) Is(target error) bool {
+	targetStatus, ok := foreignStatus(target)
+	if !ok {
+		return false
+	}
+	for u := w.err; u != nil; u = errors.Unwrap(u) {
+		if st, ok := foreignStatus(u); ok {
+			return st.Code() == targetStatus.Code() && st.Message() == targetStatus.Message()
+		}
+	}
+	return false
+}
+
+// Metadata returns a copy of the key-value pairs attached to this error, without the metadata of the errors
+// it wraps, the values of DeferContext being resolved. Use GetMetadata to collect the metadata of the whole chain.
+func (w *errWithMetadata) Metadata() []any {
+	return slices.Clone(resolveLazyValues(w.metadata))
+}
+
+// Code returns the gRPC code of the error, resolved over the whole chain it wraps, exactly like CodeOf:
+// the code set by this error with WithCode, if any, or else the code of the errors it wraps.
+// A layer without a code of its own therefore reports the code of the chain below it.
+func (w *errWithMetadata) Code() codes.Code {
+	return CodeOf(w)
+}
+
+// MetadataError is implemented by the errors carrying metadata, such as the errors returned by WithMetadata and WithCode.
+// It allows other packages to detect them with errors.As, and to render them without depending on
+// the helpers of this package, walking the chain with Unwrap and reading the metadata of each level.
+type MetadataError interface {
+	error
+	// Metadata returns the key-value pairs attached to the error itself.
+	Metadata() []any
+	// Code returns the gRPC code of the error, resolved over the chain it wraps like CodeOf,
+	// so a layer without a code of its own reports the code of the errors it wraps.
+	Code() codes.Code
+	// Unwrap returns the wrapped error, which may carry metadata as well.
+	Unwrap() error
+}
+
 type Metadata []any
 
-// Extend returns a new metadata container with combined key value pairs from current metadata and provided key value pairs
+// Extend returns a new metadata container with combined key value pairs from current metadata and provided key value pairs.
+// Slices and maps provided in place of a key are expanded into key value pairs the same way WithMetadata does,
+// so the container always holds a flat key value sequence.
 func (m *Metadata) Extend(keyValues ...any) Metadata {
-	if m == nil {
-		return keyValues
+	var cur []any
+	if m != nil {
+		cur = *m
 	}
-	return mergeKeyValuePair(*m, keyValues)
+	return mergeKeyValuePair(cur, flattenKeyValues(keyValues))
 }
 
-// WithMetadata returns the provided error wrapped with the provided metadata
+// WithMetadata returns the provided error wrapped with the provided metadata.
+// Slices and maps provided in place of a key, such as a Metadata container, are expanded into key value pairs,
+// as well as structs, and pointers to structs, whose exported fields are attached following their "meta" tags:
+//
+//	type requestContext struct {
+//		Collection string `meta:"collection"`
+//		Shard      int    `meta:"shard,omitempty"` // skipped if zero
+//		Token      string `meta:"-"`               // never attached
+//		Region     string                          // attached as "Region"
+//		tenant     string                          // unexported fields are skipped
+//	}
+//
+//	err = errhelper.WithMetadata(err, requestContext{Collection: "books"})
+//
+// Exported embedded structs are attached as a single field, named after their type unless tagged.
+// A Field is attached as its key and value, and errors provided in place of a key are never expanded.
+// If there is no metadata to attach, the error is returned unchanged, as an empty layer would contribute nothing.
+// A missing value is padded, and keys that are not strings are skipped when the metadata is read,
+// unless the strict mode is enabled with SetStrict.
 func WithMetadata(err error, keyValues ...any) error {
+	return withScopedMetadata(err, ScopeBoth, keyValues)
+}
+
+// withScopedMetadata implements WithMetadata and WithScopedMetadata.
+func withScopedMetadata(err error, scope Scope, keyValues []any) error {
+	if err == nil || len(keyValues) == 0 {
+		return err
+	}
+	return wrapFlattened(err, scope, flattenKeyValues(keyValues))
+}
+
+// wrapFlattened wraps the error with the flattened key value pairs, which it takes ownership of.
+func wrapFlattened(err error, scope Scope, flattened []any) error {
+	if len(flattened) == 0 {
+		return err
+	}
+	if strict.Load() {
+		if reason := checkMetadata(flattened); reason != "" {
+			return &malformedMetadataError{reason: reason, err: err}
+		}
+	}
+	// Ensure the final metadata slice has an even number of elements
+	// by padding if necessary. This makes the key-value pairing robust.
+	metadata := flattened
+	if len(metadata)%2 != 0 {
+		metadata = addPaddingForMissingValue(metadata)
+	}
+	wrapped := newErrWithMetadata(errWithMetadata{
+		err:      err,
+		metadata: metadata,
+		scope:    scope,
+	})
+	notifyWrap(wrapped, metadata)
+	return wrapped
+}
+
+// WrapWith returns the provided error wrapped with the metadata of the prepared container followed by
+// the extra key value pairs, e.g. with a container shared by the errors of a function:
+//
+//	errMetadata := errhelper.Metadata{"collection", name}
+//	...
+//	return errhelper.WrapWith(err, errMetadata, "shard", shardID)
+//
+// It's equivalent to WithMetadata(err, md.Extend(extra...)...), while allocating the pairs only once.
+// A nil container only attaches the extra pairs, and it returns nil for a nil error.
+func WrapWith(err error, md Metadata, extra ...any) error {
 	if err == nil {
 		return nil
 	}
-	// try to detect types of provided keyValues and build up proper key value pair
-	flattened := make([]any, 0)
+	flattened := appendFlattenedKeyValues(make([]any, 0, len(md)+1+len(extra)), md)
+	// Like Extend, pad the container so the extra pairs are not shifted.
+	if len(flattened)%2 != 0 {
+		flattened = append(flattened, "<missing>")
+	}
+	return wrapFlattened(err, ScopeBoth, appendFlattenedKeyValues(flattened, extra))
+}
+
+// WithValues is an alias of WithMetadata, following the naming of logr's WithValues,
+// for teams used to its key value convention.
+func WithValues(err error, keysAndValues ...any) error {
+	return WithMetadata(err, keysAndValues...)
+}
+
+// WithMetadataOnce is like WithMetadata, except that it returns the error unchanged if the outermost
+// error carrying metadata in its chain already carries exactly the provided key value pairs, in the same order,
+// values being compared with reflect.DeepEqual. It prevents the chain from growing when the same error
+// is wrapped again and again with the same metadata, e.g. at retry boundaries.
+// Maps provided in place of a key are expanded in no particular order, so they may not match and add a layer.
+func WithMetadataOnce(err error, keyValues ...any) error {
+	if err == nil || len(keyValues) == 0 {
+		return err
+	}
+	metadata := addPaddingForMissingValue(flattenKeyValues(keyValues))
+	for u := err; u != nil; u = errors.Unwrap(u) {
+		if e, isOurType := u.(*errWithMetadata); isOurType && len(e.metadata) > 0 { // nolint: errorlint // errors.As should not be used here
+			if reflect.DeepEqual(e.metadata, metadata) {
+				return err
+			}
+			break
+		}
+	}
+	return WithMetadata(err, keyValues...)
+}
+
+// flattenKeyValues detects the types of provided keyValues and builds up proper key value pairs.
+// Slices and maps provided in place of a key are expanded into their elements and entries respectively,
+// and structs into their fields (see appendStructFields), while the ones provided in place of a value
+// are kept as values.
+func flattenKeyValues(keyValues []any) []any {
+	return appendFlattenedKeyValues(make([]any, 0, len(keyValues)), keyValues)
+}
+
+// appendFlattenedKeyValues appends the flattened keyValues (see flattenKeyValues) to the pairs,
+// which must hold an even number of elements.
+func appendFlattenedKeyValues(flattened []any, keyValues []any) []any {
 	for _, kv := range keyValues {
-		t := reflect.TypeOf(kv)
-		switch t.Kind() {
+		// Only expand containers where a key is expected.
+		if len(flattened)%2 != 0 {
+			flattened = append(flattened, kv)
+			continue
+		}
+		if f, ok := kv.(Field); ok {
+			flattened = append(flattened, f.Key, f.Value)
+			continue
+		}
+		v := reflect.ValueOf(kv)
+		if _, isErr := kv.(error); !isErr && v.Kind() == reflect.Pointer && !v.IsNil() && v.Elem().Kind() == reflect.Struct {
+			v = v.Elem()
+		}
+		switch v.Kind() {
 		case reflect.Slice:
-			s := reflect.ValueOf(kv)
 			// We need to use .Interface() to get the actual value, not the reflect.Value
-			for i := 0; i < s.Len(); i++ {
-				flattened = append(flattened, s.Index(i).Interface())
+			for i := 0; i < v.Len(); i++ {
+				flattened = append(flattened, v.Index(i).Interface())
 			}
 		case reflect.Map:
 			// Use reflection to iterate over the map to handle any map type
 			// without panicking on type assertion.
-			v := reflect.ValueOf(kv)
 			iter := v.MapRange()
 			for iter.Next() {
 				flattened = append(flattened, iter.Key().Interface(), iter.Value().Interface())
 			}
+		case reflect.Struct:
+			if _, isErr := kv.(error); isErr {
+				flattened = append(flattened, kv)
+				continue
+			}
+			flattened = appendStructFields(flattened, v)
 		default:
 			flattened = append(flattened, kv)
 		}
 	}
-	// Ensure the final metadata slice has an even number of elements
-	// by padding if necessary. This makes the key-value pairing robust.
-	metadata := addPaddingForMissingValue(flattened)
-	// Return
-	return &errWithMetadata{
-		err:      err,
-		metadata: metadata,
-	}
+	return flattened
 }
 
-// GetMetadata returns metadata from the error chain
+// GetMetadata returns metadata from the error chain, preceded by the global metadata set with SetGlobalMetadata.
+// The branches of multi-errors, such as the ones created with errors.Join, are traversed in order,
+// the later branches taking precedence, and an error shared by several branches contributes its metadata once.
 // If there is no metadata in the chain, it will return an empty slice
 // It returns []any to make it compatible with structured logging libraries (like slog, zap, or logr).
+// The returned slice is always freshly allocated, it shares no backing array with the error,
+// so callers can safely modify it.
 func GetMetadata(err error) []any {
+	return getScopedMetadata(err, ScopeBoth)
+}
+
+// getScopedMetadata implements GetMetadata and GetMetadataScoped.
+func getScopedMetadata(err error, scope Scope) []any {
 	if err == nil {
 		return []any{}
 	}
-
-	// Recursively get metadata from the wrapped error first. This ensures that
-	// metadata from the innermost error is collected first.
-	metadata := GetMetadata(errors.Unwrap(err))
-
-	// Then, append metadata from the current error level. This way, when the
+	// Collect the metadata of every level of the chain first, so that the result can be allocated at once.
+	// The layers are the slices held by the errors, they must only be copied, never appended to.
+	var buf [8][]any
+	var visited visitedErrors
+	layers := appendScopedLayers(buf[:0], err, &visited, scope)
+	total := 0
+	for _, md := range layers {
+		total += len(md)
+	}
+	// Global metadata comes first, so it has the lowest precedence.
+	globals := getGlobalMetadata()
+	metadata := make([]any, 0, len(globals)+total)
+	metadata = append(metadata, globals...)
+	// Then, append metadata starting from the innermost error level. This way, when the
 	// resulting slice is converted to a map, keys from outer (more recent)
 	// wrappers will overwrite keys from inner wrappers, giving them precedence.
 	// This is compatible with the "last one wins" behavior of most structured loggers.
-	if e, ok := err.(*errWithMetadata); ok { // nolint: errorlint
-		metadata = append(metadata, e.metadata...)
-	} else {
-		// This captures metadata from errors that conform to the gRPC status interface.
-		if s, ok := err.(interface{ GRPCStatus() *status.Status }); ok {
-			st := s.GRPCStatus()
-			for _, detail := range st.Details() {
-				if metadataStruct, ok := detail.(*structpb.Struct); ok {
-					fields := metadataStruct.GetFields()
-					// Only extract from structs that have our marker.
-					if _, hasMarker := fields[qdrantMetadataMarker]; hasMarker {
-						for key, val := range fields {
-							// Don't include the marker itself in the final metadata.
-							if key == qdrantMetadataMarker {
-								continue
-							}
-							metadata = append(metadata, key, val.AsInterface())
-						}
+	for _, md := range layers {
+		metadata = append(metadata, md...)
+	}
+	return metadata
+}
+
+// layerMetadata returns the metadata carried by the provided error itself,
+// without looking at the errors it wraps.
+// The returned slice must not be modified, as it may be shared with the error.
+func layerMetadata(err error) []any {
+	if e, ok := err.(*errWithMetadata); ok { // nolint: errorlint // errors.As should not be used here
+		return e.metadata
+	}
+	// This captures metadata from errors that conform to the gRPC status interface.
+	st, ok := foreignStatus(err)
+	if !ok {
+		return nil
+	}
+	var metadata []any
+	details := st.Details()
+	typeHints := findTypeHints(details)
+	for _, detail := range details {
+		if metadataStruct, ok := detail.(*structpb.Struct); ok {
+			fields := metadataStruct.GetFields()
+			// Only extract from structs that have our marker.
+			if isMetadataStruct(metadataStruct) {
+				// Sort the keys, so the order of the metadata doesn't depend on map iteration.
+				for _, key := range slices.Sorted(maps.Keys(fields)) {
+					// Don't include the marker itself in the final metadata.
+					if isMetadataMarker(key) {
+						continue
 					}
+					value := fromWireValue(fields[key])
+					if hint, ok := typeHints[key]; ok {
+						value = fromHintedWireValue(value, hint.GetStringValue())
+					}
+					metadata = append(metadata, key, value)
 				}
 			}
 		}
@@ -214,6 +508,165 @@ func GetMetadata(err error) []any {
 	return metadata
 }
 
+// isMetadataStruct reports whether the struct is tagged with our marker, or one registered with RegisterMetadataMarker.
+func isMetadataStruct(s *structpb.Struct) bool {
+	fields := s.GetFields()
+	if _, ok := fields[qdrantMetadataMarker]; ok {
+		return true
+	}
+	for _, marker := range getMetadataMarkers() {
+		if _, ok := fields[marker]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// newAny wraps the message into an Any, marshaling it deterministically,
+// so that converting the same error twice produces the same status.
+func newAny(m proto.Message) (*anypb.Any, error) {
+	a := &anypb.Any{}
+	if err := anypb.MarshalFrom(a, m, proto.MarshalOptions{Deterministic: true}); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// foreignStatus returns the gRPC status provided by the error itself, if the error conforms
+// to the gRPC status interface and is not one of our wrappers, whose status is derived from the errors they wrap.
+// Some implementations return a nil status, which is treated as no status.
+func foreignStatus(err error) (*status.Status, bool) {
+	switch err.(type) { // nolint: errorlint // errors.As should not be used here
+	case *errWithMetadata, *wrapfError:
+		return nil, false
+	}
+	s, ok := err.(interface{ GRPCStatus() *status.Status }) // nolint: errorlint // errors.As should not be used here
+	if !ok {
+		return nil, false
+	}
+	st := s.GRPCStatus()
+	return st, st != nil
+}
+
+// GetMetadataMap returns metadata from the error chain as a map.
+// When a key is present multiple times in the chain, the outermost value wins,
+// following the same precedence as structured loggers applied to GetMetadata,
+// unless a function combining its values was configured with the MergeFunc option.
+// Keys that are not strings are skipped, and the keys are transformed by the function
+// set with SetKeyTransformer, if any.
+func GetMetadataMap(err error) map[string]any {
+	return toMetadataMap(GetMetadata(err))
+}
+
+// GetMetadataValues returns every value associated with the key in the error chain,
+// for keys that are intentionally set multiple times, like collected warnings.
+// The values are in the order of GetMetadata: the global metadata first, then from the innermost
+// error to the outermost one, so the last value is the one GetMetadataMap returns.
+// It returns nil if the key is not present.
+func GetMetadataValues(err error, key string) []any {
+	var values []any
+	metadata := GetMetadata(err)
+	for i := 0; i+1 < len(metadata); i += 2 {
+		if k, ok := metadata[i].(string); ok && k == key {
+			values = append(values, metadata[i+1])
+		}
+	}
+	return values
+}
+
+// toMetadataMap converts a key value pair slice into a map, the last value of a key wins.
+// Keys that are not strings are skipped.
+// The keys are transformed by the function set with SetKeyTransformer, if any.
+func toMetadataMap(metadata []any) map[string]any {
+	transform := getKeyTransformer()
+	if transform == nil {
+		return dedupMetadata(metadata)
+	}
+	// Transform the keys after deduplication: keep only the last occurrence of each key,
+	// in the order they were set, so that colliding transformed keys are last-wins as well.
+	lastIndex := make(map[string]int, len(metadata)/2)
+	for i := 0; i+1 < len(metadata); i += 2 {
+		if key, ok := metadata[i].(string); ok {
+			lastIndex[key] = i
+		}
+	}
+	deduped := dedupMetadata(metadata)
+	metadataMap := make(map[string]any, len(lastIndex))
+	for i := 0; i+1 < len(metadata); i += 2 {
+		if key, ok := metadata[i].(string); ok && lastIndex[key] == i {
+			metadataMap[transform(key)] = deduped[key]
+		}
+	}
+	return metadataMap
+}
+
+// dedupMetadata converts a key value pair slice into a map, the last value of a key wins,
+// unless a function was set for the key with the MergeFunc option.
+// Keys that are not strings are skipped.
+func dedupMetadata(metadata []any) map[string]any {
+	mergeFuncs := getOptions().mergeFuncs
+	metadataMap := make(map[string]any, len(metadata)/2)
+	for i := 0; i+1 < len(metadata); i += 2 {
+		key, ok := metadata[i].(string)
+		if !ok {
+			continue
+		}
+		if merge, ok := mergeFuncs[key]; ok {
+			if old, exists := metadataMap[key]; exists {
+				metadataMap[key] = merge(old, metadata[i+1])
+				continue
+			}
+		}
+		metadataMap[key] = metadata[i+1]
+	}
+	return metadataMap
+}
+
+// maxErrorValueDepth limits how deep the metadata of error values is expanded,
+// protecting against errors that carry themselves as metadata.
+const maxErrorValueDepth = 4
+
+// expandErrorValues returns the key value pairs with error values replaced by their message.
+// The metadata of an error value follows it, with keys prefixed by the key of the error value and a dot,
+// e.g. "cause", err becomes "cause", err.Error(), "cause.key", value.
+func expandErrorValues(metadata []any) []any {
+	return expandErrorValuesDepth(metadata, maxErrorValueDepth)
+}
+
+func expandErrorValuesDepth(metadata []any, depth int) []any {
+	hasErrorValue := false
+	for i := 1; i < len(metadata); i += 2 {
+		if _, ok := metadata[i].(error); ok {
+			hasErrorValue = true
+			break
+		}
+	}
+	if !hasErrorValue {
+		return metadata
+	}
+	expanded := make([]any, 0, len(metadata))
+	for i := 0; i+1 < len(metadata); i += 2 {
+		key, value := metadata[i], metadata[i+1]
+		valueErr, ok := value.(error)
+		if !ok {
+			expanded = append(expanded, key, value)
+			continue
+		}
+		expanded = append(expanded, key, valueErr.Error())
+		prefix, ok := key.(string)
+		if !ok || depth == 0 {
+			continue
+		}
+		nested := expandErrorValuesDepth(GetMetadata(valueErr), depth-1)
+		for j := 0; j+1 < len(nested); j += 2 {
+			if nestedKey, ok := nested[j].(string); ok {
+				expanded = append(expanded, prefix+"."+nestedKey, nested[j+1])
+			}
+		}
+	}
+	return expanded
+}
+
 // mergeKeyValuePair merges two slices into a new slice.
 // It assumes that both slices are valid key value pairs.
 // If a key is missing a value, it will add a padding "<missing>" to the slice.
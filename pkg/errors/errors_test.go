@@ -3,6 +3,7 @@ package errors
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -264,6 +265,130 @@ func TestErrWrapper_Extend(t *testing.T) {
 	require.EqualValues(t, []any{"k1", "v1", "k2", "v2"}, extendedMetadata)
 }
 
+func TestErrWrapper_ExtendExpandsContainers(t *testing.T) {
+	testCases := []struct {
+		name        string
+		curMetadata Metadata
+		newMetadata []any
+		expected    Metadata
+	}{
+		{
+			name:        "when extended with a map",
+			curMetadata: Metadata{"k1", "v1"},
+			newMetadata: []any{map[string]string{"k2": "v2"}},
+			expected:    Metadata{"k1", "v1", "k2", "v2"},
+		},
+		{
+			name:        "when extended with a slice",
+			curMetadata: Metadata{"k1", "v1"},
+			newMetadata: []any{[]any{"k2", "v2"}, "k3", "v3"},
+			expected:    Metadata{"k1", "v1", "k2", "v2", "k3", "v3"},
+		},
+		{
+			name:        "when extended with another metadata container",
+			curMetadata: nil,
+			newMetadata: []any{Metadata{"k1", "v1"}},
+			expected:    Metadata{"k1", "v1"},
+		},
+		{
+			name:        "when a slice is provided in place of a value",
+			curMetadata: Metadata{"k1", "v1"},
+			newMetadata: []any{"k2", []string{"a", "b"}},
+			expected:    Metadata{"k1", "v1", "k2", []string{"a", "b"}},
+		},
+		{
+			name:        "when a map is provided in place of a value",
+			curMetadata: Metadata{"k1", "v1"},
+			newMetadata: []any{"k2", map[string]string{"a": "b"}},
+			expected:    Metadata{"k1", "v1", "k2", map[string]string{"a": "b"}},
+		},
+		{
+			name:        "when a value is nil",
+			curMetadata: Metadata{"k1", "v1"},
+			newMetadata: []any{"k2", nil},
+			expected:    Metadata{"k1", "v1", "k2", nil},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			extended := tc.curMetadata.Extend(tc.newMetadata...)
+			require.Equal(t, tc.expected, extended)
+			// Extending and wrapping is consistent with wrapping with the containers directly.
+			fooError := errors.New("foo")
+			require.Equal(t,
+				GetMetadata(WithMetadata(fooError, append([]any{tc.curMetadata}, tc.newMetadata...)...)),
+				GetMetadata(WithMetadata(fooError, extended...)),
+			)
+		})
+	}
+}
+
+func TestErrWrapper_ExtendDoesNotAlias(t *testing.T) {
+	base := make(Metadata, 0, 10)
+	base = append(base, "k1", "v1")
+	first := base.Extend("k2", "v2")
+	second := base.Extend("k3", "v3")
+	require.Equal(t, Metadata{"k1", "v1", "k2", "v2"}, first)
+	require.Equal(t, Metadata{"k1", "v1", "k3", "v3"}, second)
+}
+
+func TestWrapWith(t *testing.T) {
+	fooError := errors.New("foo")
+	require.NoError(t, WrapWith(nil, Metadata{"k1", "v1"}, "k2", "v2"))
+	require.Equal(t, fooError, WrapWith(fooError, nil))
+
+	testCases := []struct {
+		name     string
+		md       Metadata
+		extra    []any
+		expected []any
+	}{
+		{
+			name:     "container and extra pairs",
+			md:       Metadata{"k1", "v1"},
+			extra:    []any{"k2", "v2"},
+			expected: []any{"k1", "v1", "k2", "v2"},
+		},
+		{
+			name:     "nil container",
+			md:       nil,
+			extra:    []any{"k2", "v2"},
+			expected: []any{"k2", "v2"},
+		},
+		{
+			name:     "no extra pairs",
+			md:       Metadata{"k1", "v1"},
+			expected: []any{"k1", "v1"},
+		},
+		{
+			name:     "missing values",
+			md:       Metadata{"k1"},
+			extra:    []any{"k2"},
+			expected: []any{"k1", "<missing>", "k2", "<missing>"},
+		},
+		{
+			name:     "expanded extra pairs",
+			md:       Metadata{"k1", "v1"},
+			extra:    []any{Metadata{"k2", "v2"}, "k3", []string{"v3"}},
+			expected: []any{"k1", "v1", "k2", "v2", "k3", []string{"v3"}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			wrapped := WrapWith(fooError, tc.md, tc.extra...)
+			require.Equal(t, tc.expected, GetMetadata(wrapped))
+			require.Equal(t, GetMetadata(WithMetadata(fooError, tc.md.Extend(tc.extra...)...)), GetMetadata(wrapped))
+		})
+	}
+
+	md := Metadata{"k1", "v1"}
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = WrapWith(fooError, md, "k2", "v2")
+	})
+	// The pairs and the wrapper.
+	require.LessOrEqual(t, allocs, 2.0)
+}
+
 func TestWithMetadata(t *testing.T) {
 	fooError := errors.New("foo")
 	testCases := []struct {
@@ -271,7 +396,7 @@ func TestWithMetadata(t *testing.T) {
 		curMetadata Metadata
 		newMetadata []any
 		err         error
-		expected    *errWithMetadata
+		expected    error
 	}{
 		{
 			name:        "when error is nil",
@@ -325,10 +450,14 @@ func TestWithMetadata(t *testing.T) {
 			err:         fooError,
 			curMetadata: nil,
 			newMetadata: nil,
-			expected: &errWithMetadata{
-				err:      fooError,
-				metadata: []any{},
-			},
+			expected:    fooError,
+		},
+		{
+			name:        "when both current and new metadata are empty containers",
+			err:         fooError,
+			curMetadata: Metadata{},
+			newMetadata: []any{map[string]string{}},
+			expected:    fooError,
 		},
 		{
 			name:        "when both current and new metadata are not empty",
@@ -560,3 +689,461 @@ func TestGetMetadata(t *testing.T) {
 		})
 	}
 }
+
+func TestIs(t *testing.T) {
+	sentinel := status.Error(codes.NotFound, "item not found")
+	rootError := errors.New("this is root error")
+
+	// Simulate a status received from another service, carrying metadata in its details.
+	st := status.New(codes.NotFound, "item not found")
+	metadataStruct, err := structpb.NewStruct(map[string]any{
+		"remote_key":         "remote_value",
+		qdrantMetadataMarker: true,
+	})
+	require.NoError(t, err)
+	stWithDetails, err := st.WithDetails(metadataStruct)
+	require.NoError(t, err)
+	receivedError := stWithDetails.Err()
+
+	testCases := []struct {
+		name     string
+		err      error
+		target   error
+		expected bool
+	}{
+		{
+			name:     "wrapped status error matches equal status",
+			err:      WithMetadata(sentinel, "key", "value"),
+			target:   status.Error(codes.NotFound, "item not found"),
+			expected: true,
+		},
+		{
+			name:     "wrapped status error with details matches status with same code and message",
+			err:      WithMetadata(receivedError, "key", "value"),
+			target:   sentinel,
+			expected: true,
+		},
+		{
+			name:     "status error wrapped with custom message and metadata",
+			err:      WithMetadata(fmt.Errorf("foo: %w", receivedError), "key", "value"),
+			target:   sentinel,
+			expected: true,
+		},
+		{
+			name:     "status error wrapped in multiple levels with metadata",
+			err:      fmt.Errorf("foo: %w", WithMetadata(WithMetadata(receivedError, "k1", "v1"), "k2", "v2")),
+			target:   sentinel,
+			expected: true,
+		},
+		{
+			name:     "different code does not match",
+			err:      WithMetadata(receivedError, "key", "value"),
+			target:   status.Error(codes.Internal, "item not found"),
+			expected: false,
+		},
+		{
+			name:     "different message does not match",
+			err:      WithMetadata(receivedError, "key", "value"),
+			target:   status.Error(codes.NotFound, "other item not found"),
+			expected: false,
+		},
+		{
+			name:     "plain error does not match status",
+			err:      WithMetadata(rootError, "key", "value"),
+			target:   status.Error(codes.Unknown, "this is root error"),
+			expected: false,
+		},
+		{
+			name:     "plain target uses default matching",
+			err:      WithMetadata(rootError, "key", "value"),
+			target:   rootError,
+			expected: true,
+		},
+		{
+			name:     "unrelated plain target does not match",
+			err:      WithMetadata(receivedError, "key", "value"),
+			target:   rootError,
+			expected: false,
+		},
+		{
+			name:     "metadata error as target does not match by status",
+			err:      WithMetadata(receivedError, "key", "value"),
+			target:   WithMetadata(sentinel, "key", "value"),
+			expected: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, errors.Is(tc.err, tc.target))
+		})
+	}
+}
+
+func TestGetMetadataMap(t *testing.T) {
+	rootError := errors.New("this is root error")
+
+	testCases := []struct {
+		name     string
+		err      error
+		expected map[string]any
+	}{
+		{
+			name:     "nil error",
+			err:      nil,
+			expected: map[string]any{},
+		},
+		{
+			name:     "error without metadata",
+			err:      rootError,
+			expected: map[string]any{},
+		},
+		{
+			name:     "error wrapped in multiple levels with metadata",
+			err:      WithMetadata(WithMetadata(rootError, "k1", "v1"), "k2", "v2"),
+			expected: map[string]any{"k1": "v1", "k2": "v2"},
+		},
+		{
+			name:     "error wrapped with reused key in metadata",
+			err:      WithMetadata(WithMetadata(rootError, "reused_key", "inner_value"), "reused_key", "outer_value"),
+			expected: map[string]any{"reused_key": "outer_value"},
+		},
+		{
+			name:     "error wrapped with non-string key",
+			err:      WithMetadata(rootError, 1, "v1", "k2", "v2"),
+			expected: map[string]any{"k2": "v2"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, GetMetadataMap(tc.err))
+		})
+	}
+}
+
+func TestGetMetadataValues(t *testing.T) {
+	rootError := errors.New("this is root error")
+
+	testCases := []struct {
+		name     string
+		err      error
+		key      string
+		expected []any
+	}{
+		{
+			name: "nil error",
+			err:  nil,
+			key:  "warning",
+		},
+		{
+			name: "missing key",
+			err:  WithMetadata(rootError, "k1", "v1"),
+			key:  "warning",
+		},
+		{
+			name:     "single value",
+			err:      WithMetadata(rootError, "warning", "w1", "k1", "v1"),
+			key:      "warning",
+			expected: []any{"w1"},
+		},
+		{
+			name: "values from inner to outer",
+			err: WithMetadata(
+				fmt.Errorf("foo: %w", WithMetadata(rootError, "warning", "w1", "warning", "w2")),
+				"warning", "w3",
+			),
+			key:      "warning",
+			expected: []any{"w1", "w2", "w3"},
+		},
+		{
+			name: "values received over gRPC",
+			err: WithMetadata(
+				roundTrip(t, WithMetadata(status.Error(codes.NotFound, "not found"), "warning", "w1")),
+				"warning", "w2",
+			),
+			key:      "warning",
+			expected: []any{"w1", "w2"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, GetMetadataValues(tc.err, tc.key))
+		})
+	}
+}
+
+// nilStatusError is an error whose GRPCStatus implementation returns nil,
+// as some third-party libraries do.
+type nilStatusError struct {
+	err error
+}
+
+func (e *nilStatusError) Error() string {
+	return e.err.Error()
+}
+
+func (e *nilStatusError) Unwrap() error {
+	return e.err
+}
+
+func (e *nilStatusError) GRPCStatus() *status.Status {
+	return nil
+}
+
+func TestNilGRPCStatus(t *testing.T) {
+	foreign := &nilStatusError{err: errors.New("foo")}
+
+	err := WithMetadata(foreign, "key", "value")
+	require.NotPanics(t, func() {
+		require.Equal(t, []any{"key", "value"}, GetMetadata(err))
+		require.Equal(t, codes.Unknown, CodeOf(err))
+		require.False(t, errors.Is(err, status.Error(codes.OK, "")))
+		require.False(t, errors.Is(WithMetadata(status.Error(codes.NotFound, "foo"), "key", "value"), foreign))
+	})
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.Unknown, st.Code())
+	require.Equal(t, "foo", st.Message())
+	require.Len(t, st.Details(), 1)
+
+	// A gRPC status further down the chain is still found.
+	err = WithMetadata(&nilStatusError{err: status.Error(codes.NotFound, "item not found")}, "key", "value")
+	require.Equal(t, codes.NotFound, CodeOf(err))
+	require.Equal(t, codes.NotFound, status.Code(err))
+	require.Equal(t, "item not found", status.Convert(err).Message())
+}
+
+func TestErrorValuesInMetadata(t *testing.T) {
+	cause := WithMetadata(errors.New("disk full"), "volume", "/data", "free_bytes", 0)
+	err := WithMetadata(status.Error(codes.Internal, "write failed"), "cause", cause, "key", "value")
+
+	// Locally, the error value is kept as is.
+	require.Equal(t, []any{"cause", cause, "key", "value"}, GetMetadata(err))
+
+	// Over the wire, it's rendered using its message, followed by its own metadata.
+	received := roundTrip(t, err)
+	require.Equal(t, map[string]any{
+		"cause":            "disk full",
+		"cause.volume":     "/data",
+		"cause.free_bytes": 0,
+		"key":              "value",
+	}, GetMetadataMap(received))
+
+	require.Equal(t,
+		`msg="rpc error: code = Internal desc = write failed" code=Internal cause="disk full" cause.free_bytes=0 cause.volume=/data key=value`,
+		Logfmt(err),
+	)
+}
+
+func TestErrorValueCarryingItself(t *testing.T) {
+	self := &errWithMetadata{err: errors.New("foo")}
+	self.metadata = []any{"self", self}
+
+	received := roundTrip(t, self)
+	require.Equal(t, map[string]any{
+		"self":                     "foo",
+		"self.self":                "foo",
+		"self.self.self":           "foo",
+		"self.self.self.self":      "foo",
+		"self.self.self.self.self": "foo",
+	}, GetMetadataMap(received))
+}
+
+func BenchmarkGetMetadata(b *testing.B) {
+	// 20 keys across 5 layers.
+	err := errors.New("root")
+	for layer := range 5 {
+		keyValues := make([]any, 0, 8)
+		for key := range 4 {
+			keyValues = append(keyValues, fmt.Sprintf("layer%d_key%d", layer, key), key)
+		}
+		err = WithMetadata(err, keyValues...)
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		GetMetadata(err)
+	}
+}
+
+func TestWithMetadataWithoutMetadata(t *testing.T) {
+	fooError := errors.New("foo")
+	require.Same(t, fooError, WithMetadata(fooError))
+	require.Same(t, fooError, WithMetadata(fooError, []any{}...))
+	require.Same(t, fooError, WithMetadata(fooError, Metadata{}))
+	require.Zero(t, testing.AllocsPerRun(100, func() {
+		_ = WithMetadata(fooError)
+	}))
+}
+
+func TestGetMetadataJoined(t *testing.T) {
+	left := WithMetadata(errors.New("left"), "branch", "left", "left_key", "left_value")
+	right := WithMetadata(errors.New("right"), "branch", "right")
+	joined := WithMetadata(errors.Join(left, right), "outer_key", "outer_value")
+
+	// Branches are collected in order, the later ones taking precedence.
+	require.Equal(t, []any{
+		"branch", "left", "left_key", "left_value",
+		"branch", "right",
+		"outer_key", "outer_value",
+	}, GetMetadata(joined))
+	require.Equal(t, "right", GetMetadataMap(joined)["branch"])
+
+	// Multiple %w verbs produce a multi-error as well.
+	require.Equal(t, []any{"branch", "left", "left_key", "left_value", "branch", "right"},
+		GetMetadata(fmt.Errorf("%w, %w", left, right)))
+}
+
+func TestGetMetadataDiamond(t *testing.T) {
+	// The same root error is wrapped by two branches, that are then joined.
+	root := WithMetadata(errors.New("root"), "shared", "root_value", "request_id", "42")
+	left := WithMetadata(fmt.Errorf("left: %w", root), "branch", "left")
+	right := WithMetadata(fmt.Errorf("right: %w", root), "branch", "right", "shared", "right_value")
+	diamond := errors.Join(left, right)
+
+	// The metadata of the shared root is collected once, before the metadata of both branches.
+	require.Equal(t, []any{
+		"shared", "root_value", "request_id", "42",
+		"branch", "left",
+		"branch", "right", "shared", "right_value",
+	}, GetMetadata(diamond))
+	require.Equal(t, GetMetadataMap(diamond), maps.Collect(All(diamond)))
+}
+
+func TestGetMetadataDoesNotAlias(t *testing.T) {
+	SetGlobalMetadata("version", "1.2.3")
+	t.Cleanup(func() { SetGlobalMetadata() })
+
+	inner := WithMetadata(errors.New("foo"), "k1", "v1")
+	err := WithMetadata(inner, "k2", "v2")
+	single := WithMetadata(errors.New("foo"), "k3", "v3")
+
+	for _, e := range []error{err, single} {
+		metadata := GetMetadata(e)
+		for i := range metadata {
+			metadata[i] = "mutated"
+		}
+		_ = append(metadata[:0], "appended")
+	}
+
+	require.Equal(t, []any{"version", "1.2.3", "k1", "v1", "k2", "v2"}, GetMetadata(err))
+	require.Equal(t, []any{"k1", "v1"}, layerMetadata(inner))
+	require.Equal(t, []any{"version", "1.2.3", "k3", "v3"}, GetMetadata(single))
+}
+
+func TestWithValues(t *testing.T) {
+	require.NoError(t, WithValues(nil, "key", "value"))
+	err := WithValues(errors.New("foo"), "key", "value", Metadata{"k1", "v1"})
+	require.Equal(t, WithMetadata(errors.New("foo"), "key", "value", Metadata{"k1", "v1"}), err)
+}
+
+func TestMetadataError(t *testing.T) {
+	err := fmt.Errorf("foo: %w", WithMetadata(WithMetadata(errors.New("bar"), "inner", 1), "key", "value"))
+
+	var metadataErr MetadataError
+	require.ErrorAs(t, err, &metadataErr)
+	require.Equal(t, []any{"key", "value"}, metadataErr.Metadata())
+
+	// The returned slice is a copy.
+	metadataErr.Metadata()[1] = "changed"
+	require.Equal(t, []any{"key", "value"}, metadataErr.Metadata())
+	require.Equal(t, codes.Unknown, metadataErr.Code())
+
+	// The chain can be walked through the interface.
+	inner, ok := metadataErr.Unwrap().(MetadataError) // nolint: errorlint // errors.As should not be used here
+	require.True(t, ok)
+	require.Equal(t, []any{"inner", 1}, inner.Metadata())
+	require.EqualError(t, inner.Unwrap(), "bar")
+
+	require.ErrorAs(t, WithCode(status.Error(codes.NotFound, "foo"), codes.Internal), &metadataErr)
+	require.Equal(t, codes.Internal, metadataErr.Code())
+	// A layer without a code of its own reports the code of the chain it wraps.
+	require.ErrorAs(t, WithMetadata(WithCode(errors.New("foo"), codes.NotFound), "key", "value"), &metadataErr)
+	require.Equal(t, codes.NotFound, metadataErr.Code())
+
+	require.NotErrorAs(t, errors.New("foo"), &metadataErr)
+}
+
+func TestGRPCStatusRepeatedKey(t *testing.T) {
+	err := WithMetadata(
+		fmt.Errorf("outer: %w", WithMetadata(
+			WithMetadata(errors.New("foo"), "key", "inner", "other", 1),
+			"key", "middle")),
+		"key", "outer", "key", "outermost")
+	require.Equal(t, "outermost", GetMetadataMap(err)["key"])
+
+	for range 10 {
+		st, ok := status.FromError(err)
+		require.True(t, ok)
+		var fields map[string]*structpb.Value
+		for _, detail := range st.Details() {
+			if s, ok := detail.(*structpb.Struct); ok && isMetadataStruct(s) {
+				fields = s.GetFields()
+			}
+		}
+		require.Equal(t, "outermost", fields["key"].GetStringValue())
+		require.Equal(t, GetMetadataMap(err), GetMetadataMap(roundTrip(t, err)))
+	}
+}
+
+type branchError struct {
+	msg string
+}
+
+func (e *branchError) Error() string {
+	return e.msg
+}
+
+func TestUnwrapJoined(t *testing.T) {
+	sentinel := errors.New("sentinel")
+	branch := &branchError{msg: "branch"}
+	joined := errors.Join(errors.New("first"), WithMetadata(sentinel, "key", "value"), branch)
+	err := fmt.Errorf("outer: %w", WithCode(WithMetadata(joined, "outer_key", "outer_value"), codes.Internal))
+
+	// The wrapped multi-error is returned as is, so its branches are reachable through our layers.
+	var multi interface{ Unwrap() []error }
+	require.ErrorAs(t, err, &multi)
+	require.Len(t, multi.Unwrap(), 3)
+
+	require.ErrorIs(t, err, sentinel)
+	var target *branchError
+	require.ErrorAs(t, err, &target)
+	require.Same(t, branch, target)
+	require.Equal(t, map[string]any{"key": "value", "outer_key": "outer_value"}, GetMetadataMap(err))
+}
+
+func TestWithMetadataOnce(t *testing.T) {
+	require.NoError(t, WithMetadataOnce(nil, "key", "value"))
+	base := errors.New("foo")
+	require.Equal(t, base, WithMetadataOnce(base))
+
+	err := WithMetadataOnce(base, "key", []int{1, 2}, "attempt")
+	require.NotEqual(t, base, err)
+	require.Equal(t, []any{"key", []int{1, 2}, "attempt", "<missing>"}, GetMetadata(err))
+
+	// Identical metadata, values compared deeply.
+	require.Same(t, err, WithMetadataOnce(err, "key", []int{1, 2}, "attempt"))
+	require.Same(t, err, WithMetadataOnce(err, []any{"key", []int{1, 2}, "attempt", "<missing>"}))
+	wrapped := fmt.Errorf("bar: %w", err)
+	require.Equal(t, wrapped, WithMetadataOnce(wrapped, "key", []int{1, 2}, "attempt"))
+	withCode := WithCode(err, codes.NotFound)
+	require.Equal(t, withCode, WithMetadataOnce(withCode, "key", []int{1, 2}, "attempt"))
+
+	testCases := []struct {
+		name      string
+		err       error
+		keyValues []any
+	}{
+		{name: "different value", err: err, keyValues: []any{"key", []int{1, 3}, "attempt"}},
+		{name: "different type", err: err, keyValues: []any{"key", []int64{1, 2}, "attempt"}},
+		{name: "subset", err: err, keyValues: []any{"key", []int{1, 2}}},
+		{name: "different order", err: err, keyValues: []any{"attempt", "<missing>", "key", []int{1, 2}}},
+		{name: "inner layer", err: WithMetadata(err, "other", 1), keyValues: []any{"key", []int{1, 2}, "attempt"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			wrapped := WithMetadataOnce(tc.err, tc.keyValues...)
+			require.NotSame(t, tc.err, wrapped)
+			require.Equal(t, tc.err, errors.Unwrap(wrapped))
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package errtest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	errhelper "github.com/qdrant/go-commons/pkg/errors"
+	"github.com/qdrant/go-commons/pkg/errors/httpmw"
+)
+
+// RequireHTTPStatus asserts that the error maps to the wanted HTTP status code, as returned by
+// httpmw.HTTPStatusCode for the gRPC code of the error, e.g. in REST handler tests:
+//
+//	errtest.RequireHTTPStatus(t, err, http.StatusNotFound)
+//
+// A nil error is expected to map to 200. A non-nil error mapping to 200, i.e. one whose chain carries
+// a gRPC status reporting codes.OK, always fails the test, as it would be reported to clients as a success.
+// On mismatch, the failure message includes the gRPC code and the message of the error.
+func RequireHTTPStatus(t testing.TB, err error, want int) {
+	t.Helper()
+	code := errhelper.CodeOf(err)
+	got := httpmw.HTTPStatusCode(code)
+	if err == nil {
+		require.Equal(t, want, got, "nil error maps to %d, want %d", got, want)
+		return
+	}
+	require.NotEqual(t, http.StatusOK, got, "error %q with code %s maps to a success", err.Error(), code)
+	require.Equal(t, want, got, "error %q with code %s maps to %d, want %d", err.Error(), code, got, want)
+}
@@ -0,0 +1,55 @@
+package errtest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	errhelper "github.com/qdrant/go-commons/pkg/errors"
+)
+
+func TestRequireHTTPStatus(t *testing.T) {
+	RequireHTTPStatus(t, nil, http.StatusOK)
+	RequireHTTPStatus(t, errors.New("foo"), http.StatusInternalServerError)
+	RequireHTTPStatus(t, errhelper.WithCode(errors.New("foo"), codes.NotFound), http.StatusNotFound)
+	RequireHTTPStatus(t, fmt.Errorf("foo: %w", status.Error(codes.Unavailable, "down")), http.StatusServiceUnavailable)
+
+	testCases := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{
+			name: "nil error",
+			err:  nil,
+			want: http.StatusInternalServerError,
+		},
+		{
+			name: "different status",
+			err:  errhelper.WithCode(errors.New("foo"), codes.NotFound),
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "error mapping to 200",
+			err:  errhelper.WithCode(errors.New("foo"), codes.OK),
+			want: http.StatusOK,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &mockT{TB: t}
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				RequireHTTPStatus(mock, tc.err, tc.want)
+			}()
+			<-done
+			require.True(t, mock.failed)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package errtest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	errhelper "github.com/qdrant/go-commons/pkg/errors"
+)
+
+// RequireMetadata asserts that the metadata of the error, as returned by errhelper.GetMetadataMap,
+// contains the wanted keys with the wanted values, other keys being ignored. It works the same on errors
+// received from gRPC calls, so it can check the error contract of a service in integration tests.
+//
+// Numbers are compared by value whatever their type, and slices and maps by their elements whatever
+// their type, as numbers nested in lists and maps are received as float64, and lists and maps as []any
+// and map[string]any. On mismatch, the test fails with a diff of the wanted and actual values.
+func RequireMetadata(t testing.TB, err error, want map[string]any) {
+	t.Helper()
+	require.Error(t, err)
+	metadata := errhelper.GetMetadataMap(err)
+	got := make(map[string]any, len(want))
+	for key := range want {
+		if value, ok := metadata[key]; ok {
+			got[key] = value
+		}
+	}
+	require.Equal(t, normalize(want), normalize(got), "metadata of error %q", err.Error())
+}
+
+// normalize converts numbers to float64, slices to []any and maps with string keys to map[string]any, recursively.
+func normalize(v any) any {
+	rv := reflect.ValueOf(v)
+	switch {
+	case !rv.IsValid():
+		return nil
+	case rv.CanInt():
+		return float64(rv.Int())
+	case rv.CanUint():
+		return float64(rv.Uint())
+	case rv.CanFloat():
+		return rv.Float()
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if _, ok := v.([]byte); ok {
+			return v
+		}
+		normalized := make([]any, rv.Len())
+		for i := range normalized {
+			normalized[i] = normalize(rv.Index(i).Interface())
+		}
+		return normalized
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return v
+		}
+		normalized := make(map[string]any, rv.Len())
+		for iter := rv.MapRange(); iter.Next(); {
+			normalized[iter.Key().String()] = normalize(iter.Value().Interface())
+		}
+		return normalized
+	}
+	return v
+}
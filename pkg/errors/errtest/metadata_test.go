@@ -0,0 +1,81 @@
+package errtest
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	errhelper "github.com/qdrant/go-commons/pkg/errors"
+)
+
+// mockT records the failures of the assertions.
+type mockT struct {
+	testing.TB
+	failed bool
+}
+
+func (m *mockT) Helper() {}
+
+func (m *mockT) Errorf(string, ...any) {
+	m.failed = true
+}
+
+func (m *mockT) FailNow() {
+	m.failed = true
+	runtime.Goexit()
+}
+
+func TestRequireMetadata(t *testing.T) {
+	err := errhelper.WithMetadata(errhelper.WithCode(errors.New("foo"), codes.NotFound),
+		"id", 42, "ids", []int{1, 2}, "labels", map[string]string{"key": "value"}, "other", "ignored")
+	received := status.FromProto(status.Convert(err).Proto()).Err()
+
+	want := map[string]any{"id": 42, "ids": []int{1, 2}, "labels": map[string]any{"key": "value"}}
+	for _, err := range []error{err, received} {
+		RequireMetadata(t, err, want)
+	}
+
+	testCases := []struct {
+		name string
+		err  error
+		want map[string]any
+	}{
+		{
+			name: "nil error",
+			err:  nil,
+			want: map[string]any{},
+		},
+		{
+			name: "missing key",
+			err:  received,
+			want: map[string]any{"missing": "value"},
+		},
+		{
+			name: "different value",
+			err:  received,
+			want: map[string]any{"id": 43},
+		},
+		{
+			name: "different type",
+			err:  received,
+			want: map[string]any{"id": "42"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &mockT{TB: t}
+			done := make(chan struct{})
+			// FailNow stops the goroutine, like it does for a test.
+			go func() {
+				defer close(done)
+				RequireMetadata(mock, tc.err, tc.want)
+			}()
+			<-done
+			require.True(t, mock.failed)
+		})
+	}
+}
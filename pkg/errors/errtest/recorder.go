@@ -0,0 +1,187 @@
+// Package errtest provides helpers to test the errors produced with the errors package.
+package errtest
+
+import (
+	"bytes"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+
+	errhelper "github.com/qdrant/go-commons/pkg/errors"
+)
+
+// Wrap describes an error wrapped with errhelper.WithMetadata or errhelper.WithCode.
+type Wrap struct {
+	// Code is the code of the resulting error.
+	Code codes.Code
+	// Keys are the metadata keys added by the wrap.
+	Keys []string
+}
+
+// Recorder records the wraps observed with errhelper.SetWrapObserver while it's open.
+type Recorder struct {
+	mu    sync.Mutex
+	wraps []Wrap
+	// allGoroutines is set by the AllGoroutines option, otherwise only the wraps of the goroutines are recorded.
+	allGoroutines bool
+	goroutines    map[uint64]struct{}
+}
+
+// RecorderOption configures NewRecorder.
+type RecorderOption func(*recorderOptions)
+
+type recorderOptions struct {
+	allGoroutines bool
+}
+
+// AllGoroutines records the wraps of all the goroutines of the process, e.g. when the code under test
+// wraps errors in goroutines it starts itself. The recorded wraps then include the ones of parallel tests.
+func AllGoroutines() RecorderOption {
+	return func(o *recorderOptions) {
+		o.allGoroutines = true
+	}
+}
+
+var (
+	// mu guards recorders and previous.
+	mu sync.Mutex
+	// recorders holds the open recorders.
+	recorders = map[*Recorder]struct{}{}
+	// previous holds the observer installed before the first recorder was opened.
+	previous func(code codes.Code, keys []string)
+)
+
+// NewRecorder returns an open recorder. The first open recorder installs a wrap observer,
+// which keeps calling the observer installed before, and the last closed one restores it.
+//
+// Several recorders can be open at once, e.g. in parallel tests. Although the wrap observer is process-wide,
+// a recorder only records the wraps of the goroutine which created it, usually the goroutine of the test,
+// and of the goroutines added with Include, so the recorded wraps of parallel tests don't mix.
+// Use the AllGoroutines option to record the wraps of all goroutines instead.
+func NewRecorder(opts ...RecorderOption) *Recorder {
+	o := recorderOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	r := &Recorder{allGoroutines: o.allGoroutines}
+	if !r.allGoroutines {
+		r.goroutines = map[uint64]struct{}{goroutineID(): {}}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(recorders) == 0 {
+		previous = errhelper.GetWrapObserver()
+		errhelper.SetWrapObserver(observe)
+	}
+	recorders[r] = struct{}{}
+	return r
+}
+
+// Close stops recording. Closing a recorder twice is a no-op.
+func (r *Recorder) Close() {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := recorders[r]; !ok {
+		return
+	}
+	delete(recorders, r)
+	if len(recorders) == 0 {
+		errhelper.SetWrapObserver(previous)
+		previous = nil
+	}
+}
+
+// Include adds the calling goroutine to the goroutines whose wraps are recorded, e.g. in a goroutine
+// started by the test:
+//
+//	go func() {
+//		r.Include()
+//		...
+//	}()
+func (r *Recorder) Include() {
+	id := goroutineID()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.goroutines != nil {
+		r.goroutines[id] = struct{}{}
+	}
+}
+
+// records reports whether the recorder records the wraps of the goroutine.
+// It must be called with r.mu held.
+func (r *Recorder) records(goroutine uint64) bool {
+	if r.allGoroutines {
+		return true
+	}
+	_, ok := r.goroutines[goroutine]
+	return ok
+}
+
+// Wraps returns the recorded wraps, in the order they were observed.
+func (r *Recorder) Wraps() []Wrap {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.wraps)
+}
+
+// Has reports whether a wrap producing an error with the code and adding at least the provided keys was recorded.
+func (r *Recorder) Has(code codes.Code, keys ...string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.ContainsFunc(r.wraps, func(w Wrap) bool {
+		if w.Code != code {
+			return false
+		}
+		for _, key := range keys {
+			if !slices.Contains(w.Keys, key) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Reset drops the recorded wraps.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.wraps = nil
+}
+
+// observe records the wrap in all the open recorders and calls the previous observer.
+func observe(code codes.Code, keys []string) {
+	mu.Lock()
+	open := make([]*Recorder, 0, len(recorders))
+	for r := range recorders {
+		open = append(open, r)
+	}
+	prev := previous
+	mu.Unlock()
+	goroutine := goroutineID()
+	for _, r := range open {
+		r.mu.Lock()
+		if r.records(goroutine) {
+			r.wraps = append(r.wraps, Wrap{Code: code, Keys: slices.Clone(keys)})
+		}
+		r.mu.Unlock()
+	}
+	if prev != nil {
+		prev(code, keys)
+	}
+}
+
+// goroutineID returns the ID of the calling goroutine, read from the header of its stack trace,
+// e.g. "goroutine 18 [running]:".
+func goroutineID() uint64 {
+	var buf [64]byte
+	header := buf[:runtime.Stack(buf[:], false)]
+	header = bytes.TrimPrefix(header, []byte("goroutine "))
+	if i := bytes.IndexByte(header, ' '); i >= 0 {
+		header = header[:i]
+	}
+	id, _ := strconv.ParseUint(string(header), 10, 64)
+	return id
+}
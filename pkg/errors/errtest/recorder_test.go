@@ -0,0 +1,87 @@
+package errtest
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	errhelper "github.com/qdrant/go-commons/pkg/errors"
+)
+
+func TestRecorder(t *testing.T) {
+	var previousCalls atomic.Int32
+	errhelper.SetWrapObserver(func(codes.Code, []string) { previousCalls.Add(1) })
+	t.Cleanup(func() { errhelper.SetWrapObserver(nil) })
+
+	r := NewRecorder()
+	_ = errhelper.WithMetadata(errhelper.WithCode(errors.New("foo"), codes.Internal), "tenant", "t1", "operation", "search")
+	require.Equal(t, []Wrap{
+		{Code: codes.Internal},
+		{Code: codes.Internal, Keys: []string{"tenant", "operation"}},
+	}, r.Wraps())
+	require.True(t, r.Has(codes.Internal, "tenant", "operation"))
+	require.True(t, r.Has(codes.Internal))
+	require.False(t, r.Has(codes.Internal, "tenant", "attempt"))
+	require.False(t, r.Has(codes.NotFound))
+	require.Equal(t, int32(2), previousCalls.Load())
+
+	nested := NewRecorder()
+	_ = errhelper.WithMetadata(errors.New("foo"), "key", "value")
+	require.Equal(t, []Wrap{{Code: codes.Unknown, Keys: []string{"key"}}}, nested.Wraps())
+	require.Len(t, r.Wraps(), 3)
+
+	r.Reset()
+	require.Empty(t, r.Wraps())
+	nested.Close()
+	r.Close()
+	r.Close()
+
+	// The previous observer is restored.
+	_ = errhelper.WithMetadata(errors.New("foo"), "key", "value")
+	require.Empty(t, r.Wraps())
+	require.Equal(t, int32(4), previousCalls.Load())
+}
+
+func TestRecorderParallel(t *testing.T) {
+	for _, tenant := range []string{"t1", "t2", "t3"} {
+		t.Run(tenant, func(t *testing.T) {
+			t.Parallel()
+			r := NewRecorder()
+			t.Cleanup(r.Close)
+			for range 100 {
+				_ = errhelper.WithTenant(errhelper.WithCode(errors.New("foo"), codes.NotFound), tenant)
+			}
+			// Only the wraps of the test are recorded.
+			require.Len(t, r.Wraps(), 200)
+		})
+	}
+}
+
+func TestRecorderGoroutines(t *testing.T) {
+	r := NewRecorder()
+	t.Cleanup(r.Close)
+	all := NewRecorder(AllGoroutines())
+	t.Cleanup(all.Close)
+
+	wrapInGoroutine := func(include bool) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if include {
+				r.Include()
+			}
+			_ = errhelper.WithMetadata(errors.New("foo"), "key", "value")
+		}()
+		<-done
+	}
+	wrapInGoroutine(false)
+	require.Empty(t, r.Wraps())
+	require.Len(t, all.Wraps(), 1)
+
+	wrapInGoroutine(true)
+	require.Equal(t, []Wrap{{Code: codes.Unknown, Keys: []string{"key"}}}, r.Wraps())
+	require.Len(t, all.Wraps(), 2)
+}
@@ -0,0 +1,31 @@
+package errors
+
+import (
+	"errors"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Expected returns an error for an expected outcome rather than a genuine failure, such as a cache miss,
+// with the provided code, message and metadata, like WithMetadata(WithCode(errors.New(msg), code), keyValues...).
+// The error is flagged so IsExpected reports true, e.g. to log it at debug level rather than as an error.
+// An empty message is replaced with the default text of the code (see DefaultMessageFor).
+func Expected(code codes.Code, msg string, keyValues ...any) error {
+	if msg == "" {
+		msg = DefaultMessageFor(code)
+	}
+	metadata := make([]any, 0, len(keyValues)+2)
+	metadata = append(metadata, expectedKey, true)
+	metadata = append(metadata, keyValues...)
+	return WithMetadata(WithCode(errors.New(msg), code), metadata...)
+}
+
+// IsExpected reports whether the error was created with Expected.
+func IsExpected(err error) bool {
+	v, ok := lookup(err, expectedKey)
+	if !ok {
+		return false
+	}
+	expected, ok := v.(bool)
+	return ok && expected
+}
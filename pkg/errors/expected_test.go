@@ -0,0 +1,29 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestExpected(t *testing.T) {
+	err := Expected(codes.NotFound, "cache miss", "reason", "evicted")
+	require.EqualError(t, err, "cache miss")
+	require.Equal(t, codes.NotFound, CodeOf(err))
+	require.Equal(t, "evicted", GetMetadataMap(err)["reason"])
+	require.True(t, IsExpected(err))
+	require.True(t, IsExpected(fmt.Errorf("lookup: %w", WithMetadata(err, "key", "value"))))
+
+	require.False(t, IsExpected(nil))
+	require.False(t, IsExpected(errors.New("foo")))
+	require.False(t, IsExpected(WithCode(errors.New("foo"), codes.NotFound)))
+
+	// The flag survives gRPC round trips.
+	received := roundTrip(t, err)
+	require.True(t, IsExpected(received))
+	require.Equal(t, codes.NotFound, CodeOf(received))
+	require.Equal(t, "evicted", GetMetadataMap(received)["reason"])
+}
@@ -0,0 +1,44 @@
+package errors
+
+import "time"
+
+// Field is a typed metadata key value pair, built with Str, Int, Bool or Dur, and attached with WrapFields.
+// Unlike the key value pairs of WithMetadata, a value can't be forgotten.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Str returns a string field.
+func Str(key, value string) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Int returns an integer field, received as an int on the other side of a gRPC call.
+func Int(key string, value int) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Bool returns a boolean field.
+func Bool(key string, value bool) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Dur returns a duration field, received as a time.Duration on the other side of a gRPC call.
+func Dur(key string, value time.Duration) Field {
+	return Field{Key: key, Value: value}
+}
+
+// WrapFields returns the provided error wrapped with the fields, like WithMetadata.
+// The type of the fields is preserved by GRPCStatus, so GetMetadata returns the same values
+// on the other side of a gRPC call. It returns nil for a nil error.
+func WrapFields(err error, fields ...Field) error {
+	if err == nil || len(fields) == 0 {
+		return err
+	}
+	keyValues := make([]any, 0, len(fields)*2)
+	for _, f := range fields {
+		keyValues = append(keyValues, f.Key, f.Value)
+	}
+	return WithMetadata(err, keyValues...)
+}
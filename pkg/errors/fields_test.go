@@ -0,0 +1,22 @@
+package errors
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrapFields(t *testing.T) {
+	require.NoError(t, WrapFields(nil, Str("key", "value")))
+	plain := errors.New("foo")
+	require.Equal(t, plain, WrapFields(plain))
+
+	err := WrapFields(plain, Str("name", "search"), Int("count", 42), Bool("cached", true), Dur("took", 1500*time.Millisecond))
+	expected := []any{"name", "search", "count", 42, "cached", true, "took", 1500 * time.Millisecond}
+	require.Equal(t, expected, GetMetadata(err))
+
+	// The types are preserved over gRPC.
+	require.ElementsMatch(t, expected, GetMetadata(roundTrip(t, err)))
+}
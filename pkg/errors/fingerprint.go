@@ -0,0 +1,41 @@
+package errors
+
+import (
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// Fingerprint returns a short hash identifying identical errors, e.g. to deduplicate the errors of a retry loop:
+// errors with the same gRPC code, root error type (see Summary), message and set of metadata keys
+// share the same fingerprint, whatever the values of their metadata. The global metadata is ignored.
+// The fingerprint is stable across processes running the same code, however it's not meant to be stored.
+// It returns an empty string for a nil error.
+func Fingerprint(err error) string {
+	if err == nil {
+		return ""
+	}
+	h := fnv.New64a()
+	_, _ = fmt.Fprintf(h, "%s\x00%T\x00%s", CodeName(err), rootError(err), err.Error())
+	for _, key := range slices.Sorted(maps.Keys(chainKeys(err))) {
+		_, _ = fmt.Fprintf(h, "\x00%s", key)
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// SameClass reports whether the errors belong to the same class, e.g. to cluster occurrences of a failure:
+// errors with the same gRPC code, root error type (see Summary) and set of metadata keys, whatever their
+// messages and the values of their metadata. Unlike comparing fingerprints, the message is not considered
+// and nothing is hashed. The global metadata is ignored. Two nil errors are the same class,
+// while a nil error and a non-nil one are not.
+func SameClass(a, b error) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if CodeOf(a) != CodeOf(b) || reflect.TypeOf(rootError(a)) != reflect.TypeOf(rootError(b)) {
+		return false
+	}
+	return maps.Equal(chainKeys(a), chainKeys(b))
+}
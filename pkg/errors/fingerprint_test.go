@@ -0,0 +1,84 @@
+package errors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string {
+	return "not found"
+}
+
+func TestFingerprint(t *testing.T) {
+	require.Empty(t, Fingerprint(nil))
+
+	err := WithMetadata(WithCode(errors.New("not found"), codes.NotFound), "collection", "a", "shard", 1)
+	fingerprint := Fingerprint(err)
+	require.Len(t, fingerprint, 16)
+
+	// Same code, root type, message and keys.
+	require.Equal(t, fingerprint, Fingerprint(WithMetadata(WithCode(errors.New("not found"), codes.NotFound), "shard", 2, "collection", "b")))
+	SetGlobalMetadata("version", "1.0")
+	t.Cleanup(func() { SetGlobalMetadata() })
+	require.Equal(t, fingerprint, Fingerprint(err))
+
+	testCases := []struct {
+		name string
+		err  error
+	}{
+		{name: "different code", err: WithMetadata(WithCode(errors.New("not found"), codes.Internal), "collection", "a", "shard", 1)},
+		{name: "different message", err: WithMetadata(WithCode(errors.New("missing"), codes.NotFound), "collection", "a", "shard", 1)},
+		{name: "different root type", err: WithMetadata(WithCode(notFoundError{}, codes.NotFound), "collection", "a", "shard", 1)},
+		{name: "different keys", err: WithMetadata(WithCode(errors.New("not found"), codes.NotFound), "collection", "a")},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NotEqual(t, fingerprint, Fingerprint(tc.err))
+		})
+	}
+}
+
+func TestSameClass(t *testing.T) {
+	require.True(t, SameClass(nil, nil))
+	require.False(t, SameClass(nil, errors.New("foo")))
+	require.False(t, SameClass(errors.New("foo"), nil))
+
+	err := WithMetadata(WithCode(errors.New("collection a not found"), codes.NotFound), "collection", "a", "shard", 1)
+	testCases := []struct {
+		name     string
+		other    error
+		expected bool
+	}{
+		{
+			name:     "different message and values",
+			other:    WithMetadata(WithCode(errors.New("collection b not found"), codes.NotFound), "shard", 2, "collection", "b"),
+			expected: true,
+		},
+		{
+			name:     "different code",
+			other:    WithMetadata(WithCode(errors.New("collection a not found"), codes.Internal), "collection", "a", "shard", 1),
+			expected: false,
+		},
+		{
+			name:     "different root type",
+			other:    WithMetadata(WithCode(notFoundError{}, codes.NotFound), "collection", "a", "shard", 1),
+			expected: false,
+		},
+		{
+			name:     "different keys",
+			other:    WithMetadata(WithCode(errors.New("collection a not found"), codes.NotFound), "collection", "a"),
+			expected: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, SameClass(err, tc.other))
+			require.Equal(t, tc.expected, SameClass(tc.other, err))
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package errors
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// globalMetadata holds the process-wide metadata set with SetGlobalMetadata.
+	globalMetadata atomic.Pointer[[]any]
+	// keyTransformer holds the function set with SetKeyTransformer.
+	keyTransformer atomic.Pointer[func(string) string]
+	// metadataMarkers holds the additional markers registered with RegisterMetadataMarker,
+	// it's only written under markersMu.
+	metadataMarkers atomic.Pointer[[]string]
+	markersMu       sync.Mutex
+)
+
+// SetGlobalMetadata registers process-wide metadata, such as the service version and commit,
+// that GetMetadata and GRPCStatus attach to every error with the lowest precedence,
+// so metadata attached to the error always overrides it.
+// Calling it again replaces the previous global metadata, calling it without arguments clears it.
+//
+// It is part of the global configuration, see the package documentation: set before serving traffic,
+// all errors carry the same global metadata.
+func SetGlobalMetadata(keyValues ...any) {
+	metadata := addPaddingForMissingValue(keyValues)
+	globalMetadata.Store(&metadata)
+}
+
+// getGlobalMetadata returns the metadata set with SetGlobalMetadata.
+// The returned slice must not be modified.
+func getGlobalMetadata() []any {
+	if metadata := globalMetadata.Load(); metadata != nil {
+		return *metadata
+	}
+	return nil
+}
+
+// SetKeyTransformer registers a function that transforms metadata keys when metadata is exported
+// to logs, e.g. to enforce a snake_case naming convention. It is applied by GetMetadataMap and Logfmt,
+// while GetMetadata and GRPCStatus keep the original keys.
+// The transformation is applied after deduplication, and if several keys are transformed into
+// the same key, the value of the one set last wins.
+// Calling it with nil restores the default, which keeps keys unchanged.
+//
+// It is part of the global configuration, see the package documentation.
+func SetKeyTransformer(fn func(string) string) {
+	if fn == nil {
+		keyTransformer.Store(nil)
+		return
+	}
+	keyTransformer.Store(&fn)
+}
+
+// getKeyTransformer returns the function set with SetKeyTransformer, or nil.
+func getKeyTransformer() func(string) string {
+	if fn := keyTransformer.Load(); fn != nil {
+		return *fn
+	}
+	return nil
+}
+
+// RegisterMetadataMarker registers an additional key marking the protobuf Struct carrying the metadata
+// in gRPC status details, so GetMetadata recognizes metadata produced by services using a different
+// marker, e.g. an older version of this package. GRPCStatus always writes the current marker,
+// and replaces the structs marked with any registered marker.
+// By default, only the current marker is recognized.
+//
+// To migrate to a different marker, first roll out all the services with the new marker registered,
+// so they read the metadata produced by both versions, then roll out the services writing the new marker.
+// Once every service writes the new marker, the registration can be removed.
+//
+// It is part of the global configuration, see the package documentation.
+func RegisterMetadataMarker(marker string) {
+	markersMu.Lock()
+	defer markersMu.Unlock()
+	markers := getMetadataMarkers()
+	if marker == qdrantMetadataMarker || slices.Contains(markers, marker) {
+		return
+	}
+	markers = append(slices.Clip(markers), marker)
+	metadataMarkers.Store(&markers)
+}
+
+// getMetadataMarkers returns the markers registered with RegisterMetadataMarker.
+// The returned slice must not be modified.
+func getMetadataMarkers() []string {
+	if markers := metadataMarkers.Load(); markers != nil {
+		return *markers
+	}
+	return nil
+}
+
+// isMetadataMarker reports whether the key is the current marker or a registered one.
+func isMetadataMarker(key string) bool {
+	return key == qdrantMetadataMarker || slices.Contains(getMetadataMarkers(), key)
+}
@@ -0,0 +1,98 @@
+package errors
+
+import (
+	"errors"
+	"maps"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestSetGlobalMetadata(t *testing.T) {
+	SetGlobalMetadata("version", "1.2.3", "commit", "abc123")
+	t.Cleanup(func() { SetGlobalMetadata() })
+
+	require.Equal(t, []any{}, GetMetadata(nil))
+	require.Equal(t, []any{"version", "1.2.3", "commit", "abc123"}, GetMetadata(errors.New("foo")))
+
+	// Local metadata overrides global metadata.
+	err := WithMetadata(status.Error(codes.NotFound, "item not found"), "version", "local", "key", "value")
+	require.Equal(t, []any{"version", "1.2.3", "commit", "abc123", "version", "local", "key", "value"}, GetMetadata(err))
+	expected := map[string]any{"version": "local", "commit": "abc123", "key": "value"}
+	require.Equal(t, expected, GetMetadataMap(err))
+	require.Equal(t, expected, maps.Collect(All(err)))
+
+	// Global metadata is sent over the wire.
+	received := roundTrip(t, err)
+	SetGlobalMetadata()
+	require.Equal(t, expected, GetMetadataMap(received))
+}
+
+func TestSetGlobalMetadataMissingValue(t *testing.T) {
+	SetGlobalMetadata("version")
+	t.Cleanup(func() { SetGlobalMetadata() })
+
+	require.Equal(t, []any{"version", "<missing>"}, GetMetadata(errors.New("foo")))
+}
+
+func TestSetKeyTransformer(t *testing.T) {
+	SetKeyTransformer(func(key string) string {
+		var b strings.Builder
+		for i, r := range key {
+			if unicode.IsUpper(r) {
+				if i > 0 {
+					b.WriteByte('_')
+				}
+				r = unicode.ToLower(r)
+			}
+			b.WriteRune(r)
+		}
+		return b.String()
+	})
+	t.Cleanup(func() { SetKeyTransformer(nil) })
+
+	err := WithMetadata(
+		WithMetadata(errors.New("foo"), "RequestID", "inner", "userId", "u1"),
+		"RequestID", "outer", "request_i_d", "collision",
+	)
+
+	// GetMetadata keeps the original keys.
+	require.Equal(t, []any{"RequestID", "inner", "userId", "u1", "RequestID", "outer", "request_i_d", "collision"}, GetMetadata(err))
+	// Keys are transformed after deduplication, colliding keys are last-wins.
+	require.Equal(t, map[string]any{"request_i_d": "collision", "user_id": "u1"}, GetMetadataMap(err))
+	require.Equal(t, `msg="foo" code=Unknown request_i_d=collision user_id=u1`, Logfmt(err))
+
+	SetKeyTransformer(nil)
+	require.Equal(t, map[string]any{"RequestID": "outer", "request_i_d": "collision", "userId": "u1"}, GetMetadataMap(err))
+}
+
+func TestRegisterMetadataMarker(t *testing.T) {
+	const legacyMarker = "__qdrant_legacy_metadata__"
+	legacyStruct, err := structpb.NewStruct(map[string]any{"key": "value", legacyMarker: true})
+	require.NoError(t, err)
+	legacyStatus, err := status.New(codes.NotFound, "item not found").WithDetails(legacyStruct)
+	require.NoError(t, err)
+	legacyErr := legacyStatus.Err()
+
+	// By default, only the current marker is recognized.
+	require.Empty(t, GetMetadata(legacyErr))
+
+	RegisterMetadataMarker(legacyMarker)
+	t.Cleanup(ClearMetadataMarkers)
+
+	require.Equal(t, []any{"key", "value"}, GetMetadata(legacyErr))
+
+	// The legacy struct is replaced by one with the current marker.
+	st, ok := status.FromError(WithMetadata(legacyErr, "other_key", "other_value"))
+	require.True(t, ok)
+	require.Len(t, st.Details(), 1)
+	fields := st.Details()[0].(*structpb.Struct).GetFields()
+	require.Contains(t, fields, qdrantMetadataMarker)
+	require.NotContains(t, fields, legacyMarker)
+	require.Equal(t, map[string]any{"key": "value", "other_key": "other_value"}, GetMetadataMap(roundTrip(t, st.Err())))
+}
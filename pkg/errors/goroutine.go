@@ -0,0 +1,41 @@
+package errors
+
+import "time"
+
+// Go runs fn in a new goroutine, and returns a channel receiving its error, if any, for background workers
+// to surface their errors with identifying context:
+//
+//	errCh := errhelper.Go("compaction", func() error {
+//		return compact(ctx)
+//	})
+//	...
+//	if err := <-errCh; err != nil {
+//		logger.Error("background task failed", errhelper.GetMetadata(err)...)
+//	}
+//
+// The error is wrapped with the name under the "goroutine" key, and the time the goroutine was started under
+// the "goroutine_started" key, to find which task produced it, e.g. when debugging leaks. A panic of fn is
+// recovered and converted with RecoverToError. The channel is closed once fn returned, after sending its error
+// if it failed, so receiving from it also waits for the goroutine. It's buffered, so the goroutine doesn't leak
+// if the error is never received. Go is not a supervisor: fn is neither restarted nor canceled.
+func Go(name string, fn func() error) <-chan error {
+	errCh := make(chan error, 1)
+	started := time.Now()
+	go func() {
+		defer close(errCh)
+		if err := runRecovered(fn); err != nil {
+			errCh <- WithMetadata(err, goroutineKey, name, goroutineStartedKey, started)
+		}
+	}()
+	return errCh
+}
+
+// runRecovered calls fn, converting its panic into an error.
+func runRecovered(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = RecoverToError(r)
+		}
+	}()
+	return fn()
+}
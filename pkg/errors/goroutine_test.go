@@ -0,0 +1,43 @@
+package errors
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestGo(t *testing.T) {
+	before := time.Now()
+	cause := errors.New("compaction failed")
+	errCh := Go("compaction", func() error {
+		return cause
+	})
+	err := <-errCh
+	require.ErrorIs(t, err, cause)
+	metadata := GetMetadataMap(err)
+	require.Equal(t, "compaction", metadata["goroutine"])
+	started, ok := metadata["goroutine_started"].(time.Time)
+	require.True(t, ok)
+	require.False(t, started.Before(before))
+	_, open := <-errCh
+	require.False(t, open)
+
+	// Successful goroutines only close the channel.
+	_, open = <-Go("noop", func() error { return nil })
+	require.False(t, open)
+
+	// Panics are converted to errors.
+	errCh = Go("panicking", func() error {
+		panic("boom")
+	})
+	err = <-errCh
+	require.EqualError(t, err, "panic: boom")
+	require.Equal(t, codes.Internal, CodeOf(err))
+	require.Equal(t, "panicking", GetMetadataMap(err)["goroutine"])
+	require.Contains(t, GetMetadataMap(err)["panic_stack"], "TestGo")
+	_, open = <-errCh
+	require.False(t, open)
+}
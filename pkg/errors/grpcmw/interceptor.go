@@ -0,0 +1,49 @@
+// Package grpcmw provides gRPC server interceptors for the errors of the errors package.
+package grpcmw
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	errhelper "github.com/qdrant/go-commons/pkg/errors"
+)
+
+// methodKey is the metadata key holding the full name of the gRPC method which returned the error.
+const methodKey = "grpc.method"
+
+// UnaryServerInterceptor returns an interceptor attaching the full name of the gRPC method, e.g.
+// "/qdrant.Points/Search", to the errors returned by the handlers under the "grpc.method" key,
+// so the errors can be filtered by method without changing the handlers:
+//
+//	server := grpc.NewServer(grpc.ChainUnaryInterceptor(grpcmw.UnaryServerInterceptor()))
+//
+// A method already present in the error chain, e.g. attached by a nested call, is kept.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		return resp, withMethod(err, info.FullMethod)
+	}
+}
+
+// StreamServerInterceptor returns an interceptor attaching the full name of the gRPC method to the errors
+// returned by the stream handlers, like UnaryServerInterceptor.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return withMethod(handler(srv, ss), info.FullMethod)
+	}
+}
+
+// withMethod returns the error wrapped with the method, unless the chain already carries one.
+// It returns nil for a nil error.
+func withMethod(err error, method string) error {
+	if err == nil {
+		return nil
+	}
+	for key := range errhelper.All(err) {
+		if key == methodKey {
+			return err
+		}
+	}
+	return errhelper.WithMetadata(err, methodKey, method)
+}
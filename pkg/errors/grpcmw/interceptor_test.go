@@ -0,0 +1,80 @@
+package grpcmw
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	errhelper "github.com/qdrant/go-commons/pkg/errors"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/qdrant.Points/Search"}
+
+	testCases := []struct {
+		name     string
+		err      error
+		expected any
+	}{
+		{
+			name:     "plain error",
+			err:      errors.New("foo"),
+			expected: "/qdrant.Points/Search",
+		},
+		{
+			name:     "status error",
+			err:      status.Error(codes.NotFound, "not found"),
+			expected: "/qdrant.Points/Search",
+		},
+		{
+			name:     "method of a nested call",
+			err:      errhelper.WithMetadata(errors.New("foo"), "grpc.method", "/qdrant.Collections/Get"),
+			expected: "/qdrant.Collections/Get",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := interceptor(context.Background(), "req", info, func(context.Context, any) (any, error) {
+				return "resp", tc.err
+			})
+			require.Equal(t, "resp", resp)
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.expected, errhelper.GetMetadataMap(err)["grpc.method"])
+			require.Equal(t, status.Code(tc.err), status.Code(err))
+		})
+	}
+
+	// Errors are not wrapped twice.
+	wrapped := errhelper.WithMetadata(errors.New("foo"), "grpc.method", "/qdrant.Points/Search")
+	_, err := interceptor(context.Background(), "req", info, func(context.Context, any) (any, error) {
+		return nil, wrapped
+	})
+	require.Same(t, wrapped, err)
+
+	resp, err := interceptor(context.Background(), "req", info, func(context.Context, any) (any, error) {
+		return "resp", nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "resp", resp)
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	interceptor := StreamServerInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/qdrant.Points/Scroll", IsServerStream: true}
+
+	err := interceptor(nil, nil, info, func(any, grpc.ServerStream) error {
+		return status.Error(codes.Unavailable, "down")
+	})
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	require.Equal(t, "/qdrant.Points/Scroll", errhelper.GetMetadataMap(err)["grpc.method"])
+
+	require.NoError(t, interceptor(nil, nil, info, func(any, grpc.ServerStream) error {
+		return nil
+	}))
+}
@@ -0,0 +1,116 @@
+package errors
+
+import (
+	"encoding/base64"
+	"math"
+	"reflect"
+	"time"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// typeHintsMarker is a special key used to identify the structpb.Struct in gRPC status details
+// holding the type hints of the metadata: the original Go type of the values that protobuf can't
+// represent faithfully, by key, so GetMetadata can restore them on the receiving side.
+const typeHintsMarker = "__qdrant_metadata_types__"
+
+// hintedTypes holds the types restored using a type hint, by hint.
+var hintedTypes = map[string]reflect.Type{
+	"int":           reflect.TypeFor[int](),
+	"int8":          reflect.TypeFor[int8](),
+	"int16":         reflect.TypeFor[int16](),
+	"int32":         reflect.TypeFor[int32](),
+	"int64":         reflect.TypeFor[int64](),
+	"uint":          reflect.TypeFor[uint](),
+	"uint8":         reflect.TypeFor[uint8](),
+	"uint16":        reflect.TypeFor[uint16](),
+	"uint32":        reflect.TypeFor[uint32](),
+	"uint64":        reflect.TypeFor[uint64](),
+	"float32":       reflect.TypeFor[float32](),
+	"[]byte":        reflect.TypeFor[[]byte](),
+	"time.Time":     reflect.TypeFor[time.Time](),
+	"time.Duration": reflect.TypeFor[time.Duration](),
+}
+
+// typeHints holds the hints of the hinted types, by type.
+var typeHints = func() map[reflect.Type]string {
+	hints := make(map[reflect.Type]string, len(hintedTypes))
+	for hint, t := range hintedTypes {
+		hints[t] = hint
+	}
+	return hints
+}()
+
+// toHintedWireValue converts a value whose type is restored using a type hint into a protobuf Struct value,
+// and returns the hint. Numbers keep their native representation, so receivers unaware of the hints
+// still get a number, while times and durations are formatted so they can be parsed back.
+func toHintedWireValue(v any) (*structpb.Value, string, bool) {
+	hint, ok := typeHints[reflect.TypeOf(v)]
+	if !ok {
+		return nil, "", false
+	}
+	switch v := v.(type) {
+	case time.Time:
+		return structpb.NewStringValue(v.Format(time.RFC3339Nano)), hint, true
+	case time.Duration:
+		return structpb.NewStringValue(v.String()), hint, true
+	default:
+		return coerceWireValue(v), hint, true
+	}
+}
+
+// fromHintedWireValue restores a value of the type described by the hint from the value received over the wire.
+// If the hint is unknown or the value doesn't match it, the value is returned as is.
+func fromHintedWireValue(v any, hint string) any {
+	t, ok := hintedTypes[hint]
+	if !ok {
+		return v
+	}
+	switch v := v.(type) {
+	case float64:
+		restored := reflect.New(t).Elem()
+		switch {
+		case restored.CanInt() && v == math.Trunc(v) && !restored.OverflowInt(int64(v)):
+			restored.SetInt(int64(v))
+		case restored.CanUint() && v == math.Trunc(v) && v >= 0 && !restored.OverflowUint(uint64(v)):
+			restored.SetUint(uint64(v))
+		case restored.CanFloat():
+			restored.SetFloat(v)
+		default:
+			return v
+		}
+		return restored.Interface()
+	case string:
+		switch t {
+		case reflect.TypeFor[[]byte]():
+			if b, err := base64.StdEncoding.DecodeString(v); err == nil {
+				return b
+			}
+		case reflect.TypeFor[time.Time]():
+			if ts, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				return ts
+			}
+		case reflect.TypeFor[time.Duration]():
+			if d, err := time.ParseDuration(v); err == nil {
+				return d
+			}
+		}
+	}
+	return v
+}
+
+// findTypeHints returns the type hints struct among the status details, if any.
+func findTypeHints(details []any) map[string]*structpb.Value {
+	for _, detail := range details {
+		if s, ok := detail.(*structpb.Struct); ok && isTypeHintsStruct(s) {
+			return s.GetFields()
+		}
+	}
+	return nil
+}
+
+// isTypeHintsStruct reports whether the struct holds type hints.
+func isTypeHintsStruct(s *structpb.Struct) bool {
+	_, ok := s.GetFields()[typeHintsMarker]
+	return ok
+}
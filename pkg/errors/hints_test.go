@@ -0,0 +1,79 @@
+package errors
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestTypeHintsRoundTrip(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value any
+	}{
+		{name: "int", value: 42},
+		{name: "negative int", value: -42},
+		{name: "int8", value: int8(-8)},
+		{name: "int16", value: int16(16)},
+		{name: "int32", value: int32(32)},
+		{name: "int64", value: int64(1 << 40)},
+		{name: "uint", value: uint(7)},
+		{name: "uint8", value: uint8(8)},
+		{name: "uint16", value: uint16(16)},
+		{name: "uint32", value: uint32(32)},
+		{name: "uint64", value: uint64(1 << 50)},
+		{name: "float32", value: float32(0.5)},
+		{name: "float64", value: 1.5},
+		{name: "bool", value: true},
+		{name: "string", value: "value"},
+		{name: "bytes", value: []byte{0, 1, 2, 255}},
+		{name: "time", value: time.Date(2024, 2, 29, 12, 30, 15, 123456789, time.UTC)},
+		{name: "time with zone", value: time.Date(2024, 2, 29, 12, 30, 15, 0, time.FixedZone("", 2*60*60))},
+		{name: "duration", value: 1500 * time.Millisecond},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			received := roundTrip(t, WithMetadata(status.Error(codes.Internal, "boom"), "key", tc.value))
+			metadata := GetMetadata(received)
+			require.Len(t, metadata, 2)
+			if expected, ok := tc.value.(time.Time); ok {
+				require.True(t, expected.Equal(metadata[1].(time.Time)))
+				return
+			}
+			require.Equal(t, tc.value, metadata[1])
+		})
+	}
+}
+
+func TestTypeHintsDetail(t *testing.T) {
+	err := WithMetadata(WithMetadata(errors.New("foo"), "count", 1), "count", 2, "name", "value")
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+
+	// The hints are sent in their own detail, only for the values that need one.
+	details := st.Details()
+	require.Len(t, details, 2)
+	hints, ok := details[1].(*structpb.Struct)
+	require.True(t, ok)
+	require.Equal(t, map[string]any{typeHintsMarker: true, "count": "int"}, hints.AsMap())
+
+	// Wrapping a received error replaces both details, rather than duplicating them.
+	st, ok = status.FromError(WithMetadata(st.Err(), "other", 3))
+	require.True(t, ok)
+	require.Len(t, st.Details(), 2)
+	require.Equal(t, map[string]any{"count": 2, "name": "value", "other": 3}, GetMetadataMap(st.Err()))
+}
+
+func TestTypeHintsMismatch(t *testing.T) {
+	// A hint that doesn't match the value leaves the value as received.
+	require.InDelta(t, 1.5, fromHintedWireValue(1.5, "int"), 0)
+	require.InDelta(t, -1.0, fromHintedWireValue(-1.0, "uint"), 0)
+	require.InDelta(t, 300.0, fromHintedWireValue(300.0, "int8"), 0)
+	require.Equal(t, "not a time", fromHintedWireValue("not a time", "time.Time"))
+	require.Equal(t, "value", fromHintedWireValue("value", "unknown"))
+}
@@ -0,0 +1,102 @@
+package httpmw
+
+import (
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	errhelper "github.com/qdrant/go-commons/pkg/errors"
+)
+
+// grpcCodeStatuses maps gRPC codes to HTTP status codes, following the mapping of grpc-gateway.
+var grpcCodeStatuses = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           499, // Client Closed Request
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+}
+
+// HTTPStatusCode returns the HTTP status code matching the gRPC code,
+// e.g. codes.NotFound becomes 404 and codes.Unavailable 503.
+// Unknown codes become 500.
+func HTTPStatusCode(code codes.Code) int {
+	if statusCode, ok := grpcCodeStatuses[code]; ok {
+		return statusCode
+	}
+	return http.StatusInternalServerError
+}
+
+// ErrorResponse is the standard JSON body of REST error responses.
+type ErrorResponse struct {
+	// Error is the message safe to show to users.
+	Error string `json:"error"`
+	// Code is the name of the gRPC code of the error, e.g. "NotFound".
+	Code string `json:"code"`
+	// Hint is the actionable hint attached to the error with errhelper.WithHint, if any.
+	Hint string `json:"hint,omitempty"`
+	// Context is the metadata of the error, only included for internal endpoints.
+	Context map[string]any `json:"context,omitempty"`
+}
+
+// ResponseOption configures HTTPResponse.
+type ResponseOption func(*responseOptions)
+
+type responseOptions struct {
+	includeContext bool
+}
+
+// IncludeContext includes the metadata of the error in the Context field of the response.
+// It's meant for internal endpoints, as metadata may carry sensitive information.
+func IncludeContext() ResponseOption {
+	return func(o *responseOptions) {
+		o.includeContext = true
+	}
+}
+
+// HTTPResponse returns the HTTP status code and the JSON body of the response for the error,
+// so REST handlers can write it with:
+//
+//	statusCode, resp := httpmw.HTTPResponse(err)
+//	w.Header().Set("Content-Type", "application/json")
+//	w.WriteHeader(statusCode)
+//	_ = json.NewEncoder(w).Encode(resp)
+//
+// The status code is derived from the gRPC code of the error (see HTTPStatusCode), and the message
+// is the public message of the error, or a default text for the code, as returned by errhelper.Sanitize.
+// The hint of the error (see errhelper.WithHint) is included in the Hint field, while the metadata
+// of the error is only included with the IncludeContext option.
+// For a nil error, it returns 200 with the OK code, while a non-nil error with codes.OK is reported
+// as Unknown, with 500, like errhelper.Sanitize does, as a failed request must not look successful.
+func HTTPResponse(err error, opts ...ResponseOption) (int, ErrorResponse) {
+	o := responseOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	code := errhelper.CodeOf(err)
+	if err != nil && code == codes.OK {
+		code = codes.Unknown
+	}
+	resp := ErrorResponse{
+		Error: status.Convert(errhelper.Sanitize(err)).Message(),
+		Code:  code.String(),
+	}
+	resp.Hint, _ = errhelper.HintOf(err)
+	if o.includeContext && err != nil {
+		resp.Context = errhelper.GetMetadataMap(err)
+	}
+	return HTTPStatusCode(code), resp
+}
@@ -0,0 +1,76 @@
+package httpmw
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	errhelper "github.com/qdrant/go-commons/pkg/errors"
+)
+
+func TestHTTPStatusCode(t *testing.T) {
+	testCases := []struct {
+		code               codes.Code
+		expectedStatusCode int
+	}{
+		{code: codes.OK, expectedStatusCode: http.StatusOK},
+		{code: codes.Canceled, expectedStatusCode: 499},
+		{code: codes.InvalidArgument, expectedStatusCode: http.StatusBadRequest},
+		{code: codes.NotFound, expectedStatusCode: http.StatusNotFound},
+		{code: codes.AlreadyExists, expectedStatusCode: http.StatusConflict},
+		{code: codes.PermissionDenied, expectedStatusCode: http.StatusForbidden},
+		{code: codes.Unauthenticated, expectedStatusCode: http.StatusUnauthorized},
+		{code: codes.ResourceExhausted, expectedStatusCode: http.StatusTooManyRequests},
+		{code: codes.Unimplemented, expectedStatusCode: http.StatusNotImplemented},
+		{code: codes.Unavailable, expectedStatusCode: http.StatusServiceUnavailable},
+		{code: codes.DeadlineExceeded, expectedStatusCode: http.StatusGatewayTimeout},
+		{code: codes.Internal, expectedStatusCode: http.StatusInternalServerError},
+		{code: codes.Code(100), expectedStatusCode: http.StatusInternalServerError},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.code.String(), func(t *testing.T) {
+			require.Equal(t, tc.expectedStatusCode, HTTPStatusCode(tc.code))
+		})
+	}
+}
+
+func TestHTTPResponse(t *testing.T) {
+	err := errhelper.WithMetadata(status.Error(codes.NotFound, "collection test not found in shard 3"), "collection", "test")
+
+	statusCode, resp := HTTPResponse(err)
+	require.Equal(t, http.StatusNotFound, statusCode)
+	require.Equal(t, ErrorResponse{Error: "the requested resource was not found", Code: "NotFound"}, resp)
+	body, jsonErr := json.Marshal(resp)
+	require.NoError(t, jsonErr)
+	require.JSONEq(t, `{"error":"the requested resource was not found","code":"NotFound"}`, string(body))
+
+	statusCode, resp = HTTPResponse(errhelper.WithPublicMessage(err, "collection not found"), IncludeContext())
+	require.Equal(t, http.StatusNotFound, statusCode)
+	require.Equal(t, "collection not found", resp.Error)
+	require.Equal(t, "NotFound", resp.Code)
+	require.Equal(t, map[string]any{"collection": "test", "public_message": "collection not found"}, resp.Context)
+
+	statusCode, resp = HTTPResponse(errors.New("boom"))
+	require.Equal(t, http.StatusInternalServerError, statusCode)
+	require.Equal(t, ErrorResponse{Error: "an unknown error occurred", Code: "Unknown"}, resp)
+
+	_, resp = HTTPResponse(errhelper.WithHint(err, "check that the collection exists"))
+	require.Equal(t, "check that the collection exists", resp.Hint)
+	body, jsonErr = json.Marshal(resp)
+	require.NoError(t, jsonErr)
+	require.JSONEq(t, `{"error":"the requested resource was not found","code":"NotFound","hint":"check that the collection exists"}`, string(body))
+
+	// An error with codes.OK is not reported as a success.
+	statusCode, resp = HTTPResponse(errhelper.WithMetadata(errhelper.WithCode(errors.New("boom"), codes.OK), "key", "value"), IncludeContext())
+	require.Equal(t, http.StatusInternalServerError, statusCode)
+	require.Equal(t, ErrorResponse{Error: "an unknown error occurred", Code: "Unknown", Context: map[string]any{"key": "value"}}, resp)
+
+	statusCode, resp = HTTPResponse(nil, IncludeContext())
+	require.Equal(t, http.StatusOK, statusCode)
+	require.Equal(t, ErrorResponse{Code: "OK"}, resp)
+}
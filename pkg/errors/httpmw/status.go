@@ -0,0 +1,85 @@
+// Package httpmw provides helpers to translate between HTTP and the errors of the errors package.
+package httpmw
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/grpc/codes"
+
+	errhelper "github.com/qdrant/go-commons/pkg/errors"
+)
+
+const (
+	// httpStatusKey is the metadata key holding the HTTP status code of the response.
+	httpStatusKey = "http.status"
+	// maxMessageLength limits the length of the response body used as error message.
+	maxMessageLength = 512
+)
+
+// httpStatusCodes maps HTTP status codes to gRPC codes.
+var httpStatusCodes = map[int]codes.Code{
+	http.StatusBadRequest:            codes.InvalidArgument,
+	http.StatusUnauthorized:          codes.Unauthenticated,
+	http.StatusForbidden:             codes.PermissionDenied,
+	http.StatusNotFound:              codes.NotFound,
+	http.StatusMethodNotAllowed:      codes.Unimplemented,
+	http.StatusRequestTimeout:        codes.DeadlineExceeded,
+	http.StatusConflict:              codes.Aborted,
+	http.StatusPreconditionFailed:    codes.FailedPrecondition,
+	http.StatusRequestEntityTooLarge: codes.InvalidArgument,
+	http.StatusTooManyRequests:       codes.ResourceExhausted,
+	499:                              codes.Canceled, // Client Closed Request
+	http.StatusNotImplemented:        codes.Unimplemented,
+	http.StatusBadGateway:            codes.Unavailable,
+	http.StatusServiceUnavailable:    codes.Unavailable,
+	http.StatusGatewayTimeout:        codes.DeadlineExceeded,
+}
+
+// FromHTTPStatus returns an error for a non-2xx HTTP response, carrying a gRPC code derived from the HTTP status,
+// e.g. 404 becomes codes.NotFound, 429 codes.ResourceExhausted and 503 codes.Unavailable.
+// Unknown statuses become codes.Internal for 5xx and codes.Unknown otherwise.
+// The message is the response body, truncated to a reasonable length, or the status text if the body is empty.
+// The provided metadata is attached to the error, along with the HTTP status under the "http.status" key.
+// It returns nil for a 2xx status.
+func FromHTTPStatus(statusCode int, body string, keyValues ...any) error {
+	if statusCode >= 200 && statusCode < 300 {
+		return nil
+	}
+	msg := truncate(strings.TrimSpace(body), maxMessageLength)
+	if msg == "" {
+		msg = http.StatusText(statusCode)
+	}
+	if msg == "" {
+		msg = fmt.Sprintf("HTTP status %d", statusCode)
+	}
+	err := errhelper.WithCode(errors.New(msg), codeFromHTTPStatus(statusCode))
+	return errhelper.WithMetadata(err, append([]any{httpStatusKey, statusCode}, keyValues...)...)
+}
+
+// codeFromHTTPStatus returns the gRPC code matching the HTTP status code.
+func codeFromHTTPStatus(statusCode int) codes.Code {
+	if code, ok := httpStatusCodes[statusCode]; ok {
+		return code
+	}
+	if statusCode >= 500 && statusCode < 600 {
+		return codes.Internal
+	}
+	return codes.Unknown
+}
+
+// truncate shortens s to at most maxLength bytes, without splitting a multi-byte character.
+func truncate(s string, maxLength int) string {
+	const ellipsis = "..."
+	if len(s) <= maxLength {
+		return s
+	}
+	end := maxLength - len(ellipsis)
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end] + ellipsis
+}
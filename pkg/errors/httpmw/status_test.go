@@ -0,0 +1,86 @@
+package httpmw
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	errhelper "github.com/qdrant/go-commons/pkg/errors"
+)
+
+func TestFromHTTPStatus(t *testing.T) {
+	testCases := []struct {
+		name            string
+		statusCode      int
+		body            string
+		expectedCode    codes.Code
+		expectedMessage string
+	}{
+		{
+			name:            "not found",
+			statusCode:      http.StatusNotFound,
+			body:            "collection not found\n",
+			expectedCode:    codes.NotFound,
+			expectedMessage: "collection not found",
+		},
+		{
+			name:            "too many requests",
+			statusCode:      http.StatusTooManyRequests,
+			body:            "slow down",
+			expectedCode:    codes.ResourceExhausted,
+			expectedMessage: "slow down",
+		},
+		{
+			name:            "service unavailable with empty body",
+			statusCode:      http.StatusServiceUnavailable,
+			expectedCode:    codes.Unavailable,
+			expectedMessage: "Service Unavailable",
+		},
+		{
+			name:            "unknown 5xx status",
+			statusCode:      http.StatusHTTPVersionNotSupported,
+			body:            "oops",
+			expectedCode:    codes.Internal,
+			expectedMessage: "oops",
+		},
+		{
+			name:            "unknown 4xx status",
+			statusCode:      http.StatusTeapot,
+			body:            "short and stout",
+			expectedCode:    codes.Unknown,
+			expectedMessage: "short and stout",
+		},
+		{
+			name:            "non-standard status with empty body",
+			statusCode:      599,
+			expectedCode:    codes.Internal,
+			expectedMessage: "HTTP status 599",
+		},
+		{
+			name:            "long body is truncated",
+			statusCode:      http.StatusBadRequest,
+			body:            strings.Repeat("é", 300),
+			expectedCode:    codes.InvalidArgument,
+			expectedMessage: strings.Repeat("é", 254) + "...",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := FromHTTPStatus(tc.statusCode, tc.body, "backend", "search")
+			require.Error(t, err)
+			require.Equal(t, tc.expectedMessage, err.Error())
+			require.Equal(t, tc.expectedCode, errhelper.CodeOf(err))
+			require.Equal(t, tc.expectedCode, status.Code(err))
+			require.Equal(t, map[string]any{"http.status": tc.statusCode, "backend": "search"}, errhelper.GetMetadataMap(err))
+		})
+	}
+}
+
+func TestFromHTTPStatusSuccess(t *testing.T) {
+	require.NoError(t, FromHTTPStatus(http.StatusOK, "ok"))
+	require.NoError(t, FromHTTPStatus(http.StatusNoContent, ""))
+}
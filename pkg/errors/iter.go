@@ -0,0 +1,87 @@
+package errors
+
+import (
+	"iter"
+	"slices"
+)
+
+// All returns an iterator over the metadata of the error chain.
+// Each key is yielded once, with the value that wins in GetMetadataMap (the outermost one),
+// or the value combined by the function set with the MergeFunc option for the key, if any.
+// Keys are yielded from the outermost error to the innermost one, and pairs are
+// produced lazily, without materializing the metadata slice of GetMetadata, except for the keys
+// with a merge function, whose values are combined upfront. Keys that are not strings are skipped,
+// and, unlike GetMetadataMap, the keys are not transformed by the function set with SetKeyTransformer.
+func All(err error) iter.Seq2[string, any] {
+	return func(yield func(string, any) bool) {
+		if err == nil {
+			return
+		}
+		var buf [8][]any
+		var visited visitedErrors
+		layers := appendLayers(buf[:0], err, &visited)
+		globals := getGlobalMetadata()
+		merged := mergeLayers(globals, layers)
+		seen := make(map[string]struct{})
+		for i := len(layers) - 1; i >= 0; i-- {
+			if !yieldLayer(layers[i], seen, merged, yield) {
+				return
+			}
+		}
+		// Global metadata has the lowest precedence.
+		yieldLayer(globals, seen, merged, yield)
+	}
+}
+
+// mergeLayers returns the values of the keys with a function set with the MergeFunc option,
+// combined from the global metadata and the innermost layer to the outermost one, like dedupMetadata does.
+// It returns nil if no merge function is configured.
+func mergeLayers(globals []any, layers [][]any) map[string]any {
+	mergeFuncs := getOptions().mergeFuncs
+	if len(mergeFuncs) == 0 {
+		return nil
+	}
+	merged := make(map[string]any)
+	for _, metadata := range slices.Concat([][]any{globals}, layers) {
+		for i := 0; i+1 < len(metadata); i += 2 {
+			key, ok := metadata[i].(string)
+			if !ok {
+				continue
+			}
+			merge, ok := mergeFuncs[key]
+			if !ok {
+				continue
+			}
+			if old, exists := merged[key]; exists {
+				merged[key] = merge(old, metadata[i+1])
+				continue
+			}
+			merged[key] = metadata[i+1]
+		}
+	}
+	return merged
+}
+
+// yieldLayer yields the pairs of a level whose keys were not seen yet, with their merged value, if any.
+// It returns false if the iteration was stopped.
+func yieldLayer(metadata []any, seen map[string]struct{}, merged map[string]any, yield func(string, any) bool) bool {
+	// Walk the level backwards, as later pairs take precedence over earlier ones.
+	for i := len(metadata) - 2; i >= 0; i -= 2 {
+		key, ok := metadata[i].(string)
+		if !ok {
+			continue
+		}
+		if _, exists := seen[key]; exists {
+			continue
+		}
+		seen[key] = struct{}{}
+		value, ok := merged[key]
+		if !ok {
+			value = metadata[i+1]
+		}
+		if !yield(key, value) {
+			return false
+		}
+	}
+	return true
+}
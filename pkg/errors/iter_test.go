@@ -0,0 +1,98 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestAll(t *testing.T) {
+	rootError := errors.New("this is root error")
+
+	st := status.New(codes.Internal, "internal error")
+	metadataStruct, err := structpb.NewStruct(map[string]any{
+		"grpc_key":           "grpc_value",
+		"shared_key":         "grpc_shared_value",
+		qdrantMetadataMarker: true,
+	})
+	require.NoError(t, err)
+	stWithDetails, err := st.WithDetails(metadataStruct)
+	require.NoError(t, err)
+	grpcErrorWithDetails := stWithDetails.Err()
+
+	testCases := []struct {
+		name string
+		err  error
+	}{
+		{
+			name: "nil error",
+			err:  nil,
+		},
+		{
+			name: "error without metadata",
+			err:  rootError,
+		},
+		{
+			name: "error wrapped in multiple levels with metadata and custom message",
+			err:  WithMetadata(fmt.Errorf("foo: %w", WithMetadata(rootError, "k1", "v1")), "k2", "v2"),
+		},
+		{
+			name: "error wrapped with reused key in metadata",
+			err:  WithMetadata(WithMetadata(rootError, "reused_key", "inner_value"), "reused_key", "outer_value", "reused_key", "last_value"),
+		},
+		{
+			name: "chained error with local and gRPC metadata with overlapping keys",
+			err:  WithMetadata(grpcErrorWithDetails, "local_key", "local_value", "shared_key", "local_shared_value"),
+		},
+		{
+			name: "joined errors with overlapping keys",
+			err: errors.Join(
+				WithMetadata(rootError, "k1", "left", "k2", "left"),
+				WithMetadata(rootError, "k1", "right"),
+			),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, GetMetadataMap(tc.err), maps.Collect(All(tc.err)))
+		})
+	}
+}
+
+func TestAllOrderAndBreak(t *testing.T) {
+	err := WithMetadata(WithMetadata(errors.New("foo"), "k1", "v1", "k2", "v2"), "k3", "v3", "k1", "outer")
+
+	var keys []string
+	for key := range All(err) {
+		keys = append(keys, key)
+	}
+	require.Equal(t, []string{"k1", "k3", "k2"}, keys)
+
+	var first []string
+	for key := range All(err) {
+		first = append(first, key)
+		break
+	}
+	require.Equal(t, []string{"k1"}, first)
+}
+
+func TestAllMergeFunc(t *testing.T) {
+	Configure(MergeFunc("warnings", func(old, new any) any {
+		return fmt.Sprint(old, ",", new)
+	}))
+	t.Cleanup(func() { Configure() })
+	SetGlobalMetadata("warnings", "global")
+	t.Cleanup(func() { SetGlobalMetadata() })
+
+	err := WithMetadata(WithMetadata(errors.New("foo"), "warnings", "inner", "key", "inner"), "warnings", "outer", "key", "outer")
+	err = errors.Join(err, WithMetadata(errors.New("bar"), "warnings", "joined"))
+	expected := map[string]any{"warnings": "global,inner,outer,joined", "key": "outer"}
+	require.Equal(t, expected, GetMetadataMap(err))
+	require.Equal(t, expected, maps.Collect(All(err)))
+}
@@ -0,0 +1,63 @@
+package errors
+
+import "sync/atomic"
+
+// wrapSequence is the last sequence number given to a layer, see SequenceLayers.
+var wrapSequence atomic.Uint64
+
+// nextSequence returns the sequence number of a new layer, or 0 if the SequenceLayers option is not set.
+func nextSequence() uint64 {
+	if !getOptions().sequenceLayers {
+		return 0
+	}
+	return wrapSequence.Add(1)
+}
+
+// newErrWithMetadata returns a new layer, stamped with its sequence number.
+// All the layers must be created with it, so the SequenceLayers option orders them all.
+func newErrWithMetadata(w errWithMetadata) *errWithMetadata {
+	w.sequence = nextSequence()
+	return &w
+}
+
+// Layer describes a layer of metadata of an error tree, as added by WithMetadata, WithCode
+// and the helpers built on them.
+type Layer struct {
+	// Message is the message of the layer, i.e. of the error it wraps.
+	Message string
+	// Metadata holds a copy of the key-value pairs attached to the layer itself.
+	Metadata []any
+	// Sequence is the creation order of the layer, greater for layers created later,
+	// or 0 if the SequenceLayers option was not set when it was created.
+	Sequence uint64
+}
+
+// Layers returns the layers of the error tree, in the order of GetMetadata: from the innermost to the outermost,
+// the branches of multi-errors being traversed in order, an error shared by several branches being reported once.
+// Layers received over gRPC are merged into their status and are not reported. It returns nil for a nil error.
+func Layers(err error) []Layer {
+	var visited visitedErrors
+	return appendLayerInfos(nil, err, &visited)
+}
+
+func appendLayerInfos(layers []Layer, err error, visited *visitedErrors) []Layer {
+	if err == nil || !visited.visit(err) {
+		return layers
+	}
+	switch x := err.(type) { // nolint: errorlint // errors.As should not be used here
+	case interface{ Unwrap() []error }:
+		if *visited == nil {
+			*visited = make(visitedErrors)
+			visited.visit(err)
+		}
+		for _, branch := range x.Unwrap() {
+			layers = appendLayerInfos(layers, branch, visited)
+		}
+	case interface{ Unwrap() error }:
+		layers = appendLayerInfos(layers, x.Unwrap(), visited)
+	}
+	if e, ok := err.(*errWithMetadata); ok { // nolint: errorlint // errors.As should not be used here
+		layers = append(layers, Layer{Message: e.Error(), Metadata: e.Metadata(), Sequence: e.sequence})
+	}
+	return layers
+}
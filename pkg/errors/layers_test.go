@@ -0,0 +1,67 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLayers(t *testing.T) {
+	require.Nil(t, Layers(nil))
+	require.Nil(t, Layers(errors.New("foo")))
+
+	// Without the option, the layers are not stamped.
+	err := WithMetadata(WithCode(errors.New("foo"), codes.NotFound), "key", "value")
+	require.Equal(t, []Layer{
+		{Message: "foo"},
+		{Message: "foo", Metadata: []any{"key", "value"}},
+	}, Layers(err))
+
+	Configure(SequenceLayers())
+	t.Cleanup(func() { Configure() })
+	inner := WithMetadata(errors.New("foo"), "key", "value")
+	err = errors.Join(
+		fmt.Errorf("bar: %w", WithGRPCMessage(inner, "short")),
+		WithCode(inner, codes.Internal),
+	)
+	err = WithMetadata(err, "key", "value")
+	layers := Layers(err)
+	require.Len(t, layers, 4)
+	require.Equal(t, []any{"key", "value"}, layers[0].Metadata)
+	require.Empty(t, layers[1].Metadata)
+	require.Empty(t, layers[2].Metadata)
+	require.Equal(t, []any{"key", "value"}, layers[3].Metadata)
+	// The layers are stamped in creation order, even with the same message and metadata.
+	require.Equal(t, "foo", layers[0].Message)
+	require.Equal(t, "bar: foo\nfoo", layers[3].Message)
+	for i := 1; i < len(layers); i++ {
+		require.Greater(t, layers[i].Sequence, layers[i-1].Sequence)
+	}
+
+	// All the constructors stamp their layer.
+	data, err := MarshalBinary(WithMetadata(errors.New("foo"), "key", "value"))
+	require.NoError(t, err)
+	unmarshaled, err := UnmarshalBinary(data)
+	require.NoError(t, err)
+	for _, err := range []error{
+		Aggregate(2, []error{errors.New("foo")}),
+		FromStatus(status.New(codes.NotFound, "foo")),
+		unmarshaled,
+	} {
+		layers := Layers(err)
+		require.NotEmpty(t, layers)
+		require.NotZero(t, layers[len(layers)-1].Sequence)
+	}
+
+	// The sequence is rendered in the tree.
+	data, err = ToTreeJSON(WithMetadata(errors.New("foo"), "key", "value"))
+	require.NoError(t, err)
+	var node map[string]any
+	require.NoError(t, json.Unmarshal(data, &node))
+	require.Greater(t, node["sequence"], float64(layers[3].Sequence))
+}
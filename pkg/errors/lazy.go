@@ -0,0 +1,31 @@
+package errors
+
+// lazyValue is a metadata value computed when the metadata is read, e.g. when the error is logged,
+// rather than when it's attached to the error. Every read calls the function again.
+type lazyValue func() any
+
+// resolveValue returns the current value of a lazy value, or the value itself.
+func resolveValue(value any) any {
+	if lazy, ok := value.(lazyValue); ok {
+		return lazy()
+	}
+	return value
+}
+
+// resolveLazyValues returns the key value pairs with lazy values replaced by their current value.
+// The provided slice is returned as is when it holds no lazy value, so it must not be modified.
+func resolveLazyValues(metadata []any) []any {
+	for i := 1; i < len(metadata); i += 2 {
+		if _, ok := metadata[i].(lazyValue); ok {
+			resolved := make([]any, len(metadata))
+			for j, v := range metadata {
+				if j%2 == 1 {
+					v = resolveValue(v)
+				}
+				resolved[j] = v
+			}
+			return resolved
+		}
+	}
+	return metadata
+}
@@ -0,0 +1,91 @@
+package errors
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// LogMetadata returns the metadata of the error as the log renderings of this package and the log adapters
+// log it: the metadata visible to internal sinks (see GetMetadataScoped), so the ScopeExternal metadata
+// stays out of the logs, deduplicated like GetMetadataMap, error values being replaced with their message,
+// followed by their own metadata under keys prefixed by the key of the error value and a dot, and redacted
+// by the functions registered with RegisterValueRedactor. It returns nil for a nil error.
+func LogMetadata(err error) map[string]any {
+	if err == nil {
+		return nil
+	}
+	metadata := toMetadataMap(expandErrorValues(GetMetadataScoped(err, ScopeInternal)))
+	redactMap(metadata)
+	return metadata
+}
+
+// Logfmt renders the error as a single logfmt line, for environments without a structured logger, e.g.:
+//
+//	msg="item not found" code=NotFound key1=value1 key2="value with spaces"
+//
+// Metadata is read with LogMetadata, so the ScopeExternal metadata is left out, and rendered sorted by key.
+// Error values are rendered using their message, followed by their own metadata
+// with keys prefixed by the key of the error value and a dot.
+// Values are redacted by the functions registered with RegisterValueRedactor.
+// Values containing spaces, equal signs, quotes or non-printable characters are quoted and escaped,
+// and invalid characters in keys are replaced with underscores. Keys rendered as a field already written,
+// such as "msg" and "code", are prefixed with underscores until they are unique, e.g. "_code",
+// so crafted metadata can't inject additional fields or lines, nor spoof the fields of a last-wins parser.
+// It returns an empty string for a nil error.
+func Logfmt(err error) string {
+	if err == nil {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("msg=")
+	b.WriteString(strconv.Quote(err.Error()))
+	b.WriteString(" code=")
+	b.WriteString(CodeName(err))
+	metadata := LogMetadata(err)
+	written := map[string]struct{}{"msg": {}, "code": {}}
+	for _, key := range slices.Sorted(maps.Keys(metadata)) {
+		name := logfmtKey(key)
+		for {
+			if _, ok := written[name]; !ok {
+				break
+			}
+			name = "_" + name
+		}
+		written[name] = struct{}{}
+		b.WriteByte(' ')
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(logfmtValue(metadata[key]))
+	}
+	return b.String()
+}
+
+// logfmtKey replaces the characters that are not allowed in a logfmt key with underscores.
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if needsQuoting(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// logfmtValue renders the value, quoting it if needed.
+func logfmtValue(value any) string {
+	s := fmt.Sprint(value)
+	if s == "" || strings.IndexFunc(s, needsQuoting) >= 0 {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func needsQuoting(r rune) bool {
+	return r <= ' ' || r == '=' || r == '"' || r == '\\' || !unicode.IsPrint(r)
+}
@@ -0,0 +1,92 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLogfmt(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "nil error",
+			err:      nil,
+			expected: "",
+		},
+		{
+			name:     "plain error",
+			err:      errors.New("foo"),
+			expected: `msg="foo" code=Unknown`,
+		},
+		{
+			name:     "gRPC status error wrapped with metadata",
+			err:      WithMetadata(status.Error(codes.NotFound, "item not found"), "key2", "value with spaces", "key1", "value1"),
+			expected: `msg="rpc error: code = NotFound desc = item not found" code=NotFound key1=value1 key2="value with spaces"`,
+		},
+		{
+			name:     "reused key is deduplicated",
+			err:      WithMetadata(WithMetadata(errors.New("foo"), "key", "inner"), "key", "outer"),
+			expected: `msg="foo" code=Unknown key=outer`,
+		},
+		{
+			name:     "non-string values",
+			err:      WithCode(WithMetadata(errors.New("foo"), "int", 42, "bool", true, "err", errors.New("bar baz")), codes.Internal),
+			expected: `msg="foo" code=Internal bool=true err="bar baz" int=42`,
+		},
+		{
+			name:     "empty value",
+			err:      WithMetadata(errors.New("foo"), "key", ""),
+			expected: `msg="foo" code=Unknown key=""`,
+		},
+		{
+			name:     "values with special characters are escaped",
+			err:      WithMetadata(errors.New("multi\nline"), "eq", "a=b", "quote", `say "hi"`, "injection", "x\nmsg=\"fake\""),
+			expected: `msg="multi\nline" code=Unknown eq="a=b" injection="x\nmsg=\"fake\"" quote="say \"hi\""`,
+		},
+		{
+			name:     "keys with special characters are sanitized",
+			err:      WithMetadata(errors.New("foo"), "bad key=", "value", "", "empty"),
+			expected: `msg="foo" code=Unknown _=empty bad_key_=value`,
+		},
+		{
+			name:     "reserved and colliding keys are renamed",
+			err:      WithCode(WithMetadata(errors.New("boom"), "code", "OK", "msg", "all good", "_code", "x", "a b", 1, "a_b", 2), codes.PermissionDenied),
+			expected: `msg="boom" code=PermissionDenied _code=x a_b=1 _a_b=2 __code=OK _msg="all good"`,
+		},
+		{
+			name:     "message wrapped with custom message",
+			err:      fmt.Errorf("bar: %w", WithMetadata(errors.New("foo"), "key", "value")),
+			expected: `msg="bar: foo" code=Unknown key=value`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, Logfmt(tc.err))
+		})
+	}
+}
+
+func TestLogMetadata(t *testing.T) {
+	require.Nil(t, LogMetadata(nil))
+
+	RegisterValueRedactor(func(key string, value any) (any, bool) {
+		return "[REDACTED]", key == "cause.token"
+	})
+	t.Cleanup(ClearValueRedactors)
+	cause := WithMetadata(errors.New("bar"), "inner", 1, "token", "secret")
+	err := WithMetadata(WithMetadata(errors.New("foo"), "key", "inner"), "key", "outer", "cause", cause)
+	require.Equal(t, map[string]any{
+		"key":         "outer",
+		"cause":       "bar",
+		"cause.inner": 1,
+		"cause.token": "[REDACTED]",
+	}, LogMetadata(err))
+}
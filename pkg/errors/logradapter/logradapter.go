@@ -0,0 +1,43 @@
+// Package logradapter provides helpers to log the errors of the errors package with logr.
+package logradapter
+
+import (
+	"maps"
+	"slices"
+
+	errhelper "github.com/qdrant/go-commons/pkg/errors"
+)
+
+// codeKey is the key holding the name of the gRPC code of the error.
+const codeKey = "code"
+
+// Values returns the metadata of the error as logr key value pairs, as read by errhelper.LogMetadata, and sorted
+// by key, the priority keys first in the given order, followed by the name of its gRPC code under the "code" key,
+// ready to be passed to a logr logger. Error values are expanded under prefixed keys and the values are redacted,
+// like errhelper.Logfmt does. Metadata keys colliding with the "code" key, or with a renamed key, are prefixed
+// with underscores until they are unique, e.g. "_code", like errhelper.Logfmt does, so no metadata is lost:
+//
+//	logger.Error(err, "request failed", logradapter.Values(err)...)
+//
+// It returns nil for a nil error.
+func Values(err error, priority ...string) []any {
+	if err == nil {
+		return nil
+	}
+	metadata := errhelper.LogMetadata(err)
+	values := make([]any, 0, len(metadata)*2+2)
+	written := map[string]struct{}{codeKey: {}}
+	for _, key := range slices.Sorted(maps.Keys(metadata)) {
+		name := key
+		for {
+			if _, ok := written[name]; !ok {
+				break
+			}
+			name = "_" + name
+		}
+		written[name] = struct{}{}
+		values = append(values, name, metadata[key])
+	}
+	values = errhelper.SortMetadata(values, priority...)
+	return append(values, codeKey, errhelper.CodeName(err))
+}
@@ -0,0 +1,68 @@
+package logradapter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	errhelper "github.com/qdrant/go-commons/pkg/errors"
+)
+
+func TestValues(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		priority []string
+		expected []any
+	}{
+		{
+			name: "nil error",
+			err:  nil,
+		},
+		{
+			name:     "plain error",
+			err:      errors.New("foo"),
+			expected: []any{"code", "Unknown"},
+		},
+		{
+			name: "error with metadata",
+			err: errhelper.WithValues(
+				errhelper.WithValues(status.Error(codes.NotFound, "not found"), "b", 1, "a", "inner"),
+				"a", "outer",
+			),
+			expected: []any{"a", "outer", "b", 1, "code", "NotFound"},
+		},
+		{
+			name:     "error value",
+			err:      errhelper.WithValues(errors.New("foo"), "cause", errhelper.WithValues(errors.New("bar"), "inner", 1)),
+			expected: []any{"cause", "bar", "cause.inner", 1, "code", "Unknown"},
+		},
+		{
+			name: "scoped metadata",
+			err: errhelper.WithScopedMetadata(
+				errhelper.WithScopedMetadata(errors.New("foo"), errhelper.ScopeInternal, "query", "SELECT 1"),
+				errhelper.ScopeExternal, "hint", "check the name",
+			),
+			expected: []any{"query", "SELECT 1", "code", "Unknown"},
+		},
+		{
+			name:     "code key in metadata",
+			err:      errhelper.WithValues(errors.New("foo"), "code", 42, "_code", "x"),
+			expected: []any{"_code", "x", "__code", 42, "code", "Unknown"},
+		},
+		{
+			name:     "priority keys",
+			err:      errhelper.WithValues(errors.New("foo"), "a", 1, "tenant", "t1", "operation", "search"),
+			priority: []string{"tenant", "operation"},
+			expected: []any{"tenant", "t1", "operation", "search", "a", 1, "code", "Unknown"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, Values(tc.err, tc.priority...))
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package errors
+
+import "errors"
+
+// Messages returns the messages of the error chain, from the outermost error to the innermost one,
+// following Unwrap() error, e.g. ["search: lookup: not found", "lookup: not found", "not found"].
+// The layers added by WithMetadata and WithCode are skipped, as they don't change the message.
+// A multi-error, such as the one returned by errors.Join, ends the chain.
+// It returns nil for a nil error.
+func Messages(err error) []string {
+	var messages []string
+	for u := err; u != nil; u = errors.Unwrap(u) {
+		if _, isOurType := u.(*errWithMetadata); isOurType { // nolint: errorlint // errors.As should not be used here
+			continue
+		}
+		messages = append(messages, u.Error())
+	}
+	return messages
+}
+
+// WithChainMessages returns the provided error wrapped with the messages of its chain (see Messages),
+// stored as a []string under the provided key. It snapshots how the error propagated so far,
+// which is kept even if outer layers change the message later. The list is received as a []any
+// on the other side of a gRPC call. It returns nil for a nil error.
+func WithChainMessages(err error, key string) error {
+	if err == nil {
+		return nil
+	}
+	return WithMetadata(err, key, Messages(err))
+}
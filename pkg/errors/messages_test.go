@@ -0,0 +1,32 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestMessages(t *testing.T) {
+	require.Nil(t, Messages(nil))
+	require.Equal(t, []string{"foo"}, Messages(errors.New("foo")))
+
+	err := fmt.Errorf("search: %w", WithMetadata(fmt.Errorf("lookup: %w", WithCode(errors.New("not found"), codes.NotFound)), "key", "value"))
+	require.Equal(t, []string{"search: lookup: not found", "lookup: not found", "not found"}, Messages(err))
+
+	joined := fmt.Errorf("batch: %w", errors.Join(errors.New("foo"), errors.New("bar")))
+	require.Equal(t, []string{"batch: foo\nbar", "foo\nbar"}, Messages(joined))
+}
+
+func TestWithChainMessages(t *testing.T) {
+	require.NoError(t, WithChainMessages(nil, "chain"))
+
+	err := WithChainMessages(fmt.Errorf("lookup: %w", errors.New("not found")), "chain")
+	err = fmt.Errorf("search: %w", err)
+	require.Equal(t, []string{"lookup: not found", "not found"}, GetMetadataMap(err)["chain"])
+
+	// The messages are received as a list.
+	require.Equal(t, []any{"lookup: not found", "not found"}, GetMetadataMap(roundTrip(t, WithCode(err, codes.Internal)))["chain"])
+}
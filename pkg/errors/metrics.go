@@ -0,0 +1,148 @@
+package errors
+
+import (
+	"fmt"
+	"path"
+)
+
+// codeLabel is the label holding the name of the gRPC code of the error in MetricsLabels.
+const codeLabel = "code"
+
+// highCardinalityKeys holds the reserved keys whose values are unbounded, never returned by MetricsLabels.
+var highCardinalityKeys = map[string]struct{}{
+	operationMillisKey:  {},
+	retryAfterMillisKey: {},
+	bytesDoneKey:        {},
+	bytesTotalKey:       {},
+	logCountKey:         {},
+	ctxErrKey:           {},
+	publicMessageKey:    {},
+	correlationKey:      {},
+	panicStackKey:       {},
+	goroutineStartedKey: {},
+}
+
+// WithCardinalKey returns the provided error wrapped with the key and value, like WithMetadata,
+// flagging the key as high-cardinality, such as IDs or timestamps, so MetricsLabels never returns it.
+// The key is flagged for the whole chain, whatever the layer carrying its outermost value.
+// The metadata is otherwise read, logged and sent over gRPC as usual, and the flag is not sent.
+// It returns nil for a nil error.
+func WithCardinalKey(err error, key string, value any) error {
+	if err == nil {
+		return nil
+	}
+	wrapped := newErrWithMetadata(errWithMetadata{
+		err:             err,
+		metadata:        []any{key, value},
+		highCardinality: true,
+	})
+	notifyWrap(wrapped, wrapped.metadata)
+	return wrapped
+}
+
+// MetricsLabels returns labels suitable for metrics derived from the error, e.g. Prometheus counters:
+// the name of its gRPC code under "code" (see CodeName), the operation set with WithOperation, if any,
+// and the metadata keys allowed with the MetricsLabelKeys option, formatted with fmt.Sprint.
+// The metadata of the chain is read like GetMetadataMap, excluding the keys flagged with WithCardinalKey,
+// the keys matching the MetricsLabelDenylist option, the reserved keys holding unbounded values, such as
+// the elapsed time set by WithOperation, and the values that are not strings, booleans or integers,
+// as durations, timestamps and nested values hardly make labels.
+// As in GetMetadataMap, the keys are transformed by the function set with SetKeyTransformer, if any,
+// and the patterns of the options are matched against the transformed keys.
+// It returns nil for a nil error.
+func MetricsLabels(err error) map[string]string {
+	if err == nil {
+		return nil
+	}
+	transform := getKeyTransformer()
+	if transform == nil {
+		transform = func(key string) string { return key }
+	}
+	// The excluded keys are compared with the keys of GetMetadataMap, so they are transformed as well.
+	excluded := make(map[string]struct{}, len(highCardinalityKeys))
+	for key := range highCardinalityKeys {
+		excluded[transform(key)] = struct{}{}
+	}
+	flagged := map[string]struct{}{}
+	var visited visitedErrors
+	collectHighCardinalityKeys(err, "", &visited, flagged)
+	for key := range flagged {
+		excluded[transform(key)] = struct{}{}
+	}
+	operation := transform(operationKey)
+	o := getOptions()
+
+	labels := map[string]string{}
+	for key, value := range GetMetadataMap(err) {
+		if key != operation && !matchesAny(o.labelKeys, key) {
+			continue
+		}
+		if _, ok := excluded[key]; ok || matchesAny(o.labelDenylist, key) {
+			continue
+		}
+		if label, ok := labelValue(value); ok {
+			labels[key] = label
+		}
+	}
+	labels[codeLabel] = CodeName(err)
+	return labels
+}
+
+// collectHighCardinalityKeys adds the keys flagged with WithCardinalKey in the tree rooted at err to the keys,
+// as seen by GetMetadata, i.e. prefixed by the WithPrefixedMetadata layers wrapping them.
+func collectHighCardinalityKeys(err error, prefix string, visited *visitedErrors, keys map[string]struct{}) {
+	if err == nil || !visited.visit(err) {
+		return
+	}
+	if e, ok := err.(*errWithMetadata); ok { // nolint: errorlint // errors.As should not be used here
+		if e.highCardinality {
+			for i := 0; i+1 < len(e.metadata); i += 2 {
+				if key, ok := e.metadata[i].(string); ok {
+					keys[prefix+key] = struct{}{}
+				}
+			}
+		}
+		collectHighCardinalityKeys(e.err, prefix+e.keyPrefix, visited, keys)
+		return
+	}
+	switch x := err.(type) { // nolint: errorlint // errors.As should not be used here
+	case interface{ Unwrap() []error }:
+		if *visited == nil {
+			*visited = make(visitedErrors)
+			visited.visit(err)
+		}
+		for _, branch := range x.Unwrap() {
+			collectHighCardinalityKeys(branch, prefix, visited, keys)
+		}
+	case interface{ Unwrap() error }:
+		collectHighCardinalityKeys(x.Unwrap(), prefix, visited, keys)
+	}
+}
+
+// matchesAny reports whether the key matches one of the path.Match patterns.
+func matchesAny(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// labelValue formats the metadata value as a label value, if it's a string, a boolean or an integer.
+// Integral floats are accepted as well, as integers received over gRPC are decoded as float64.
+func labelValue(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case bool:
+		return fmt.Sprint(v), true
+	case float32, float64:
+		n, ok := toInt64(v)
+		return fmt.Sprint(n), ok
+	}
+	if n, ok := toInt64(value); ok {
+		return fmt.Sprint(n), true
+	}
+	return "", false
+}
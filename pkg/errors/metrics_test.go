@@ -0,0 +1,73 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMetricsLabels(t *testing.T) {
+	require.Nil(t, MetricsLabels(nil))
+	require.Equal(t, map[string]string{"code": "Unknown"}, MetricsLabels(errors.New("foo")))
+
+	err := WithCode(errors.New("foo"), codes.NotFound)
+	err = WithOperation(err, "search", 120*time.Millisecond)
+	err = WithCardinalKey(err, "point_id", 42)
+	err = WithMetadata(err, "tenant", "free", "cached", false, "shard", 3, "request_id", "r1",
+		"started", time.Unix(0, 0), "ids", []int{1, 2}, "ratio", 0.5, "code", "overridden")
+	err = WithCardinalKey(fmt.Errorf("bar: %w", err), "collection_id", "c1")
+	require.Equal(t, 42, GetMetadataMap(err)["point_id"])
+
+	// Only the code and the operation are returned by default.
+	require.Equal(t, map[string]string{"code": "NotFound", "operation": "search"}, MetricsLabels(err))
+
+	Configure(MetricsLabelKeys("*"))
+	t.Cleanup(func() { Configure() })
+	expected := map[string]string{
+		"code":       "NotFound",
+		"operation":  "search",
+		"tenant":     "free",
+		"cached":     "false",
+		"shard":      "3",
+		"request_id": "r1",
+	}
+	require.Equal(t, expected, MetricsLabels(err))
+
+	// The flags apply to the keys set again by other layers.
+	require.NotContains(t, MetricsLabels(WithMetadata(err, "point_id", 43)), "point_id")
+
+	// Flagged keys are prefixed like the metadata.
+	prefixed := WithPrefixedMetadata(err, "backend")
+	require.Contains(t, MetricsLabels(prefixed), "backend.shard")
+	require.NotContains(t, MetricsLabels(prefixed), "backend.point_id")
+
+	// Flags are local, while integers received over gRPC are decoded as float64.
+	received := status.FromProto(status.Convert(WithMetadata(status.Error(codes.Unavailable, "down"), "shard", 3)).Proto()).Err()
+	require.Equal(t, map[string]string{"code": "Unavailable", "shard": "3"}, MetricsLabels(received))
+
+	Configure(MetricsLabelKeys("*"), MetricsLabelDenylist("request_*", "[", "tenant"))
+	delete(expected, "request_id")
+	delete(expected, "tenant")
+	require.Equal(t, expected, MetricsLabels(err))
+
+	Configure(MetricsLabelKeys("shard", "cache*", "["))
+	require.Equal(t, map[string]string{"code": "NotFound", "operation": "search", "shard": "3", "cached": "false"}, MetricsLabels(err))
+}
+
+func TestMetricsLabelsKeyTransformer(t *testing.T) {
+	SetKeyTransformer(strings.ToUpper)
+	t.Cleanup(func() { SetKeyTransformer(nil) })
+	Configure(MetricsLabelKeys("*"))
+	t.Cleanup(func() { Configure() })
+
+	err := WithOperation(errors.New("foo"), "search", time.Second)
+	err = WithCardinalKey(err, "point_id", 42)
+	err = WithMetadata(err, "shard", 3)
+	require.Equal(t, map[string]string{"code": "Unknown", "OPERATION": "search", "SHARD": "3"}, MetricsLabels(err))
+}
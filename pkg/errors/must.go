@@ -0,0 +1,35 @@
+package errors
+
+// mustError is the error Must and Must0 panic with.
+// Its message includes the code and metadata of the error, as the runtime only prints
+// the message of the panic value.
+type mustError struct {
+	err error
+}
+
+func (m *mustError) Error() string {
+	return "unexpected error: " + Logfmt(m.err)
+}
+
+func (m *mustError) Unwrap() error {
+	return m.err
+}
+
+// Must returns v if err is nil, and panics otherwise. It's meant for tests and init code,
+// where the error should be impossible, e.g.:
+//
+//	var defaultConfig = errhelper.Must(loadConfig())
+//
+// The panic value is an error wrapping err, whose message includes the code and metadata of err
+// rendered with Logfmt, so a recovering layer can still use errors.Is, errors.As and GetMetadata.
+func Must[T any](v T, err error) T {
+	Must0(err)
+	return v
+}
+
+// Must0 panics if err is not nil, the same way Must does.
+func Must0(err error) {
+	if err != nil {
+		panic(newErrWithMetadata(errWithMetadata{err: &mustError{err: err}}))
+	}
+}
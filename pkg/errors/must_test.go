@@ -0,0 +1,32 @@
+package errors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestMust(t *testing.T) {
+	require.Equal(t, 42, Must(42, nil))
+	require.NotPanics(t, func() { Must0(nil) })
+
+	rootError := errors.New("foo")
+	err := WithCode(WithMetadata(rootError, "key", "value with spaces"), codes.NotFound)
+	require.PanicsWithError(t, `unexpected error: msg="foo" code=NotFound key="value with spaces"`, func() {
+		Must(42, err)
+	})
+
+	// The panic value carries the original error.
+	recovered := func() (r any) {
+		defer func() { r = recover() }()
+		Must0(err)
+		return nil
+	}()
+	panicErr, ok := recovered.(*errWithMetadata)
+	require.True(t, ok)
+	require.ErrorIs(t, panicErr, rootError)
+	require.Equal(t, codes.NotFound, CodeOf(panicErr))
+	require.Equal(t, []any{"key", "value with spaces"}, GetMetadata(panicErr))
+}
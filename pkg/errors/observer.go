@@ -0,0 +1,78 @@
+package errors
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	// wrapObserver holds the function set with SetWrapObserver.
+	wrapObserver atomic.Pointer[func(code codes.Code, keys []string)]
+	// observingGoroutines holds the IDs of the goroutines running the observer.
+	observingGoroutines sync.Map
+)
+
+// SetWrapObserver registers a function called once per WithMetadata and WithCode call wrapping an error,
+// including the helpers built on them, with the code of the resulting error (see CodeOf) and the keys of the
+// metadata added by the call, e.g. to increment an error counter labeled by code.
+// The observer is called synchronously, so it must be cheap and safe for concurrent use.
+// The errors it wraps itself with this package, e.g. when a metrics push fails, are not observed.
+// Calling it with nil removes the observer, which is the default.
+//
+// It is part of the global configuration, see the package documentation.
+func SetWrapObserver(fn func(code codes.Code, keys []string)) {
+	if fn == nil {
+		wrapObserver.Store(nil)
+		return
+	}
+	wrapObserver.Store(&fn)
+}
+
+// GetWrapObserver returns the function set with SetWrapObserver, or nil, e.g. to chain it
+// with another observer or to restore it after a test.
+func GetWrapObserver() func(code codes.Code, keys []string) {
+	if fn := wrapObserver.Load(); fn != nil {
+		return *fn
+	}
+	return nil
+}
+
+// notifyWrap calls the observer set with SetWrapObserver, if any, for the error resulting from a wrap
+// with the provided metadata.
+func notifyWrap(err error, metadata []any) {
+	fn := wrapObserver.Load()
+	if fn == nil {
+		return
+	}
+	// The wraps done by the observer itself must not call it again, endlessly.
+	goroutine := goroutineID()
+	if _, nested := observingGoroutines.LoadOrStore(goroutine, struct{}{}); nested {
+		return
+	}
+	defer observingGoroutines.Delete(goroutine)
+	var keys []string
+	for i := 0; i < len(metadata); i += 2 {
+		if key, ok := metadata[i].(string); ok {
+			keys = append(keys, key)
+		}
+	}
+	(*fn)(CodeOf(err), keys)
+}
+
+// goroutineID returns the ID of the calling goroutine, read from the header of its stack trace,
+// e.g. "goroutine 18 [running]:".
+func goroutineID() uint64 {
+	var buf [64]byte
+	header := buf[:runtime.Stack(buf[:], false)]
+	header = bytes.TrimPrefix(header, []byte("goroutine "))
+	if i := bytes.IndexByte(header, ' '); i >= 0 {
+		header = header[:i]
+	}
+	id, _ := strconv.ParseUint(string(header), 10, 64)
+	return id
+}
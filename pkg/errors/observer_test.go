@@ -0,0 +1,68 @@
+package errors
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type observedWrap struct {
+	code codes.Code
+	keys []string
+}
+
+func TestSetWrapObserver(t *testing.T) {
+	var observed []observedWrap
+	SetWrapObserver(func(code codes.Code, keys []string) {
+		observed = append(observed, observedWrap{code: code, keys: keys})
+	})
+	t.Cleanup(func() { SetWrapObserver(nil) })
+	require.NotNil(t, GetWrapObserver())
+
+	err := WithMetadata(status.Error(codes.NotFound, "not found"), "k1", "v1", 1, "v2", "k3", "v3")
+	err = WithCode(err, codes.Internal)
+	_ = WithTenant(err, "tenant-a")
+	// Calls that don't wrap are not observed.
+	_ = WithMetadata(nil, "key", "value")
+	_ = WithMetadata(err)
+
+	require.Equal(t, []observedWrap{
+		{code: codes.NotFound, keys: []string{"k1", "k3"}},
+		{code: codes.Internal},
+		{code: codes.Internal, keys: []string{"tenant"}},
+	}, observed)
+
+	SetWrapObserver(nil)
+	_ = WithMetadata(errors.New("foo"), "key", "value")
+	require.Len(t, observed, 3)
+}
+
+func TestWrapObserverRecursion(t *testing.T) {
+	var calls atomic.Int32
+	SetWrapObserver(func(codes.Code, []string) {
+		calls.Add(1)
+		// Errors wrapped by the observer, e.g. a failed metrics push, don't trigger it again.
+		_ = WithMetadata(errors.New("push failed"), "key", "value")
+	})
+	t.Cleanup(func() { SetWrapObserver(nil) })
+
+	_ = WithMetadata(errors.New("foo"), "key", "value")
+	require.Equal(t, int32(1), calls.Load())
+	_ = WithCode(errors.New("foo"), codes.Internal)
+	require.Equal(t, int32(2), calls.Load())
+
+	// Wraps from all goroutines are observed, including while another call of the observer is running.
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Go(func() {
+			_ = WithMetadata(errors.New("foo"), "key", "value")
+		})
+	}
+	wg.Wait()
+	require.Equal(t, int32(52), calls.Load())
+}
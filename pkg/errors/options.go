@@ -0,0 +1,157 @@
+package errors
+
+import (
+	"slices"
+	"sync/atomic"
+)
+
+// Option configures how errors are converted to gRPC statuses, see Configure.
+type Option func(*options)
+
+type options struct {
+	// maxDetails is the maximum number of details of the statuses returned by GRPCStatus, 0 means no limit.
+	maxDetails int
+	// maxMetadataBytes is the maximum serialized size of the metadata of the statuses returned by GRPCStatus,
+	// 0 means no limit.
+	maxMetadataBytes int
+	// metadataPriority lists the metadata keys dropped last to honor maxMetadataBytes, nil means the default.
+	metadataPriority []string
+	// mergeFuncs holds the functions combining the values of repeated keys, by key.
+	mergeFuncs map[string]func(old, new any) any
+	// redactStatus applies the value redactors to the metadata sent by GRPCStatus.
+	redactStatus bool
+	// statusScope is the scope of the metadata sent by GRPCStatus.
+	statusScope Scope
+	// sequenceLayers stamps the layers with their creation order.
+	sequenceLayers bool
+	// labelKeys holds the patterns of the metadata keys returned by MetricsLabels, besides the operation.
+	labelKeys []string
+	// labelDenylist holds the patterns of the metadata keys excluded by MetricsLabels.
+	labelDenylist []string
+}
+
+// config holds the options set with Configure.
+var config atomic.Pointer[options]
+
+// Configure sets the options used by GRPCStatus and GetMetadataMap.
+// Calling it again replaces the previous configuration, calling it without options restores the defaults.
+//
+// It is part of the global configuration, see the package documentation.
+func Configure(opts ...Option) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	config.Store(o)
+}
+
+// getOptions returns the options set with Configure.
+func getOptions() *options {
+	if o := config.Load(); o != nil {
+		return o
+	}
+	return &options{}
+}
+
+// MaxDetails caps the number of details of the statuses returned by GRPCStatus to n,
+// so that large errors don't exceed the message size limits of gRPC and fail to be sent.
+// The metadata detail, and its type hints, are always kept, while the least important
+// other details are dropped, their number being reported under the "details_truncated" metadata key.
+// Details are ranked by type, from ErrorInfo and RetryInfo, the most important, to DebugInfo,
+// followed by unknown types, and by position for the same type, the first ones being the most important.
+// A value lower than or equal to 0 means no limit, which is the default.
+func MaxDetails(n int) Option {
+	return func(o *options) {
+		o.maxDetails = n
+	}
+}
+
+// MaxMetadataBytes caps the serialized size of the metadata detail of the statuses returned by GRPCStatus,
+// along with its type hints, to n bytes, so that statuses are guaranteed to fit the receive limit of clients.
+// Metadata keys are dropped until the metadata fits, the number of dropped bytes being reported under the
+// "metadata_dropped_bytes" metadata key, which is never dropped, like "details_truncated".
+// Keys that are not listed by MetadataPriority are dropped first, the largest first, followed by the listed keys,
+// from the last one to the first one. The size is estimated, ignoring the framing of the detail in the status.
+// A value lower than or equal to 0 means no limit, which is the default.
+func MaxMetadataBytes(n int) Option {
+	return func(o *options) {
+		o.maxMetadataBytes = n
+	}
+}
+
+// MetadataPriority sets the metadata keys kept the longest to honor MaxMetadataBytes, from the most important
+// to the least important one. By default, the request ID ("request_id") is the most important key,
+// followed by the reserved keys of this package, such as the tenant and the severity.
+func MetadataPriority(keys ...string) Option {
+	return func(o *options) {
+		o.metadataPriority = slices.Clone(keys)
+	}
+}
+
+// MergeFunc sets the function combining the values of the key when it's set multiple times in an error chain,
+// instead of keeping the outermost value, e.g. to accumulate warnings into a slice. It's used by GetMetadataMap,
+// and the helpers built on it, as well as GRPCStatus, which sends the combined value.
+// The function is called with the values from the innermost to the outermost one, old being the result
+// of the previous call, if any. As the combined value is sent over gRPC, old can also be a value combined
+// by another service. The other keys keep the default behavior, the outermost value wins.
+func MergeFunc(key string, fn func(old, new any) any) Option {
+	return func(o *options) {
+		if fn == nil {
+			delete(o.mergeFuncs, key)
+			return
+		}
+		if o.mergeFuncs == nil {
+			o.mergeFuncs = map[string]func(old, new any) any{}
+		}
+		o.mergeFuncs[key] = fn
+	}
+}
+
+// RedactGRPCStatus applies the functions registered with RegisterValueRedactor to the metadata
+// of the statuses returned by GRPCStatus, so the redacted values are never sent to other services.
+// By default, the metadata is sent unchanged.
+func RedactGRPCStatus() Option {
+	return func(o *options) {
+		o.redactStatus = true
+	}
+}
+
+// ExternalMetadataOnly makes GRPCStatus only send the metadata in the ScopeExternal scope,
+// i.e. the metadata attached with WithScopedMetadata and ScopeExternal or ScopeBoth, and the one attached
+// with WithMetadata, keeping the metadata in the ScopeInternal scope out of the responses sent to clients.
+// By default, all the metadata is sent.
+func ExternalMetadataOnly() Option {
+	return func(o *options) {
+		o.statusScope = ScopeExternal
+	}
+}
+
+// SequenceLayers stamps each layer created by WithMetadata, WithCode and the helpers built on them
+// with a sequence number increasing with their creation, reported by Layers and ToTreeJSON, to reconstruct
+// the order in which the layers were created, even when they carry the same message and similar metadata.
+// It's disabled by default, to avoid the cost of the shared counter.
+func SequenceLayers() Option {
+	return func(o *options) {
+		o.sequenceLayers = true
+	}
+}
+
+// MetricsLabelKeys adds the metadata keys matching the patterns to the labels returned by MetricsLabels,
+// e.g. "shard" or "cache_*". Patterns follow the syntax of path.Match, and malformed patterns match no key.
+// By default, only the code and the operation set with WithOperation are returned, as other keys, such as
+// the tenant, may take as many values as there are users, exploding the number of time series.
+func MetricsLabelKeys(patterns ...string) Option {
+	return func(o *options) {
+		o.labelKeys = slices.Clone(patterns)
+	}
+}
+
+// MetricsLabelDenylist excludes the metadata keys matching the patterns from the labels returned by MetricsLabels,
+// e.g. "request_id" or "*_id". Patterns follow the syntax of path.Match, and malformed patterns match no key.
+// It takes precedence over MetricsLabelKeys, e.g. to allow "*" except a few keys.
+// The keys attached with WithCardinalKey and the high-cardinality reserved keys are always excluded.
+func MetricsLabelDenylist(patterns ...string) Option {
+	return func(o *options) {
+		o.labelDenylist = slices.Clone(patterns)
+	}
+}
@@ -0,0 +1,43 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeFunc(t *testing.T) {
+	appendWarnings := func(old, new any) any {
+		warnings, ok := old.([]any)
+		if !ok {
+			warnings = []any{old}
+		}
+		return append(slices.Clone(warnings), new)
+	}
+	Configure(MergeFunc("warnings", appendWarnings), MergeFunc("ignored", nil))
+	t.Cleanup(func() { Configure() })
+
+	err := WithMetadata(
+		fmt.Errorf("outer: %w", WithMetadata(WithMetadata(errors.New("foo"), "warnings", "w1", "key", "inner"), "warnings", "w2")),
+		"warnings", "w3", "key", "outer")
+	expected := map[string]any{"warnings": []any{"w1", "w2", "w3"}, "key": "outer"}
+	require.Equal(t, expected, GetMetadataMap(err))
+
+	// The combined value is sent over gRPC, and combined again on the receiving side.
+	received := roundTrip(t, err)
+	require.Equal(t, expected, GetMetadataMap(received))
+	require.Equal(t, map[string]any{"warnings": []any{"w1", "w2", "w3", "w4"}, "key": "outer"},
+		GetMetadataMap(WithMetadata(received, "warnings", "w4")))
+
+	// A single value is not combined.
+	require.Equal(t, map[string]any{"warnings": "w1"}, GetMetadataMap(WithMetadata(errors.New("foo"), "warnings", "w1")))
+
+	// Keys are combined before being transformed.
+	SetKeyTransformer(strings.ToUpper)
+	t.Cleanup(func() { SetKeyTransformer(nil) })
+	require.Equal(t, map[string]any{"WARNINGS": []any{"w1", "w2", "w3"}, "KEY": "outer"}, GetMetadataMap(err))
+}
@@ -0,0 +1,63 @@
+// Package otel provides helpers to propagate the metadata of the errors of the errors package
+// through OpenTelemetry.
+package otel
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/baggage"
+
+	errhelper "github.com/qdrant/go-commons/pkg/errors"
+)
+
+// MetadataToBaggage returns a copy of ctx whose baggage carries the metadata of the error
+// under the provided keys, so downstream spans can see it. Only the provided keys are propagated,
+// to avoid leaking sensitive metadata. Values are encoded with fmt.Sprint, and keys that are not
+// valid baggage keys are skipped.
+// The values take precedence over the baggage members of ctx with the same keys.
+// If the error is nil, or none of the keys is present, ctx is returned unchanged.
+func MetadataToBaggage(ctx context.Context, err error, keys ...string) context.Context {
+	if err == nil || len(keys) == 0 {
+		return ctx
+	}
+	bag := baggage.FromContext(ctx)
+	changed := false
+	for key, value := range errhelper.All(err) {
+		if !slices.Contains(keys, key) {
+			continue
+		}
+		member, err := baggage.NewMemberRaw(key, fmt.Sprint(value))
+		if err != nil {
+			continue
+		}
+		if updated, err := bag.SetMember(member); err == nil {
+			bag, changed = updated, true
+		}
+	}
+	if !changed {
+		return ctx
+	}
+	return baggage.ContextWithBaggage(ctx, bag)
+}
+
+// BaggageToMetadata returns the baggage members of ctx as key value pairs, sorted by key,
+// ready to be attached to an error with WithMetadata. It's the inverse of MetadataToBaggage,
+// the values being the strings they were encoded to.
+// If keys are provided, only the members with these keys are returned.
+func BaggageToMetadata(ctx context.Context, keys ...string) []any {
+	members := baggage.FromContext(ctx).Members()
+	slices.SortFunc(members, func(a, b baggage.Member) int {
+		return strings.Compare(a.Key(), b.Key())
+	})
+	metadata := make([]any, 0, len(members)*2)
+	for _, member := range members {
+		if len(keys) > 0 && !slices.Contains(keys, member.Key()) {
+			continue
+		}
+		metadata = append(metadata, member.Key(), member.Value())
+	}
+	return metadata
+}
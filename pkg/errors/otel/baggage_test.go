@@ -0,0 +1,48 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/baggage"
+
+	errhelper "github.com/qdrant/go-commons/pkg/errors"
+)
+
+func TestMetadataToBaggage(t *testing.T) {
+	err := errhelper.WithMetadata(errors.New("foo"),
+		"collection", "test", "shard_id", 3, "token", "secret", "\xff", "value")
+
+	ctx := MetadataToBaggage(context.Background(), err, "collection", "shard_id", "\xff")
+	bag := baggage.FromContext(ctx)
+	require.Equal(t, 2, bag.Len())
+	require.Equal(t, "test", bag.Member("collection").Value())
+	require.Equal(t, "3", bag.Member("shard_id").Value())
+
+	// Only the allowlisted keys are propagated.
+	require.Equal(t, []any{"collection", "test", "shard_id", "3"}, BaggageToMetadata(ctx))
+	require.Equal(t, []any{"shard_id", "3"}, BaggageToMetadata(ctx, "shard_id", "token"))
+}
+
+func TestMetadataToBaggageKeepsMembers(t *testing.T) {
+	member, err := baggage.NewMemberRaw("collection", "other")
+	require.NoError(t, err)
+	existing, err := baggage.NewMemberRaw("tenant", "tenant-a")
+	require.NoError(t, err)
+	bag, err := baggage.New(member, existing)
+	require.NoError(t, err)
+	ctx := baggage.ContextWithBaggage(context.Background(), bag)
+
+	ctx = MetadataToBaggage(ctx, errhelper.WithMetadata(errors.New("foo"), "collection", "test"), "collection")
+	require.Equal(t, []any{"collection", "test", "tenant", "tenant-a"}, BaggageToMetadata(ctx))
+}
+
+func TestMetadataToBaggageUnchanged(t *testing.T) {
+	ctx := context.Background()
+	require.Equal(t, ctx, MetadataToBaggage(ctx, nil, "collection"))
+	require.Equal(t, ctx, MetadataToBaggage(ctx, errhelper.WithMetadata(errors.New("foo"), "collection", "test")))
+	require.Equal(t, ctx, MetadataToBaggage(ctx, errors.New("foo"), "collection"))
+	require.Empty(t, BaggageToMetadata(ctx))
+}
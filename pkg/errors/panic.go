@@ -0,0 +1,79 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"runtime/debug"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+)
+
+// FormatPanic renders a recovered panic value as a readable multi-line string for crash logs,
+// followed by the stack of the current goroutine, which is the stack of the panic when called
+// from the deferred function recovering it:
+//
+//	defer func() {
+//		if r := recover(); r != nil {
+//			logger.Error(errhelper.FormatPanic(r))
+//		}
+//	}()
+//
+// If the value is an error carrying metadata (see MetadataError), such as the panic value of Must,
+// its message is followed by its code and metadata, redacted like Logfmt, one key per line, sorted by key,
+// while the runtime would only print its message. Other values are formatted with fmt.Sprint.
+func FormatPanic(r any) string {
+	var b strings.Builder
+	b.WriteString("panic: ")
+	err, isErr := r.(error)
+	var metadataErr MetadataError
+	if isErr && errors.As(err, &metadataErr) {
+		b.WriteString(err.Error())
+		b.WriteString("\ncode: ")
+		b.WriteString(CodeName(err))
+		metadata := LogMetadata(err)
+		if len(metadata) > 0 {
+			b.WriteString("\nmetadata:")
+			for _, key := range slices.Sorted(maps.Keys(metadata)) {
+				b.WriteString("\n  ")
+				b.WriteString(logfmtKey(key))
+				b.WriteByte('=')
+				b.WriteString(logfmtValue(metadata[key]))
+			}
+		}
+	} else {
+		fmt.Fprint(&b, r)
+	}
+	b.WriteString("\n\n")
+	b.Write(debug.Stack())
+	return b.String()
+}
+
+// RecoverToError converts a value recovered from a panic into an error, e.g. to return it from a function
+// that must not crash the process:
+//
+//	defer func() {
+//		if r := recover(); r != nil {
+//			err = errhelper.RecoverToError(r)
+//		}
+//	}()
+//
+// The message is "panic: " followed by the value. An error value is wrapped, so errors.Is, errors.As,
+// its code and its metadata still reach it, such as the panic value of Must, while other values are
+// formatted with fmt.Sprint. The error has codes.Internal, unless the error value carries a code,
+// and the stack of the current goroutine under the "panic_stack" key, which is the stack of the panic
+// when called from the deferred function recovering it. It returns nil for a nil value.
+func RecoverToError(r any) error {
+	if r == nil {
+		return nil
+	}
+	var err error
+	if rErr, ok := r.(error); ok {
+		err = fmt.Errorf("panic: %w", rErr)
+	} else {
+		err = fmt.Errorf("panic: %v", r)
+	}
+	return WithMetadata(WithCodeIfUnset(err, codes.Internal), panicStackKey, string(debug.Stack()))
+}
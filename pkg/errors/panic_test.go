@@ -0,0 +1,58 @@
+package errors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func recoverPanic(fn func()) (formatted string) {
+	defer func() {
+		formatted = FormatPanic(recover())
+	}()
+	fn()
+	return ""
+}
+
+func TestFormatPanic(t *testing.T) {
+	formatted := recoverPanic(func() {
+		Must0(WithMetadata(WithCode(errors.New("item not found"), codes.NotFound), "key", "value with spaces", "id", 1))
+	})
+	require.Contains(t, formatted, "panic: unexpected error: msg=\"item not found\" code=NotFound id=1 key=\"value with spaces\"\n"+
+		"code: NotFound\n"+
+		"metadata:\n"+
+		"  id=1\n"+
+		"  key=\"value with spaces\"\n\n")
+	require.Contains(t, formatted, "goroutine ")
+	require.Contains(t, formatted, "recoverPanic")
+
+	formatted = recoverPanic(func() {
+		panic(errors.New("foo"))
+	})
+	require.Contains(t, formatted, "panic: foo\n\ngoroutine ")
+
+	formatted = recoverPanic(func() {
+		panic(42)
+	})
+	require.Contains(t, formatted, "panic: 42\n\ngoroutine ")
+}
+
+func TestRecoverToError(t *testing.T) {
+	require.NoError(t, RecoverToError(nil))
+
+	err := RecoverToError("boom")
+	require.EqualError(t, err, "panic: boom")
+	require.Equal(t, codes.Internal, CodeOf(err))
+	stack, ok := GetMetadataMap(err)["panic_stack"].(string)
+	require.True(t, ok)
+	require.Contains(t, stack, "TestRecoverToError")
+
+	cause := WithMetadata(WithCode(errors.New("not found"), codes.NotFound), "key", "value")
+	err = RecoverToError(cause)
+	require.EqualError(t, err, "panic: not found")
+	require.ErrorIs(t, err, cause)
+	require.Equal(t, codes.NotFound, CodeOf(err))
+	require.Equal(t, "value", GetMetadataMap(err)["key"])
+}
@@ -0,0 +1,32 @@
+package errors
+
+// WithPrefixedMetadata returns the provided error wrapped so that its metadata is exposed with every key
+// prefixed by prefix and a dot, e.g. to embed the context of a downstream error under "backend."
+// without clobbering the keys of the current service. GetMetadata, GRPCStatus and the other functions reading
+// the metadata of the chain see the prefixed keys only, while the provided error is not modified,
+// and errors.Is, errors.As and CodeOf still reach it. The metadata attached to the returned error itself,
+// e.g. with WithMetadata, and the global metadata are not prefixed. Keys that are not strings are kept unchanged.
+// It returns the error unchanged for an empty prefix, and nil for a nil error.
+func WithPrefixedMetadata(err error, prefix string) error {
+	if err == nil || prefix == "" {
+		return err
+	}
+	wrapped := newErrWithMetadata(errWithMetadata{
+		err:       err,
+		keyPrefix: prefix + ".",
+	})
+	notifyWrap(wrapped, nil)
+	return wrapped
+}
+
+// prefixKeys returns a copy of the key value pairs with the string keys prefixed.
+func prefixKeys(metadata []any, prefix string) []any {
+	prefixed := make([]any, len(metadata))
+	for i := 0; i+1 < len(metadata); i += 2 {
+		prefixed[i], prefixed[i+1] = metadata[i], metadata[i+1]
+		if key, ok := metadata[i].(string); ok {
+			prefixed[i] = prefix + key
+		}
+	}
+	return prefixed
+}
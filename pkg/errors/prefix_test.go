@@ -0,0 +1,50 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWithPrefixedMetadata(t *testing.T) {
+	require.NoError(t, WithPrefixedMetadata(nil, "backend"))
+	base := WithMetadata(errors.New("foo"), "key", "value")
+	require.Equal(t, base, WithPrefixedMetadata(base, ""))
+
+	SetGlobalMetadata("version", "1.0")
+	t.Cleanup(func() { SetGlobalMetadata() })
+
+	sentinel := status.Error(codes.NotFound, "not found")
+	downstream := fmt.Errorf("lookup: %w", WithMetadata(WithCode(sentinel, codes.Unavailable), "collection", "a", 42, "int key"))
+	received := roundTrip(t, WithMetadata(downstream, "request_id", "downstream"))
+	err := WithMetadata(WithPrefixedMetadata(received, "backend"), "request_id", "local")
+
+	require.Equal(t, map[string]any{
+		"version":            "1.0",
+		"backend.version":    "1.0",
+		"backend.collection": "a",
+		"backend.request_id": "downstream",
+		"request_id":         "local",
+	}, GetMetadataMap(err))
+	require.Equal(t, codes.Unavailable, CodeOf(err))
+	require.Equal(t, map[string]any{
+		"version":            "1.0",
+		"backend.version":    "1.0",
+		"backend.collection": "a",
+		"backend.request_id": "downstream",
+		"request_id":         "local",
+	}, GetMetadataMap(roundTrip(t, err)))
+
+	// The original error is not modified, and remains reachable.
+	local := WithPrefixedMetadata(downstream, "backend")
+	require.Equal(t, []any{"collection", "a", 42, "int key"}, GetMetadata(downstream)[2:])
+	require.Equal(t, []any{"version", "1.0", "backend.collection", "a", 42, "int key"}, GetMetadata(local))
+	require.ErrorIs(t, local, sentinel)
+	var st interface{ GRPCStatus() *status.Status }
+	require.ErrorAs(t, local, &st)
+	require.EqualError(t, local, downstream.Error())
+}
@@ -0,0 +1,146 @@
+package errors
+
+import (
+	"maps"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WithPublicMessage returns the provided error wrapped with a message safe to show to users,
+// while Error keeps returning the detailed internal message.
+func WithPublicMessage(err error, msg string) error {
+	return WithMetadata(err, publicMessageKey, msg)
+}
+
+// PublicMessage returns the message attached to the error with WithPublicMessage, if any.
+func PublicMessage(err error) (string, bool) {
+	v, ok := lookup(err, publicMessageKey)
+	if !ok {
+		return "", false
+	}
+	msg, ok := v.(string)
+	return msg, ok
+}
+
+// WithGRPCMessage returns the provided error wrapped with the message used by GRPCStatus, instead of
+// the message of the error, e.g. a shorter and cleaner message for clients, while Error keeps returning
+// the detailed message for local logs. The code, details and metadata are sent as usual, use Sanitize
+// to drop them as well. The outermost message wins. It returns nil for a nil error.
+func WithGRPCMessage(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	wrapped := newErrWithMetadata(errWithMetadata{
+		err:            err,
+		grpcMessage:    msg,
+		hasGRPCMessage: true,
+	})
+	notifyWrap(wrapped, nil)
+	return wrapped
+}
+
+// Sanitize returns an error safe to return to external clients.
+// It is a gRPC status error with the code of the provided error (see CodeOf) and its public message,
+// or the default text of the code (see DefaultMessageFor) if there is no public message.
+// The detailed message and the metadata of the provided error are dropped.
+// An error with codes.OK gets codes.Unknown, like in GRPCStatus, as a status with codes.OK is no error.
+func Sanitize(err error) error {
+	if err == nil {
+		return nil
+	}
+	code := CodeOf(err)
+	if code == codes.OK {
+		code = codes.Unknown
+	}
+	msg, ok := PublicMessage(err)
+	if !ok {
+		msg = DefaultMessageFor(code)
+	}
+	return status.Error(code, msg)
+}
+
+// builtinDefaultMessages holds the default texts of the codes, used until overridden with SetDefaultMessage.
+var builtinDefaultMessages = map[codes.Code]string{
+	codes.OK:                 "the request succeeded",
+	codes.Canceled:           "the request was canceled",
+	codes.Unknown:            "an unknown error occurred",
+	codes.InvalidArgument:    "the request is invalid",
+	codes.DeadlineExceeded:   "the request timed out",
+	codes.NotFound:           "the requested resource was not found",
+	codes.AlreadyExists:      "the resource already exists",
+	codes.PermissionDenied:   "permission denied",
+	codes.ResourceExhausted:  "too many requests, please retry later",
+	codes.FailedPrecondition: "the request cannot be executed in the current state",
+	codes.Aborted:            "the request was aborted, please retry",
+	codes.OutOfRange:         "the request is out of the valid range",
+	codes.Unimplemented:      "the operation is not supported",
+	codes.Internal:           "an internal error occurred",
+	codes.Unavailable:        "the service is unavailable, please retry later",
+	codes.DataLoss:           "data was lost or corrupted",
+	codes.Unauthenticated:    "the request is not authenticated",
+}
+
+var (
+	// defaultMessages holds the overrides set with SetDefaultMessage, it's only written under defaultMessagesMu.
+	defaultMessages   atomic.Pointer[map[codes.Code]string]
+	defaultMessagesMu sync.Mutex
+)
+
+// SetDefaultMessage overrides the default user-facing text of the code, used by Sanitize and Expected
+// when no public or explicit message is available, e.g. to localize it. An empty message restores the built-in text.
+//
+// It is part of the global configuration, see the package documentation.
+func SetDefaultMessage(code codes.Code, msg string) {
+	defaultMessagesMu.Lock()
+	defer defaultMessagesMu.Unlock()
+	var messages map[codes.Code]string
+	if current := defaultMessages.Load(); current != nil {
+		messages = maps.Clone(*current)
+	} else {
+		messages = make(map[codes.Code]string)
+	}
+	if msg == "" {
+		delete(messages, code)
+	} else {
+		messages[code] = msg
+	}
+	defaultMessages.Store(&messages)
+}
+
+// DefaultMessageFor returns the default user-facing text of the code, either set with SetDefaultMessage
+// or built in, e.g. "the requested resource was not found" for codes.NotFound.
+// It returns the name of the code for codes without a default text.
+func DefaultMessageFor(code codes.Code) string {
+	if messages := defaultMessages.Load(); messages != nil {
+		if msg, ok := (*messages)[code]; ok {
+			return msg
+		}
+	}
+	if msg, ok := builtinDefaultMessages[code]; ok {
+		return msg
+	}
+	return code.String()
+}
+
+// MarkExternal returns the provided error tagged as externalized, i.e. deliberately stripped
+// of internal details, typically the output of Sanitize. It doesn't strip anything by itself.
+// The tag is sent over gRPC, so downstream services know the error is already externalized.
+// It returns nil for a nil error.
+func MarkExternal(err error) error {
+	return WithMetadata(err, externalKey, true)
+}
+
+// IsExternal reports whether the error was tagged with MarkExternal.
+// Unlike an empty GetMetadata, which also holds for errors never wrapped,
+// it lets boundary code verify that the error went through sanitization.
+func IsExternal(err error) bool {
+	v, ok := lookup(err, externalKey)
+	if !ok {
+		return false
+	}
+	external, ok := v.(bool)
+	return ok && external
+}
@@ -0,0 +1,175 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPublicMessage(t *testing.T) {
+	require.NoError(t, WithPublicMessage(nil, "public"))
+
+	_, ok := PublicMessage(errors.New("foo"))
+	require.False(t, ok)
+
+	err := WithPublicMessage(errors.New("connection to 10.0.0.1:5432 refused"), "the service is temporarily unavailable")
+	require.Equal(t, "connection to 10.0.0.1:5432 refused", err.Error())
+	msg, ok := PublicMessage(err)
+	require.True(t, ok)
+	require.Equal(t, "the service is temporarily unavailable", msg)
+
+	// The outermost public message wins.
+	msg, ok = PublicMessage(WithPublicMessage(fmt.Errorf("bar: %w", err), "outer"))
+	require.True(t, ok)
+	require.Equal(t, "outer", msg)
+
+	// The public message survives a gRPC round trip.
+	msg, ok = PublicMessage(roundTrip(t, err))
+	require.True(t, ok)
+	require.Equal(t, "the service is temporarily unavailable", msg)
+}
+
+func TestSanitize(t *testing.T) {
+	testCases := []struct {
+		name            string
+		err             error
+		expectedCode    codes.Code
+		expectedMessage string
+	}{
+		{
+			name:            "plain error",
+			err:             WithMetadata(errors.New("secret details"), "secret", "value"),
+			expectedCode:    codes.Unknown,
+			expectedMessage: "an unknown error occurred",
+		},
+		{
+			name:            "gRPC status error with metadata",
+			err:             WithMetadata(status.Error(codes.NotFound, "collection secret_name not found"), "secret", "value"),
+			expectedCode:    codes.NotFound,
+			expectedMessage: "the requested resource was not found",
+		},
+		{
+			name:            "error with code and public message",
+			err:             WithPublicMessage(WithCode(errors.New("secret details"), codes.Unavailable), "try again later"),
+			expectedCode:    codes.Unavailable,
+			expectedMessage: "try again later",
+		},
+		{
+			name:            "error with codes.OK",
+			err:             WithCode(errors.New("secret details"), codes.OK),
+			expectedCode:    codes.Unknown,
+			expectedMessage: "an unknown error occurred",
+		},
+		{
+			name:            "wrapped status with codes.OK",
+			err:             fmt.Errorf("bar: %w", WithPublicMessage(okStatusError{}, "something went wrong")),
+			expectedCode:    codes.Unknown,
+			expectedMessage: "something went wrong",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sanitized := Sanitize(tc.err)
+			st, ok := status.FromError(sanitized)
+			require.True(t, ok)
+			require.Equal(t, tc.expectedCode, st.Code())
+			require.Equal(t, tc.expectedMessage, st.Message())
+			require.Empty(t, st.Details())
+			require.Empty(t, GetMetadata(sanitized))
+		})
+	}
+
+	require.NoError(t, Sanitize(nil))
+}
+
+func TestDefaultMessageFor(t *testing.T) {
+	for code := codes.OK; code <= codes.Unauthenticated; code++ {
+		require.NotEqual(t, code.String(), DefaultMessageFor(code), code)
+	}
+	require.Equal(t, "Code(42)", DefaultMessageFor(42))
+
+	SetDefaultMessage(codes.NotFound, "ressource introuvable")
+	SetDefaultMessage(42, "custom")
+	t.Cleanup(ClearDefaultMessages)
+	require.Equal(t, "ressource introuvable", DefaultMessageFor(codes.NotFound))
+	require.Equal(t, "custom", DefaultMessageFor(42))
+	require.Equal(t, "ressource introuvable", status.Convert(Sanitize(WithCode(errors.New("foo"), codes.NotFound))).Message())
+	require.EqualError(t, Expected(codes.NotFound, ""), "ressource introuvable")
+
+	SetDefaultMessage(codes.NotFound, "")
+	require.Equal(t, "the requested resource was not found", DefaultMessageFor(codes.NotFound))
+}
+
+func TestMarkExternal(t *testing.T) {
+	require.NoError(t, MarkExternal(nil))
+	require.False(t, IsExternal(nil))
+	require.False(t, IsExternal(errors.New("foo")))
+	require.False(t, IsExternal(Sanitize(errors.New("foo"))))
+
+	external := MarkExternal(Sanitize(WithCode(errors.New("secret details"), codes.NotFound)))
+	require.True(t, IsExternal(external))
+	require.True(t, IsExternal(fmt.Errorf("foo: %w", external)))
+	require.Equal(t, codes.NotFound, CodeOf(external))
+
+	// The tag survives gRPC round trips.
+	received := roundTrip(t, external)
+	require.True(t, IsExternal(received))
+	require.Equal(t, codes.NotFound, CodeOf(received))
+	require.True(t, IsExternal(roundTrip(t, WithMetadata(received, "key", "value"))))
+}
+
+func TestWithGRPCMessage(t *testing.T) {
+	require.NoError(t, WithGRPCMessage(nil, "foo"))
+
+	testCases := []struct {
+		name            string
+		err             error
+		expectedCode    codes.Code
+		expectedMessage string
+	}{
+		{
+			name:            "plain error",
+			err:             WithGRPCMessage(errors.New("query failed: table secret_name missing"), "query failed"),
+			expectedCode:    codes.Unknown,
+			expectedMessage: "query failed",
+		},
+		{
+			name:            "gRPC status error with code and metadata",
+			err:             WithMetadata(WithCode(WithGRPCMessage(status.Error(codes.NotFound, "collection secret_name not found"), "collection not found"), codes.FailedPrecondition), "key", "value"),
+			expectedCode:    codes.FailedPrecondition,
+			expectedMessage: "collection not found",
+		},
+		{
+			name:            "outermost message wins",
+			err:             WithGRPCMessage(fmt.Errorf("foo: %w", WithGRPCMessage(errors.New("bar"), "inner")), "outer"),
+			expectedCode:    codes.Unknown,
+			expectedMessage: "outer",
+		},
+		{
+			name:            "empty message",
+			err:             WithGRPCMessage(errors.New("bar"), ""),
+			expectedCode:    codes.Unknown,
+			expectedMessage: "",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			st, ok := status.FromError(tc.err)
+			require.True(t, ok)
+			require.Equal(t, tc.expectedCode, st.Code())
+			require.Equal(t, tc.expectedMessage, st.Message())
+			require.NotEqual(t, tc.expectedMessage, tc.err.Error())
+		})
+	}
+
+	// The metadata is sent as usual.
+	err := WithMetadata(WithGRPCMessage(errors.New("detailed"), "short"), "key", "value")
+	received := roundTrip(t, WithCode(err, codes.Internal))
+	require.Equal(t, "rpc error: code = Internal desc = short", received.Error())
+	require.Equal(t, map[string]any{"key": "value"}, GetMetadataMap(received))
+	require.EqualError(t, err, "detailed")
+}
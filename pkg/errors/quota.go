@@ -0,0 +1,84 @@
+package errors
+
+import (
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// QuotaInfo describes an exceeded quota or rate limit.
+type QuotaInfo struct {
+	// Subject identifies the quota, e.g. "tenant:t1" or "ip:10.0.0.1".
+	Subject string
+	// Description explains the limit that was exceeded.
+	Description string
+	// RetryAfter is how long the client should wait before retrying, 0 if unknown.
+	RetryAfter time.Duration
+}
+
+// QuotaExceeded returns an error signaling an exceeded quota or rate limit, with codes.ResourceExhausted
+// and the provided metadata. Its gRPC status carries the standard errdetails.QuotaFailure and errdetails.RetryInfo
+// details, understood by standard gRPC tooling, and the retry delay is also stored in milliseconds
+// under the "retry_after_ms" metadata key. Use QuotaInfoOf to read them back.
+func QuotaExceeded(subject, description string, retryAfter time.Duration, keyValues ...any) error {
+	msg := "quota exceeded for " + subject
+	if description != "" {
+		msg += ": " + description
+	}
+	st := status.New(codes.ResourceExhausted, msg)
+	if withDetails, err := st.WithDetails(
+		&errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{{Subject: subject, Description: description}}},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)},
+	); err == nil {
+		st = withDetails
+	}
+	metadata := make([]any, 0, len(keyValues)+2)
+	metadata = append(metadata, retryAfterMillisKey, retryAfter.Milliseconds())
+	metadata = append(metadata, keyValues...)
+	return WithMetadata(st.Err(), metadata...)
+}
+
+// QuotaInfoOf returns the quota information of an error returned by QuotaExceeded, or of any error
+// whose gRPC status carries an errdetails.QuotaFailure detail, such as errors received from other services.
+// The subject and description are the ones of the first violation. The retry delay is read from
+// the errdetails.RetryInfo detail, or the "retry_after_ms" metadata key if there is none.
+func QuotaInfoOf(err error) (QuotaInfo, bool) {
+	if err == nil {
+		return QuotaInfo{}, false
+	}
+	st, _ := GRPCStatusOf(err)
+	var info QuotaInfo
+	found, hasRetryInfo := false, false
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.QuotaFailure:
+			if found {
+				continue
+			}
+			found = true
+			if violations := d.GetViolations(); len(violations) > 0 {
+				info.Subject = violations[0].GetSubject()
+				info.Description = violations[0].GetDescription()
+			}
+		case *errdetails.RetryInfo:
+			if !hasRetryInfo {
+				hasRetryInfo = true
+				info.RetryAfter = d.GetRetryDelay().AsDuration()
+			}
+		}
+	}
+	if !found {
+		return QuotaInfo{}, false
+	}
+	if !hasRetryInfo {
+		if v, ok := lookup(err, retryAfterMillisKey); ok {
+			if millis, ok := toInt64(v); ok {
+				info.RetryAfter = time.Duration(millis) * time.Millisecond
+			}
+		}
+	}
+	return info, true
+}
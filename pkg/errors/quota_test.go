@@ -0,0 +1,64 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestQuotaExceeded(t *testing.T) {
+	err := QuotaExceeded("tenant:t1", "100 requests per second", 1500*time.Millisecond, "endpoint", "search")
+	require.EqualError(t, err, "rpc error: code = ResourceExhausted desc = quota exceeded for tenant:t1: 100 requests per second")
+	require.Equal(t, codes.ResourceExhausted, CodeOf(err))
+	require.Equal(t, map[string]any{"retry_after_ms": int64(1500), "endpoint": "search"}, GetMetadataMap(err))
+
+	expected := QuotaInfo{Subject: "tenant:t1", Description: "100 requests per second", RetryAfter: 1500 * time.Millisecond}
+	info, ok := QuotaInfoOf(fmt.Errorf("search: %w", err))
+	require.True(t, ok)
+	require.Equal(t, expected, info)
+
+	// The standard details and the metadata survive gRPC round trips.
+	received := roundTrip(t, WithMetadata(err, "key", "value"))
+	st, ok := status.FromError(received)
+	require.True(t, ok)
+	require.Equal(t, codes.ResourceExhausted, st.Code())
+	var quotaFailures, retryInfos int
+	for _, detail := range st.Details() {
+		switch detail.(type) {
+		case *errdetails.QuotaFailure:
+			quotaFailures++
+		case *errdetails.RetryInfo:
+			retryInfos++
+		}
+	}
+	require.Equal(t, 1, quotaFailures)
+	require.Equal(t, 1, retryInfos)
+	require.Equal(t, map[string]any{"retry_after_ms": int64(1500), "endpoint": "search", "key": "value"}, GetMetadataMap(received))
+	info, ok = QuotaInfoOf(received)
+	require.True(t, ok)
+	require.Equal(t, expected, info)
+}
+
+func TestQuotaInfoOf(t *testing.T) {
+	_, ok := QuotaInfoOf(nil)
+	require.False(t, ok)
+	_, ok = QuotaInfoOf(errors.New("foo"))
+	require.False(t, ok)
+	_, ok = QuotaInfoOf(status.Error(codes.ResourceExhausted, "foo"))
+	require.False(t, ok)
+
+	// A status sent by another service, without RetryInfo.
+	st, err := status.New(codes.ResourceExhausted, "slow down").WithDetails(&errdetails.QuotaFailure{
+		Violations: []*errdetails.QuotaFailure_Violation{{Subject: "ip:10.0.0.1"}},
+	})
+	require.NoError(t, err)
+	info, ok := QuotaInfoOf(WithMetadata(st.Err(), "retry_after_ms", 200))
+	require.True(t, ok)
+	require.Equal(t, QuotaInfo{Subject: "ip:10.0.0.1", RetryAfter: 200 * time.Millisecond}, info)
+}
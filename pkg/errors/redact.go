@@ -0,0 +1,66 @@
+package errors
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// valueRedactors holds the functions registered with RegisterValueRedactor,
+	// it's only written under redactorsMu.
+	valueRedactors atomic.Pointer[[]func(key string, value any) (any, bool)]
+	redactorsMu    sync.Mutex
+)
+
+// RegisterValueRedactor registers a function redacting sensitive metadata values when they are rendered,
+// based on the value itself rather than its key, e.g. values looking like bearer tokens or too long values.
+// When the function returns true, the value is replaced by the returned one. The key is provided as well,
+// so a redactor can also redact the values of sensitive keys.
+// Redactors are called in the order they were registered, and the first one redacting a value wins,
+// the next ones are not called for it. Nil functions are ignored.
+//
+// Redaction applies to the renderings meant for logs: Logfmt, FormatPanic and the log adapters, which call
+// RedactValue. The metadata itself is not modified, so GetMetadata and GetMetadataMap return the original values,
+// and GRPCStatus sends them unless the RedactGRPCStatus option is set.
+//
+// It is part of the global configuration, see the package documentation.
+func RegisterValueRedactor(fn func(key string, value any) (any, bool)) {
+	if fn == nil {
+		return
+	}
+	redactorsMu.Lock()
+	defer redactorsMu.Unlock()
+	redactors := append(slices.Clip(getValueRedactors()), fn)
+	valueRedactors.Store(&redactors)
+}
+
+// getValueRedactors returns the functions registered with RegisterValueRedactor.
+// The returned slice must not be modified.
+func getValueRedactors() []func(key string, value any) (any, bool) {
+	if redactors := valueRedactors.Load(); redactors != nil {
+		return *redactors
+	}
+	return nil
+}
+
+// RedactValue returns the value of the metadata key, as redacted by the first function registered with
+// RegisterValueRedactor redacting it, or the value unchanged. It's meant for custom renderers of metadata.
+func RedactValue(key string, value any) any {
+	for _, redact := range getValueRedactors() {
+		if redacted, ok := redact(key, value); ok {
+			return redacted
+		}
+	}
+	return value
+}
+
+// redactMap redacts the values of the metadata map in place.
+func redactMap(metadata map[string]any) {
+	if len(getValueRedactors()) == 0 {
+		return
+	}
+	for key, value := range metadata {
+		metadata[key] = RedactValue(key, value)
+	}
+}
@@ -0,0 +1,77 @@
+package errors
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func registerValueRedactor(t *testing.T, fn func(key string, value any) (any, bool)) {
+	t.Helper()
+	RegisterValueRedactor(fn)
+	t.Cleanup(ClearValueRedactors)
+}
+
+func TestRedactValue(t *testing.T) {
+	require.Equal(t, "Bearer abc", RedactValue("header", "Bearer abc"))
+
+	registerValueRedactor(t, func(_ string, value any) (any, bool) {
+		if s, ok := value.(string); ok && strings.HasPrefix(s, "Bearer ") {
+			return "Bearer [REDACTED]", true
+		}
+		return nil, false
+	})
+	registerValueRedactor(t, func(key string, value any) (any, bool) {
+		if key == "password" || key == "header" {
+			return "[REDACTED]", true
+		}
+		return nil, false
+	})
+	RegisterValueRedactor(nil)
+
+	testCases := []struct {
+		name     string
+		key      string
+		value    any
+		expected any
+	}{
+		{name: "not redacted", key: "id", value: 42, expected: 42},
+		{name: "redacted by value", key: "auth", value: "Bearer abc", expected: "Bearer [REDACTED]"},
+		{name: "redacted by key", key: "password", value: "hunter2", expected: "[REDACTED]"},
+		{name: "first redactor wins", key: "header", value: "Bearer abc", expected: "Bearer [REDACTED]"},
+		{name: "second redactor", key: "header", value: "other", expected: "[REDACTED]"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, RedactValue(tc.key, tc.value))
+		})
+	}
+}
+
+func TestRedactRendering(t *testing.T) {
+	registerValueRedactor(t, func(_ string, value any) (any, bool) {
+		if s, ok := value.(string); ok && strings.HasPrefix(s, "sk-") {
+			return "[REDACTED]", true
+		}
+		return nil, false
+	})
+	err := WithMetadata(status.Error(codes.PermissionDenied, "denied"), "api_key", "sk-123", "id", 1)
+
+	// The metadata itself is unchanged.
+	require.Equal(t, map[string]any{"api_key": "sk-123", "id": 1}, GetMetadataMap(err))
+	require.Equal(t, `msg="rpc error: code = PermissionDenied desc = denied" code=PermissionDenied api_key=[REDACTED] id=1`, Logfmt(err))
+	require.Contains(t, FormatPanic(err), "api_key=[REDACTED]")
+
+	// GRPCStatus only redacts with the RedactGRPCStatus option.
+	require.Equal(t, "sk-123", GetMetadataMap(roundTrip(t, err))["api_key"])
+	Configure(RedactGRPCStatus())
+	t.Cleanup(func() { Configure() })
+	require.Equal(t, "[REDACTED]", GetMetadataMap(roundTrip(t, err))["api_key"])
+
+	wrapped := WithMetadata(errors.New("foo"), "cause", err)
+	require.Contains(t, Logfmt(wrapped), "cause.api_key=[REDACTED]")
+}
@@ -0,0 +1,69 @@
+package errors
+
+import "reflect"
+
+// ResetGlobals restores the defaults of every process-wide setting of the package: the global metadata,
+// the key transformer, the metadata markers, the wrap observer, the encoders and decoders, the context extractors,
+// the value redactors, the default messages, the strict mode, the options set with Configure,
+// and the counter of the layer sequence numbers. It's meant for test teardown, so tests changing these settings
+// don't leak them into the next ones:
+//
+//	t.Cleanup(errhelper.ResetGlobals)
+//
+// It must not be used in production code. Each setting is reset atomically, so it's safe to call concurrently
+// with the use of the package, however errors created meanwhile may see a mix of the old and default settings,
+// and parallel tests relying on these settings, including open errtest recorders, lose them.
+func ResetGlobals() {
+	SetGlobalMetadata()
+	SetKeyTransformer(nil)
+	ClearMetadataMarkers()
+	SetWrapObserver(nil)
+	ClearCodecs()
+	ClearContextExtractors()
+	ClearValueRedactors()
+	ClearDefaultMessages()
+	SetStrict(false)
+	Configure()
+	wrapSequence.Store(0)
+}
+
+// ClearMetadataMarkers removes the markers registered with RegisterMetadataMarker. Like ResetGlobals,
+// it's meant for tests.
+func ClearMetadataMarkers() {
+	markersMu.Lock()
+	defer markersMu.Unlock()
+	metadataMarkers.Store(nil)
+}
+
+// ClearCodecs removes the functions registered with RegisterEncoder and RegisterDecoder. Like ResetGlobals,
+// it's meant for tests.
+func ClearCodecs() {
+	codecs.mu.Lock()
+	defer codecs.mu.Unlock()
+	codecs.encoders = map[reflect.Type]EncoderFunc{}
+	codecs.decoders = map[string]DecoderFunc{}
+}
+
+// ClearContextExtractors removes the functions registered with RegisterContextExtractor. Like ResetGlobals,
+// it's meant for tests.
+func ClearContextExtractors() {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	contextExtractors.Store(nil)
+}
+
+// ClearValueRedactors removes the functions registered with RegisterValueRedactor. Like ResetGlobals,
+// it's meant for tests.
+func ClearValueRedactors() {
+	redactorsMu.Lock()
+	defer redactorsMu.Unlock()
+	valueRedactors.Store(nil)
+}
+
+// ClearDefaultMessages restores the built-in texts of all the codes overridden with SetDefaultMessage.
+// Like ResetGlobals, it's meant for tests.
+func ClearDefaultMessages() {
+	defaultMessagesMu.Lock()
+	defer defaultMessagesMu.Unlock()
+	defaultMessages.Store(nil)
+}
@@ -0,0 +1,45 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestResetGlobals(t *testing.T) {
+	t.Cleanup(ResetGlobals)
+	SetGlobalMetadata("version", "1.0")
+	SetKeyTransformer(strings.ToUpper)
+	RegisterMetadataMarker("__legacy_metadata__")
+	SetWrapObserver(func(codes.Code, []string) {})
+	RegisterEncoder(reflect.TypeOf(money{}), func(v any) (any, error) { return "encoded", nil })
+	RegisterContextExtractor(func(context.Context) []any { return []any{"extracted", true} })
+	RegisterValueRedactor(func(string, any) (any, bool) { return "redacted", true })
+	SetDefaultMessage(codes.NotFound, "nothing here")
+	SetStrict(true)
+	Configure(SequenceLayers())
+	_ = WithMetadata(errors.New("foo"), "key", "value")
+
+	ResetGlobals()
+	require.Empty(t, getGlobalMetadata())
+	require.Nil(t, getKeyTransformer())
+	require.Nil(t, getMetadataMarkers())
+	require.Nil(t, GetWrapObserver())
+	_, ok := codecs.encoder(reflect.TypeOf(money{}))
+	require.False(t, ok)
+	require.Empty(t, getContextExtractors())
+	require.Equal(t, "value", RedactValue("key", "value"))
+	require.Equal(t, builtinDefaultMessages[codes.NotFound], DefaultMessageFor(codes.NotFound))
+	require.Equal(t, &options{}, getOptions())
+
+	err := WithMetadata(errors.New("foo"), "key")
+	require.NotErrorIs(t, err, ErrMalformedMetadata)
+	require.Zero(t, wrapSequence.Load())
+	require.Equal(t, map[string]any{"key": "<missing>"}, GetMetadataMap(status.Convert(err).Err()))
+}
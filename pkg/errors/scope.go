@@ -0,0 +1,44 @@
+package errors
+
+// Scope is the scope of metadata, telling which sinks it's meant for: the internal observability sinks,
+// such as logs, or the external responses sent to clients.
+type Scope int
+
+const (
+	// ScopeBoth is the scope of metadata meant for all the sinks, the default one.
+	ScopeBoth Scope = iota
+	// ScopeInternal is the scope of metadata meant for internal sinks only, e.g. debugging context.
+	ScopeInternal
+	// ScopeExternal is the scope of metadata meant for client-facing responses only.
+	ScopeExternal
+)
+
+// includes reports whether metadata in the other scope is visible to the sinks of the scope.
+// All the metadata is visible to ScopeBoth.
+func (s Scope) includes(other Scope) bool {
+	return s == ScopeBoth || other == ScopeBoth || s == other
+}
+
+// WithScopedMetadata returns the provided error wrapped with the provided metadata, like WithMetadata,
+// restricted to the sinks of the scope: GetMetadataScoped only returns it for this scope, and GRPCStatus
+// doesn't send ScopeInternal metadata if the ExternalMetadataOnly option is set.
+// Metadata attached with WithMetadata, received over gRPC or set globally is in the ScopeBoth scope.
+// The log renderings built on LogMetadata, such as Logfmt, FormatPanic and the log adapters, leave out
+// the ScopeExternal metadata, while other functions reading metadata, such as GetMetadata, ignore the scopes.
+func WithScopedMetadata(err error, scope Scope, keyValues ...any) error {
+	return withScopedMetadata(err, scope, keyValues)
+}
+
+// GetMetadataScoped returns the metadata of the error visible to the sinks of the scope, like GetMetadata:
+// the metadata in the ScopeBoth scope and in the provided scope. With ScopeBoth, it returns all the metadata.
+func GetMetadataScoped(err error, scope Scope) []any {
+	return getScopedMetadata(err, scope)
+}
+
+// layerScope returns the scope of the metadata attached to the error itself.
+func layerScope(err error) Scope {
+	if e, ok := err.(*errWithMetadata); ok { // nolint: errorlint // errors.As should not be used here
+		return e.scope
+	}
+	return ScopeBoth
+}
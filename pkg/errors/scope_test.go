@@ -0,0 +1,63 @@
+package errors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestWithScopedMetadata(t *testing.T) {
+	require.NoError(t, WithScopedMetadata(nil, ScopeInternal, "key", "value"))
+
+	SetGlobalMetadata("version", "1.0")
+	t.Cleanup(func() { SetGlobalMetadata() })
+	err := WithCode(errors.New("foo"), codes.NotFound)
+	err = WithScopedMetadata(err, ScopeInternal, "query", "SELECT 1", "key", "internal")
+	err = WithScopedMetadata(err, ScopeExternal, "hint", "check the name")
+	err = WithMetadata(err, "collection", "a")
+
+	testCases := []struct {
+		name     string
+		scope    Scope
+		expected []any
+	}{
+		{
+			name:     "both",
+			scope:    ScopeBoth,
+			expected: []any{"version", "1.0", "query", "SELECT 1", "key", "internal", "hint", "check the name", "collection", "a"},
+		},
+		{
+			name:     "internal",
+			scope:    ScopeInternal,
+			expected: []any{"version", "1.0", "query", "SELECT 1", "key", "internal", "collection", "a"},
+		},
+		{
+			name:     "external",
+			scope:    ScopeExternal,
+			expected: []any{"version", "1.0", "hint", "check the name", "collection", "a"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, GetMetadataScoped(err, tc.scope))
+		})
+	}
+	require.Equal(t, GetMetadataScoped(err, ScopeBoth), GetMetadata(err))
+
+	// The metadata meant for clients only is not logged.
+	require.Equal(t, map[string]any{"version": "1.0", "query": "SELECT 1", "key": "internal", "collection": "a"}, LogMetadata(err))
+	require.Equal(t, `msg="foo" code=NotFound collection=a key=internal query="SELECT 1" version=1.0`, Logfmt(err))
+
+	// All the metadata is sent by default.
+	require.Equal(t, map[string]any{
+		"version": "1.0", "query": "SELECT 1", "key": "internal", "hint": "check the name", "collection": "a",
+	}, GetMetadataMap(roundTrip(t, err)))
+
+	Configure(ExternalMetadataOnly())
+	t.Cleanup(func() { Configure() })
+	received := roundTrip(t, err)
+	require.Equal(t, codes.NotFound, CodeOf(received))
+	require.Equal(t, map[string]any{"version": "1.0", "hint": "check the name", "collection": "a"}, GetMetadataMap(received))
+}
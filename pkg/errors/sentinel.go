@@ -0,0 +1,50 @@
+package errors
+
+import (
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+)
+
+// IsSentinel reports whether the error matches the sentinel error, whatever the mix of metadata layers,
+// fmt.Errorf layers and gRPC conversions it went through. It first uses errors.Is, and then, as the identity
+// of the sentinel is lost when the error is sent over gRPC, it compares the outermost gRPC status
+// of the chain with the sentinel: the status matches if its message is the message of the sentinel,
+// possibly prefixed by the messages of fmt.Errorf layers ("context: sentinel message"),
+// and if the sentinel carries a code (see HasGRPCStatus), if the status has the same code.
+// A sentinel without code is sent with codes.Unknown, so a prefixed message only matches a status
+// with codes.Unknown, and statuses with other codes must carry exactly the message of the sentinel,
+// e.g. "tenant config: not found" with codes.PermissionDenied doesn't match a sentinel "not found".
+func IsSentinel(err, sentinel error) bool {
+	if errors.Is(err, sentinel) {
+		return true
+	}
+	if err == nil || sentinel == nil {
+		return false
+	}
+	// The message of a gRPC status sentinel is either its status message, or its error message
+	// when it was wrapped by fmt.Errorf before being converted.
+	msgs := []string{sentinel.Error()}
+	if st, ok := foreignStatus(sentinel); ok {
+		msgs = append(msgs, st.Message())
+	}
+	code, hasCode := explicitCode(sentinel)
+	for u := err; u != nil; u = errors.Unwrap(u) {
+		st, ok := foreignStatus(u)
+		if !ok {
+			continue
+		}
+		if hasCode && st.Code() != code {
+			return false
+		}
+		allowPrefix := hasCode || st.Code() == codes.Unknown
+		for _, msg := range msgs {
+			if st.Message() == msg || allowPrefix && strings.HasSuffix(st.Message(), ": "+msg) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
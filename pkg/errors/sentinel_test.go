@@ -0,0 +1,78 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsSentinel(t *testing.T) {
+	sentinels := map[string]error{
+		"plain sentinel":       errors.New("item not found"),
+		"sentinel with code":   WithCode(errors.New("item not found"), codes.NotFound),
+		"gRPC status sentinel": status.Error(codes.NotFound, "item not found"),
+	}
+	wrappings := map[string]func(error) error{
+		"error without wrapping": func(err error) error {
+			return err
+		},
+		"error wrapped with metadata": func(err error) error {
+			return WithMetadata(err, "key", "value")
+		},
+		"error wrapped with custom message": func(err error) error {
+			return fmt.Errorf("foo: %w", err)
+		},
+		"error wrapped in multiple levels with metadata": func(err error) error {
+			return WithMetadata(WithMetadata(err, "k1", "v1"), "k2", "v2")
+		},
+		"error wrapped in multiple levels with custom message": func(err error) error {
+			return fmt.Errorf("foo: %w", fmt.Errorf("bar: %w", err))
+		},
+		"error wrapped in multiple levels with metadata and custom message": func(err error) error {
+			return fmt.Errorf("foo: %w", WithMetadata(err, "k1", "v1"))
+		},
+	}
+	for sentinelName, sentinel := range sentinels {
+		for wrappingName, wrap := range wrappings {
+			t.Run(sentinelName+"/"+wrappingName, func(t *testing.T) {
+				err := wrap(sentinel)
+				require.True(t, IsSentinel(err, sentinel))
+				require.True(t, IsSentinel(fmt.Errorf("outer: %w", err), sentinel))
+
+				// The identity of the sentinel is lost over gRPC, its message is matched.
+				received := overGRPC(err)
+				require.True(t, IsSentinel(received, sentinel))
+				require.True(t, IsSentinel(WithMetadata(fmt.Errorf("outer: %w", received), "key", "value"), sentinel))
+				require.True(t, IsSentinel(overGRPC(WithMetadata(received, "key", "value")), sentinel))
+
+				require.False(t, IsSentinel(received, errors.New("other item not found")))
+				require.False(t, IsSentinel(received, WithCode(errors.New("item not found"), codes.Internal)))
+			})
+		}
+	}
+}
+
+func TestIsSentinelWithoutMatch(t *testing.T) {
+	sentinel := errors.New("item not found")
+	require.True(t, IsSentinel(nil, nil))
+	require.False(t, IsSentinel(nil, sentinel))
+	require.False(t, IsSentinel(sentinel, nil))
+	require.False(t, IsSentinel(errors.New("item not found"), sentinel))
+	require.False(t, IsSentinel(overGRPC(errors.New("other item not found")), sentinel))
+	require.False(t, IsSentinel(overGRPC(errors.New("item not found, retry")), sentinel))
+
+	// The message of a status with a code can't be prefixed for a sentinel without code.
+	notFound := errors.New("not found")
+	require.False(t, IsSentinel(status.Error(codes.PermissionDenied, "tenant config: not found"), notFound))
+	require.True(t, IsSentinel(status.Error(codes.PermissionDenied, "not found"), notFound))
+	require.True(t, IsSentinel(status.Error(codes.Unknown, "tenant config: not found"), notFound))
+}
+
+// overGRPC returns the error received on the other side of a gRPC call returning err.
+func overGRPC(err error) error {
+	return status.FromProto(status.Convert(err).Proto()).Err()
+}
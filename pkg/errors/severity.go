@@ -0,0 +1,55 @@
+package errors
+
+// Severity is the severity of an error, telling how it should be reported.
+type Severity int
+
+const (
+	// SeverityUnspecified is the severity of errors without an explicit severity.
+	SeverityUnspecified Severity = iota
+	SeverityDebug
+	SeverityInfo
+	SeverityWarning
+	SeverityError
+	SeverityCritical
+)
+
+var severityNames = map[Severity]string{
+	SeverityUnspecified: "unspecified",
+	SeverityDebug:       "debug",
+	SeverityInfo:        "info",
+	SeverityWarning:     "warning",
+	SeverityError:       "error",
+	SeverityCritical:    "critical",
+}
+
+// String returns the lowercase name of the severity, e.g. "warning".
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return "unspecified"
+}
+
+// WithSeverity returns the provided error wrapped with the provided severity.
+// The severity is stored by name, so it's readable in the logs and in the received metadata.
+func WithSeverity(err error, severity Severity) error {
+	return WithMetadata(err, severityKey, severity.String())
+}
+
+// SeverityOf returns the severity attached to the error with WithSeverity, if any.
+func SeverityOf(err error) (Severity, bool) {
+	v, ok := lookup(err, severityKey)
+	if !ok {
+		return SeverityUnspecified, false
+	}
+	name, ok := v.(string)
+	if !ok {
+		return SeverityUnspecified, false
+	}
+	for severity, severityName := range severityNames {
+		if severity != SeverityUnspecified && severityName == name {
+			return severity, true
+		}
+	}
+	return SeverityUnspecified, false
+}
@@ -0,0 +1,34 @@
+package errors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWithSeverity(t *testing.T) {
+	require.NoError(t, WithSeverity(nil, SeverityError))
+
+	severity, ok := SeverityOf(errors.New("foo"))
+	require.False(t, ok)
+	require.Equal(t, SeverityUnspecified, severity)
+
+	// The outermost severity wins.
+	err := WithSeverity(WithSeverity(errors.New("foo"), SeverityCritical), SeverityWarning)
+	severity, ok = SeverityOf(err)
+	require.True(t, ok)
+	require.Equal(t, SeverityWarning, severity)
+
+	// The severity survives a gRPC round trip.
+	received := roundTrip(t, WithSeverity(status.Error(codes.Internal, "boom"), SeverityCritical))
+	severity, ok = SeverityOf(received)
+	require.True(t, ok)
+	require.Equal(t, SeverityCritical, severity)
+
+	// An unknown severity is ignored.
+	_, ok = SeverityOf(WithMetadata(errors.New("foo"), "severity", "fatal"))
+	require.False(t, ok)
+}
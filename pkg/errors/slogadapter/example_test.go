@@ -0,0 +1,48 @@
+package slogadapter_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+
+	errhelper "github.com/qdrant/go-commons/pkg/errors"
+)
+
+// render renders each level of the error chain on its own line, with its code, resolved over the chain
+// it wraps, and the metadata attached to it,
+// relying only on the errhelper.MetadataError interface.
+func render(err error) string {
+	var b strings.Builder
+	for err != nil {
+		// Type assertions rather than errors.As, to look at each level on its own.
+		metadataErr, ok := err.(errhelper.MetadataError) // nolint: errorlint // errors.As should not be used here
+		if !ok {
+			fmt.Fprintf(&b, "%q\n", err.Error())
+			err = errors.Unwrap(err)
+			continue
+		}
+		fmt.Fprintf(&b, "[%s]", metadataErr.Code())
+		metadata := metadataErr.Metadata()
+		for i := 0; i+1 < len(metadata); i += 2 {
+			fmt.Fprintf(&b, " %v=%v", metadata[i], metadata[i+1])
+		}
+		b.WriteByte('\n')
+		err = metadataErr.Unwrap()
+	}
+	return b.String()
+}
+
+func Example_customRenderer() {
+	err := errhelper.WithMetadata(errors.New("collection not found"), "collection", "books")
+	err = fmt.Errorf("search: %w", errhelper.WithCode(err, codes.NotFound))
+	err = errhelper.WithMetadata(err, "request_id", "r1")
+	fmt.Print(render(err))
+	// Output:
+	// [NotFound] request_id=r1
+	// "search: collection not found"
+	// [NotFound]
+	// [Unknown] collection=books
+	// "collection not found"
+}
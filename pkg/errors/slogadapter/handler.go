@@ -0,0 +1,111 @@
+package slogadapter
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	errhelper "github.com/qdrant/go-commons/pkg/errors"
+)
+
+// handler is a slog.Handler adding the metadata of the logged errors to the records.
+type handler struct {
+	inner slog.Handler
+}
+
+// NewHandler returns a slog.Handler adding the name of the gRPC code and the metadata of the errors
+// carrying metadata (see errhelper.MetadataError) to the attributes of the records, before passing
+// them to the inner handler. The attributes are added right after the error attribute, in the same group,
+// so the error is logged with its metadata however it's passed to the logger, e.g. with slog.Any("error", err).
+// Errors passed to WithAttrs are handled the same way.
+func NewHandler(inner slog.Handler) slog.Handler {
+	return &handler{inner: inner}
+}
+
+// Enabled reports whether the inner handler handles records at the given level.
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle adds the metadata of the errors to the record and passes it to the inner handler.
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	found := false
+	r.Attrs(func(a slog.Attr) bool {
+		found = hasMetadataError(a)
+		return !found
+	})
+	if !found {
+		return h.inner.Handle(ctx, r)
+	}
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	expanded := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	expanded.AddAttrs(expandAttrs(attrs)...)
+	return h.inner.Handle(ctx, expanded)
+}
+
+// WithAttrs returns a handler whose inner handler has the attributes, with the metadata of the errors added.
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &handler{inner: h.inner.WithAttrs(expandAttrs(attrs))}
+}
+
+// WithGroup returns a handler whose inner handler has the group.
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{inner: h.inner.WithGroup(name)}
+}
+
+// expandAttrs returns the attributes with the code and metadata of the errors carrying metadata
+// added after them, looking into groups.
+func expandAttrs(attrs []slog.Attr) []slog.Attr {
+	expanded := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+		switch a.Value.Kind() {
+		case slog.KindGroup:
+			expanded = append(expanded, slog.Attr{Key: a.Key, Value: slog.GroupValue(expandAttrs(a.Value.Group())...)})
+		case slog.KindAny:
+			expanded = append(expanded, a)
+			if err := metadataError(a.Value); err != nil {
+				// Skip the error message, it's already logged.
+				expanded = append(expanded, Attrs(err)[1:]...)
+			}
+		default:
+			expanded = append(expanded, a)
+		}
+	}
+	return expanded
+}
+
+// hasMetadataError reports whether the attribute, or any attribute of its group, is an error carrying metadata.
+func hasMetadataError(a slog.Attr) bool {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		for _, ga := range v.Group() {
+			if hasMetadataError(ga) {
+				return true
+			}
+		}
+		return false
+	}
+	return metadataError(v) != nil
+}
+
+// metadataError returns the error held by the value if its chain contains an error carrying metadata,
+// or nil otherwise.
+func metadataError(v slog.Value) error {
+	if v.Kind() != slog.KindAny {
+		return nil
+	}
+	err, ok := v.Any().(error)
+	if !ok {
+		return nil
+	}
+	var metadataErr errhelper.MetadataError
+	if !errors.As(err, &metadataErr) {
+		return nil
+	}
+	return err
+}
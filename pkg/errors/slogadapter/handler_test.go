@@ -0,0 +1,84 @@
+package slogadapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	errhelper "github.com/qdrant/go-commons/pkg/errors"
+)
+
+func TestHandler(t *testing.T) {
+	err := fmt.Errorf("request: %w", errhelper.WithMetadata(errhelper.WithCode(errors.New("foo"), codes.NotFound), "key", "value"))
+
+	testCases := []struct {
+		name           string
+		log            func(logger *slog.Logger)
+		expectedRecord map[string]any
+	}{
+		{
+			name: "error attribute",
+			log: func(logger *slog.Logger) {
+				logger.Error("failed", "error", err, "other", 1)
+			},
+			expectedRecord: map[string]any{"error": "request: foo", "code": "NotFound", "key": "value", "other": float64(1)},
+		},
+		{
+			name: "plain error",
+			log: func(logger *slog.Logger) {
+				logger.Error("failed", "error", errors.New("foo"))
+			},
+			expectedRecord: map[string]any{"error": "foo"},
+		},
+		{
+			name: "error in a group",
+			log: func(logger *slog.Logger) {
+				logger.Error("failed", slog.Group("request", "error", err))
+			},
+			expectedRecord: map[string]any{"request": map[string]any{"error": "request: foo", "code": "NotFound", "key": "value"}},
+		},
+		{
+			name: "error passed to WithGroup logger",
+			log: func(logger *slog.Logger) {
+				logger.WithGroup("request").Error("failed", "error", err)
+			},
+			expectedRecord: map[string]any{"request": map[string]any{"error": "request: foo", "code": "NotFound", "key": "value"}},
+		},
+		{
+			name: "error passed to With",
+			log: func(logger *slog.Logger) {
+				logger.With("error", err).WithGroup("request").Error("failed", "other", 1)
+			},
+			expectedRecord: map[string]any{"error": "request: foo", "code": "NotFound", "key": "value", "request": map[string]any{"other": float64(1)}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tc.log(slog.New(NewHandler(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey || a.Key == slog.MessageKey) {
+						return slog.Attr{}
+					}
+					return a
+				},
+			}))))
+
+			var record map[string]any
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+			require.Equal(t, tc.expectedRecord, record)
+		})
+	}
+}
+
+func TestHandlerEnabled(t *testing.T) {
+	h := NewHandler(slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	require.False(t, h.Enabled(t.Context(), slog.LevelInfo))
+	require.True(t, h.Enabled(t.Context(), slog.LevelError))
+}
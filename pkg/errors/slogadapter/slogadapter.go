@@ -0,0 +1,167 @@
+// Package slogadapter provides helpers to log the errors of the errors package with log/slog.
+package slogadapter
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+
+	errhelper "github.com/qdrant/go-commons/pkg/errors"
+)
+
+const (
+	// errorKey is the attribute key holding the error message.
+	errorKey = "error"
+	// codeKey is the attribute key holding the name of the gRPC code of the error.
+	codeKey = "code"
+)
+
+// severityLevels maps the severities of errors to slog levels.
+var severityLevels = map[errhelper.Severity]slog.Level{
+	errhelper.SeverityDebug:    slog.LevelDebug,
+	errhelper.SeverityInfo:     slog.LevelInfo,
+	errhelper.SeverityWarning:  slog.LevelWarn,
+	errhelper.SeverityError:    slog.LevelError,
+	errhelper.SeverityCritical: slog.LevelError,
+}
+
+// defaultCodeLevels maps the gRPC codes of expected client errors to slog levels.
+// Errors with any other code are logged at slog.LevelError.
+var defaultCodeLevels = map[codes.Code]slog.Level{
+	codes.Canceled:           slog.LevelInfo,
+	codes.InvalidArgument:    slog.LevelInfo,
+	codes.NotFound:           slog.LevelInfo,
+	codes.AlreadyExists:      slog.LevelInfo,
+	codes.PermissionDenied:   slog.LevelInfo,
+	codes.FailedPrecondition: slog.LevelInfo,
+	codes.OutOfRange:         slog.LevelInfo,
+	codes.Unauthenticated:    slog.LevelInfo,
+}
+
+// Option configures LogError, Level and Attrs.
+type Option func(*options)
+
+type options struct {
+	codeLevels  map[codes.Code]slog.Level
+	keyPriority []string
+}
+
+func newOptions(opts []Option) options {
+	o := options{codeLevels: maps.Clone(defaultCodeLevels)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithCodeLevel sets the level used to log errors with the provided gRPC code,
+// when the error has no explicit severity. It overrides the default mapping,
+// which logs expected client errors, such as codes.NotFound, at slog.LevelInfo.
+func WithCodeLevel(code codes.Code, level slog.Level) Option {
+	return func(o *options) {
+		o.codeLevels[code] = level
+	}
+}
+
+// WithKeyPriority sets the metadata keys logged first, in the given order, before the other keys
+// sorted alphabetically, so the important dimensions, such as the tenant, are always up front.
+func WithKeyPriority(keys ...string) Option {
+	return func(o *options) {
+		o.keyPriority = keys
+	}
+}
+
+// LogError logs the error with the provided logger and message, with the attributes returned by Attrs.
+// The level is picked from the severity set with errhelper.WithSeverity, Critical and Error
+// being logged at slog.LevelError, Warning at slog.LevelWarn, and so on.
+// Errors without severity created with errhelper.Expected are logged at slog.LevelDebug.
+// Otherwise, the level is picked from the gRPC code of the error, expected client errors
+// being logged at slog.LevelInfo and others at slog.LevelError. Use WithCodeLevel to override it.
+// If the logger is nil, slog.Default is used. Nothing is logged for a nil error.
+func LogError(ctx context.Context, logger *slog.Logger, err error, msg string, opts ...Option) {
+	if err == nil {
+		return
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	level := Level(err, opts...)
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	logger.LogAttrs(ctx, level, msg, Attrs(err, opts...)...)
+}
+
+// Level returns the level LogError logs the error at.
+func Level(err error, opts ...Option) slog.Level {
+	if severity, ok := errhelper.SeverityOf(err); ok {
+		if level, ok := severityLevels[severity]; ok {
+			return level
+		}
+	}
+	if errhelper.IsExpected(err) {
+		return slog.LevelDebug
+	}
+	o := newOptions(opts)
+	if level, ok := o.codeLevels[errhelper.CodeOf(err)]; ok {
+		return level
+	}
+	return slog.LevelError
+}
+
+// Attrs returns the error message, the name of its gRPC code under the "code" key,
+// and the metadata of the error as attributes, sorted by key, the keys set with WithKeyPriority first.
+// The metadata is read with errhelper.LogMetadata, so error values are expanded under prefixed keys and
+// the values are redacted, like errhelper.Logfmt does, and maps with string keys are logged as groups.
+// Metadata keys colliding with the "error" and "code" attributes, or with a renamed key, are prefixed with
+// underscores until they are unique, e.g. "_code", like errhelper.Logfmt does, so no metadata is lost.
+// It returns no attributes for a nil error.
+func Attrs(err error, opts ...Option) []slog.Attr {
+	if err == nil {
+		return nil
+	}
+	metadata := errhelper.LogMetadata(err)
+	keyValues := make([]any, 0, len(metadata)*2)
+	written := map[string]struct{}{errorKey: {}, codeKey: {}}
+	for _, key := range slices.Sorted(maps.Keys(metadata)) {
+		name := key
+		for {
+			if _, ok := written[name]; !ok {
+				break
+			}
+			name = "_" + name
+		}
+		written[name] = struct{}{}
+		keyValues = append(keyValues, name, metadata[key])
+	}
+	keyValues = errhelper.SortMetadata(keyValues, newOptions(opts).keyPriority...)
+	attrs := make([]slog.Attr, 0, len(keyValues)/2+2)
+	attrs = append(attrs, slog.String(errorKey, err.Error()), slog.String(codeKey, errhelper.CodeName(err)))
+	for i := 0; i < len(keyValues); i += 2 {
+		attrs = append(attrs, slog.Attr{Key: keyValues[i].(string), Value: attrValue(keyValues[i+1])})
+	}
+	return attrs
+}
+
+// attrValue returns the value of a metadata attribute. Maps with string keys, such as the correlation IDs
+// set with errhelper.WithCorrelation, are logged as groups, sorted by key, nested maps being nested groups.
+func attrValue(v any) slog.Value {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String || rv.Len() == 0 {
+		return slog.AnyValue(v)
+	}
+	attrs := make([]slog.Attr, 0, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		attrs = append(attrs, slog.Attr{Key: iter.Key().String(), Value: attrValue(iter.Value().Interface())})
+	}
+	slices.SortFunc(attrs, func(a, b slog.Attr) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return slog.GroupValue(attrs...)
+}
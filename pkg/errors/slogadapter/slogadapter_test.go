@@ -0,0 +1,157 @@
+package slogadapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	errhelper "github.com/qdrant/go-commons/pkg/errors"
+)
+
+func TestLevel(t *testing.T) {
+	testCases := []struct {
+		name          string
+		err           error
+		opts          []Option
+		expectedLevel slog.Level
+	}{
+		{
+			name:          "plain error",
+			err:           errors.New("foo"),
+			expectedLevel: slog.LevelError,
+		},
+		{
+			name:          "critical",
+			err:           errhelper.WithSeverity(errors.New("foo"), errhelper.SeverityCritical),
+			expectedLevel: slog.LevelError,
+		},
+		{
+			name:          "warning",
+			err:           errhelper.WithSeverity(errors.New("foo"), errhelper.SeverityWarning),
+			expectedLevel: slog.LevelWarn,
+		},
+		{
+			name:          "expected client error",
+			err:           status.Error(codes.NotFound, "item not found"),
+			expectedLevel: slog.LevelInfo,
+		},
+		{
+			name:          "expected outcome",
+			err:           errhelper.Expected(codes.NotFound, "cache miss"),
+			expectedLevel: slog.LevelDebug,
+		},
+		{
+			name:          "severity wins over expected outcome",
+			err:           errhelper.WithSeverity(errhelper.Expected(codes.NotFound, "cache miss"), errhelper.SeverityWarning),
+			expectedLevel: slog.LevelWarn,
+		},
+		{
+			name:          "severity wins over code",
+			err:           errhelper.WithSeverity(status.Error(codes.NotFound, "item not found"), errhelper.SeverityCritical),
+			expectedLevel: slog.LevelError,
+		},
+		{
+			name:          "overridden code level",
+			err:           status.Error(codes.NotFound, "item not found"),
+			opts:          []Option{WithCodeLevel(codes.NotFound, slog.LevelWarn)},
+			expectedLevel: slog.LevelWarn,
+		},
+		{
+			name:          "added code level",
+			err:           errhelper.WithCode(errors.New("foo"), codes.Unavailable),
+			opts:          []Option{WithCodeLevel(codes.Unavailable, slog.LevelWarn)},
+			expectedLevel: slog.LevelWarn,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expectedLevel, Level(tc.err, tc.opts...))
+		})
+	}
+}
+
+func TestLogError(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	err := errhelper.WithMetadata(status.Error(codes.NotFound, "item not found"), "key", "value", "count", 2)
+	LogError(context.Background(), logger, err, "request failed")
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, "INFO", record["level"])
+	require.Equal(t, "request failed", record["msg"])
+	require.Equal(t, "rpc error: code = NotFound desc = item not found", record["error"])
+	require.Equal(t, "NotFound", record["code"])
+	require.Equal(t, "value", record["key"])
+	require.InDelta(t, 2, record["count"], 0)
+
+	// Nothing is logged for a nil error, or below the level of the logger.
+	buf.Reset()
+	LogError(context.Background(), logger, nil, "request failed")
+	LogError(context.Background(), logger, errhelper.WithSeverity(errors.New("foo"), errhelper.SeverityDebug), "request failed")
+	require.Empty(t, buf.String())
+}
+
+func TestAttrs(t *testing.T) {
+	require.Empty(t, Attrs(nil))
+
+	err := errhelper.WithMetadata(errors.New("foo"), "b", 1, "tenant", "t1", "a", 2, "operation", "search", "code", 42)
+	require.Equal(t, []slog.Attr{
+		slog.String("error", "foo"), slog.String("code", "Unknown"),
+		slog.Any("a", 2), slog.Any("b", 1), slog.Any("_code", 42), slog.Any("operation", "search"), slog.Any("tenant", "t1"),
+	}, Attrs(err))
+	require.Equal(t, []slog.Attr{
+		slog.String("error", "foo"), slog.String("code", "Unknown"),
+		slog.Any("tenant", "t1"), slog.Any("operation", "search"), slog.Any("a", 2), slog.Any("b", 1), slog.Any("_code", 42),
+	}, Attrs(err, WithKeyPriority("tenant", "operation")))
+
+	// Keys colliding with the attributes of the error, or with a renamed key, are renamed.
+	err = errhelper.WithMetadata(errors.New("foo"), "error", "e", "code", "c", "_code", "x")
+	require.Equal(t, []slog.Attr{
+		slog.String("error", "foo"), slog.String("code", "Unknown"),
+		slog.Any("_code", "x"), slog.Any("__code", "c"), slog.Any("_error", "e"),
+	}, Attrs(err))
+}
+
+func TestAttrsErrorValues(t *testing.T) {
+	cause := errhelper.WithMetadata(errors.New("bar"), "inner", 1)
+	err := errhelper.WithMetadata(errors.New("foo"), "cause", cause)
+	require.Equal(t, []slog.Attr{
+		slog.String("error", "foo"), slog.String("code", "Unknown"),
+		slog.Any("cause", "bar"), slog.Any("cause.inner", 1),
+	}, Attrs(err))
+
+	var buf bytes.Buffer
+	LogError(context.Background(), slog.New(slog.NewJSONHandler(&buf, nil)), err, "request failed")
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, "bar", record["cause"])
+	require.Equal(t, float64(1), record["cause.inner"])
+}
+
+func TestAttrsScopes(t *testing.T) {
+	err := errhelper.WithScopedMetadata(errors.New("foo"), errhelper.ScopeInternal, "query", "SELECT 1")
+	err = errhelper.WithScopedMetadata(err, errhelper.ScopeExternal, "hint", "check the name")
+	require.Equal(t, []slog.Attr{
+		slog.String("error", "foo"), slog.String("code", "Unknown"), slog.Any("query", "SELECT 1"),
+	}, Attrs(err))
+}
+
+func TestAttrsGroups(t *testing.T) {
+	err := errhelper.WithCorrelation(errors.New("foo"), map[string]string{"trace": "t1", "request": "r1"})
+	err = errhelper.WithMetadata(err, "nested", map[string]any{"b": map[string]int{"c": 1}, "a": true}, "empty", map[string]any{})
+	require.Equal(t, []slog.Attr{
+		slog.String("error", "foo"), slog.String("code", "Unknown"),
+		slog.Group("correlation", slog.String("request", "r1"), slog.String("trace", "t1")),
+		slog.Any("empty", map[string]any{}),
+		slog.Group("nested", slog.Bool("a", true), slog.Group("b", slog.Int("c", 1))),
+	}, Attrs(err))
+}
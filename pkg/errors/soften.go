@@ -0,0 +1,22 @@
+package errors
+
+// Soften swallows the error of a best effort operation, which must not fail the caller,
+// while ensuring its context is recorded: it calls logger with the error message under the "error" key,
+// the name of its gRPC code under the "code" key, followed by its metadata as returned by GetMetadata,
+// and returns nil, e.g.:
+//
+//	return errhelper.Soften(refreshCache(ctx), func(keyValues []any) {
+//		logger.Warn("cache refresh failed", keyValues...)
+//	})
+//
+// It returns nil without calling logger for a nil error. A nil logger drops the error.
+func Soften(err error, logger func(keyValues []any)) error {
+	if err == nil || logger == nil {
+		return nil
+	}
+	metadata := GetMetadata(err)
+	keyValues := make([]any, 0, len(metadata)+4)
+	keyValues = append(keyValues, "error", err.Error(), "code", CodeName(err))
+	logger(append(keyValues, metadata...))
+	return nil
+}
@@ -0,0 +1,25 @@
+package errors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestSoften(t *testing.T) {
+	var logged [][]any
+	logger := func(keyValues []any) {
+		logged = append(logged, keyValues)
+	}
+
+	require.NoError(t, Soften(nil, logger))
+	require.Empty(t, logged)
+
+	err := WithMetadata(WithCode(errors.New("refresh failed"), codes.Unavailable), "cache", "users")
+	require.NoError(t, Soften(err, logger))
+	require.Equal(t, [][]any{{"error", "refresh failed", "code", "Unavailable", "cache", "users"}}, logged)
+
+	require.NoError(t, Soften(errors.New("foo"), nil))
+}
@@ -0,0 +1,41 @@
+package errors
+
+import "slices"
+
+// SortMetadata returns a copy of the key value pairs with the priority keys first, in the given order,
+// followed by the other pairs in their original order. All the pairs of a key set multiple times are moved,
+// keeping their relative order, so the outermost value still wins. Keys that are not strings are never
+// prioritized. Like WithMetadata, a missing value for the last key is padded with "<missing>".
+// It's meant to render log lines with the important dimensions, such as the tenant, always up front.
+func SortMetadata(metadata []any, priority ...string) []any {
+	padded := addPaddingForMissingValue(metadata)
+	if len(priority) == 0 {
+		return padded
+	}
+	ranks := make(map[string]int, len(priority))
+	for i, key := range priority {
+		if _, ok := ranks[key]; !ok {
+			ranks[key] = i
+		}
+	}
+	rank := func(pair int) int {
+		if key, ok := padded[pair*2].(string); ok {
+			if r, ok := ranks[key]; ok {
+				return r
+			}
+		}
+		return len(priority)
+	}
+	pairs := make([]int, len(padded)/2)
+	for i := range pairs {
+		pairs[i] = i
+	}
+	slices.SortStableFunc(pairs, func(a, b int) int {
+		return rank(a) - rank(b)
+	})
+	sorted := make([]any, 0, len(padded))
+	for _, pair := range pairs {
+		sorted = append(sorted, padded[pair*2], padded[pair*2+1])
+	}
+	return sorted
+}
@@ -0,0 +1,69 @@
+package errors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortMetadata(t *testing.T) {
+	testCases := []struct {
+		name     string
+		metadata []any
+		priority []string
+		expected []any
+	}{
+		{
+			name:     "no metadata",
+			metadata: nil,
+			priority: []string{"tenant"},
+			expected: []any{},
+		},
+		{
+			name:     "no priority",
+			metadata: []any{"b", 1, "a", 2},
+			expected: []any{"b", 1, "a", 2},
+		},
+		{
+			name:     "priority keys first in the given order",
+			metadata: []any{"b", 1, "operation", "search", "a", 2, "tenant", "t1", "request_id", "r1"},
+			priority: []string{"request_id", "tenant", "operation"},
+			expected: []any{"request_id", "r1", "tenant", "t1", "operation", "search", "b", 1, "a", 2},
+		},
+		{
+			name:     "missing priority keys",
+			metadata: []any{"b", 1, "tenant", "t1"},
+			priority: []string{"request_id", "tenant"},
+			expected: []any{"tenant", "t1", "b", 1},
+		},
+		{
+			name:     "repeated key keeps its relative order",
+			metadata: []any{"tenant", "inner", "a", 1, "tenant", "outer"},
+			priority: []string{"tenant"},
+			expected: []any{"tenant", "inner", "tenant", "outer", "a", 1},
+		},
+		{
+			name:     "non-string keys",
+			metadata: []any{1, "one", "tenant", "t1"},
+			priority: []string{"tenant"},
+			expected: []any{"tenant", "t1", 1, "one"},
+		},
+		{
+			name:     "missing value",
+			metadata: []any{"a", 1, "tenant"},
+			priority: []string{"tenant"},
+			expected: []any{"tenant", "<missing>", "a", 1},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, SortMetadata(tc.metadata, tc.priority...))
+		})
+	}
+}
+
+func TestSortMetadataDoesNotModifyInput(t *testing.T) {
+	metadata := []any{"a", 1, "tenant", "t1"}
+	SortMetadata(metadata, "tenant")
+	require.Equal(t, []any{"a", 1, "tenant", "t1"}, metadata)
+}
@@ -0,0 +1,95 @@
+// Package sqlmap translates the errors returned by database/sql and its drivers into errors
+// of the errors package carrying the matching gRPC code.
+package sqlmap
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+
+	errhelper "github.com/qdrant/go-commons/pkg/errors"
+)
+
+// driverKey is the metadata key holding the type of the driver error.
+const driverKey = "sql_driver"
+
+// uniqueViolationState is the SQLSTATE of unique constraint violations.
+const uniqueViolationState = "23505"
+
+// uniqueViolationPatterns are the fragments of the messages of unique constraint violations,
+// lowercased, as reported by the common drivers of PostgreSQL, MySQL and SQLite.
+var uniqueViolationPatterns = []string{
+	"duplicate key value violates unique constraint",
+	"duplicate entry",
+	"unique constraint failed",
+	"sqlstate 23505",
+}
+
+// Wrap returns the error returned by database/sql or a driver wrapped with the matching gRPC code
+// and the provided metadata, along with the Go type of the innermost error under the "sql_driver" key,
+// e.g. "*pgconn.PgError", identifying the driver which returned it:
+//   - sql.ErrNoRows is mapped to codes.NotFound,
+//   - unique constraint violations, detected with the SQLState method of the driver error, if any,
+//     or the message of the error, are mapped to codes.AlreadyExists,
+//   - context.Canceled and context.DeadlineExceeded are mapped to codes.Canceled and codes.DeadlineExceeded,
+//   - other errors are mapped to codes.Internal, unless their chain already carries a code.
+//
+// It returns nil for a nil error.
+func Wrap(err error, keyValues ...any) error {
+	if err == nil {
+		return nil
+	}
+	metadata := make([]any, 0, len(keyValues)+2)
+	metadata = append(metadata, driverKey, fmt.Sprintf("%T", root(err)))
+	metadata = append(metadata, keyValues...)
+	if code, ok := codeOf(err); ok {
+		return errhelper.WithMetadata(errhelper.WithCode(err, code), metadata...)
+	}
+	return errhelper.WithMetadata(errhelper.WithCodeIfUnset(err, codes.Internal), metadata...)
+}
+
+// codeOf returns the code of the errors recognized by Wrap.
+func codeOf(err error) (codes.Code, bool) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return codes.NotFound, true
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled, true
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded, true
+	case isUniqueViolation(err):
+		return codes.AlreadyExists, true
+	default:
+		return codes.OK, false
+	}
+}
+
+// isUniqueViolation reports whether the error is a unique constraint violation.
+func isUniqueViolation(err error) bool {
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) {
+		return stateErr.SQLState() == uniqueViolationState
+	}
+	msg := strings.ToLower(err.Error())
+	for _, pattern := range uniqueViolationPatterns {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// root returns the innermost error of the chain.
+func root(err error) error {
+	for {
+		u := errors.Unwrap(err)
+		if u == nil {
+			return err
+		}
+		err = u
+	}
+}
@@ -0,0 +1,102 @@
+package sqlmap
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	errhelper "github.com/qdrant/go-commons/pkg/errors"
+)
+
+// pgError mimics the errors of PostgreSQL drivers, which report their SQLSTATE.
+type pgError struct {
+	code string
+}
+
+func (e *pgError) Error() string {
+	return "pg error " + e.code
+}
+
+func (e *pgError) SQLState() string {
+	return e.code
+}
+
+func TestWrap(t *testing.T) {
+	require.NoError(t, Wrap(nil, "table", "users"))
+
+	testCases := []struct {
+		name           string
+		err            error
+		expectedCode   codes.Code
+		expectedDriver string
+	}{
+		{
+			name:           "no rows",
+			err:            fmt.Errorf("get user: %w", sql.ErrNoRows),
+			expectedCode:   codes.NotFound,
+			expectedDriver: "*errors.errorString",
+		},
+		{
+			name:           "unique violation state",
+			err:            fmt.Errorf("insert: %w", &pgError{code: "23505"}),
+			expectedCode:   codes.AlreadyExists,
+			expectedDriver: "*sqlmap.pgError",
+		},
+		{
+			name:           "other state",
+			err:            &pgError{code: "23503"},
+			expectedCode:   codes.Internal,
+			expectedDriver: "*sqlmap.pgError",
+		},
+		{
+			name:           "MySQL duplicate entry",
+			err:            errors.New("Error 1062 (23000): Duplicate entry 'a' for key 'users.name'"),
+			expectedCode:   codes.AlreadyExists,
+			expectedDriver: "*errors.errorString",
+		},
+		{
+			name:           "SQLite unique constraint",
+			err:            errors.New("UNIQUE constraint failed: users.name"),
+			expectedCode:   codes.AlreadyExists,
+			expectedDriver: "*errors.errorString",
+		},
+		{
+			name:           "canceled",
+			err:            fmt.Errorf("query: %w", context.Canceled),
+			expectedCode:   codes.Canceled,
+			expectedDriver: "*errors.errorString",
+		},
+		{
+			name:           "deadline exceeded",
+			err:            context.DeadlineExceeded,
+			expectedCode:   codes.DeadlineExceeded,
+			expectedDriver: "context.deadlineExceededError",
+		},
+		{
+			name:           "unknown error",
+			err:            errors.New("connection reset"),
+			expectedCode:   codes.Internal,
+			expectedDriver: "*errors.errorString",
+		},
+		{
+			name:           "unknown error with code",
+			err:            status.Error(codes.Unavailable, "unavailable"),
+			expectedCode:   codes.Unavailable,
+			expectedDriver: "*status.Error",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Wrap(tc.err, "table", "users")
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.expectedCode, errhelper.CodeOf(err))
+			require.Equal(t, map[string]any{"sql_driver": tc.expectedDriver, "table": "users"}, errhelper.GetMetadataMap(err))
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// GRPCStatusOf returns the gRPC status of the error, as provided by the outermost error of the chain
+// able to provide one, including the metadata added by GRPCStatus, and whether the status is genuine.
+// The status is not genuine if the chain carries no code, neither set with WithCode nor coming from
+// a gRPC status, in which case it is synthesized with codes.Unknown.
+// For a nil error it returns a nil status and true, like status.FromError.
+func GRPCStatusOf(err error) (*status.Status, bool) {
+	if err == nil {
+		return nil, true
+	}
+	_, genuine := explicitCode(err)
+	for u := err; u != nil; u = errors.Unwrap(u) {
+		if s, ok := u.(interface{ GRPCStatus() *status.Status }); ok { // nolint: errorlint // errors.As should not be used here
+			if st := s.GRPCStatus(); st != nil {
+				return st, genuine
+			}
+		}
+	}
+	return status.New(codes.Unknown, err.Error()), false
+}
+
+// FromProtoStatus returns an error for the google.rpc.Status, e.g. received through a message queue
+// rather than a gRPC call, so GetMetadata, CodeOf and the other helpers work on it as on errors
+// returned by gRPC calls. The error carries the code and message of the status, as well as the metadata
+// found in its details, in the metadata structs added by GRPCStatus and in the metadata of ErrorInfo details.
+// The metadata structs win over ErrorInfo details for a key set in both, while ErrorInfo details win over
+// the global metadata. The details are kept,
+// so the error converts back to the same status, with the ErrorInfo metadata added to its metadata struct.
+// It returns nil for a nil status or a status with codes.OK.
+func FromProtoStatus(st *spb.Status) error {
+	err := status.FromProto(st).Err()
+	if err == nil {
+		return nil
+	}
+	var metadata []any
+	for _, detail := range status.Convert(err).Details() {
+		info, ok := detail.(*errdetails.ErrorInfo)
+		if !ok {
+			continue
+		}
+		for _, key := range slices.Sorted(maps.Keys(info.GetMetadata())) {
+			metadata = append(metadata, key, info.GetMetadata()[key])
+		}
+	}
+	if len(metadata) == 0 {
+		return err
+	}
+	// Keep the keys of the metadata structs, as the metadata we add would win over them.
+	existing := chainKeys(err)
+	var added []any
+	for i := 0; i < len(metadata); i += 2 {
+		if _, ok := existing[metadata[i].(string)]; !ok {
+			added = append(added, metadata[i], metadata[i+1])
+		}
+	}
+	return WithMetadata(err, added...)
+}
+
+// FromStatus returns an error carrying the status, e.g. handed by an interceptor, which GRPCStatus reports
+// exactly as provided, with all its details, including the metadata structs and foreign details, and without
+// the global metadata, so proxies can re-emit the statuses they receive unchanged:
+// GRPCStatusOf(FromStatus(st)) is equal to st. GetMetadata, CodeOf and the other helpers work on it
+// as on errors returned by gRPC calls. Wrapping it, e.g. with WithMetadata, builds a new status as usual.
+// It returns nil for a nil status or a status with codes.OK.
+func FromStatus(st *status.Status) error {
+	err := st.Err()
+	if err == nil {
+		return nil
+	}
+	return newErrWithMetadata(errWithMetadata{err: err, verbatim: true})
+}
+
+// AsErrorInfo returns the errdetails.ErrorInfo found in the details of the gRPC status of the error
+// (see GRPCStatusOf), to branch on its reason and domain through the metadata layers, whether the ErrorInfo
+// was attached to a local status or received from an upstream service. If there are several ErrorInfo details,
+// the first one is returned. errors.As can't be used for this purpose, as errdetails.ErrorInfo is not an error.
+func AsErrorInfo(err error) (*errdetails.ErrorInfo, bool) {
+	if err == nil {
+		return nil, false
+	}
+	st, _ := GRPCStatusOf(err)
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info, true
+		}
+	}
+	return nil, false
+}
+
+// AppendToStatus returns a copy of the status with the key value pairs merged into its metadata struct,
+// for gRPC-centric code which needs a *status.Status rather than an error wrapping it. The metadata already
+// carried by the status is kept, the provided pairs taking precedence for repeated keys, and values are
+// converted the same way as by GRPCStatus. The other details are preserved, as well as the metadata markers
+// of the existing metadata struct, a new one being added if the status carries none. The status itself is
+// not modified. It returns an error for a nil status or a status with codes.OK, which can't carry metadata,
+// and if a detail fails to be marshaled.
+func AppendToStatus(st *status.Status, keyValues ...any) (*status.Status, error) {
+	if st == nil {
+		return nil, errors.New("cannot append metadata to a nil status")
+	}
+	if st.Code() == codes.OK {
+		return nil, errors.New("cannot append metadata to a status with codes.OK")
+	}
+	added := addPaddingForMissingValue(flattenKeyValues(keyValues))
+	if len(added) == 0 {
+		return st, nil
+	}
+	metadata := slices.Concat(layerMetadata(st.Err()), expandErrorValues(added))
+	stProto := status.New(st.Code(), st.Message()).Proto()
+	var markers []string
+	for _, detail := range st.Proto().GetDetails() {
+		var s structpb.Struct
+		if detail.MessageIs(&s) {
+			if err := detail.UnmarshalTo(&s); err == nil && (isMetadataStruct(&s) || isTypeHintsStruct(&s)) {
+				for key := range s.GetFields() {
+					if isMetadataMarker(key) {
+						markers = append(markers, key)
+					}
+				}
+				continue
+			}
+		}
+		stProto.Details = append(stProto.Details, detail)
+	}
+	metadataStruct, typeHints := newMetadataStructs(dedupMetadata(metadata))
+	for _, marker := range markers {
+		metadataStruct.GetFields()[marker] = structpb.NewBoolValue(true)
+	}
+	structs := []proto.Message{metadataStruct}
+	if typeHints != nil {
+		structs = append(structs, typeHints)
+	}
+	for _, m := range structs {
+		anyRef, err := newAny(m)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal the metadata: %w", err)
+		}
+		stProto.Details = append(stProto.Details, anyRef)
+	}
+	return status.FromProto(stProto), nil
+}
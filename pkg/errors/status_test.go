@@ -0,0 +1,264 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestGRPCStatusOf(t *testing.T) {
+	grpcErr := status.Error(codes.NotFound, "item not found")
+
+	testCases := []struct {
+		name            string
+		err             error
+		expectedCode    codes.Code
+		expectedMessage string
+		expectedDetails int
+		expectedGenuine bool
+	}{
+		{
+			name:            "plain error",
+			err:             errors.New("foo"),
+			expectedCode:    codes.Unknown,
+			expectedMessage: "foo",
+		},
+		{
+			name:            "plain error wrapped with metadata",
+			err:             WithMetadata(errors.New("foo"), "key", "value"),
+			expectedCode:    codes.Unknown,
+			expectedMessage: "foo",
+			expectedDetails: 1,
+		},
+		{
+			name:            "plain error wrapped with code",
+			err:             WithCode(errors.New("foo"), codes.Internal),
+			expectedCode:    codes.Internal,
+			expectedMessage: "foo",
+			expectedGenuine: true,
+		},
+		{
+			name:            "gRPC status error",
+			err:             grpcErr,
+			expectedCode:    codes.NotFound,
+			expectedMessage: "item not found",
+			expectedGenuine: true,
+		},
+		{
+			name:            "gRPC status error wrapped with metadata and custom message",
+			err:             fmt.Errorf("foo: %w", WithMetadata(grpcErr, "key", "value")),
+			expectedCode:    codes.NotFound,
+			expectedMessage: "item not found",
+			expectedDetails: 1,
+			expectedGenuine: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			st, genuine := GRPCStatusOf(tc.err)
+			require.Equal(t, tc.expectedGenuine, genuine)
+			require.Equal(t, tc.expectedCode, st.Code())
+			require.Equal(t, tc.expectedMessage, st.Message())
+			require.Len(t, st.Details(), tc.expectedDetails)
+		})
+	}
+
+	st, genuine := GRPCStatusOf(nil)
+	require.Nil(t, st)
+	require.True(t, genuine)
+}
+
+func TestGRPCStatusOfMatchesGRPCStatus(t *testing.T) {
+	err := WithMetadata(status.Error(codes.NotFound, "item not found"), "key", "value")
+	st, _ := GRPCStatusOf(err)
+	expected, ok := status.FromError(err)
+	require.True(t, ok)
+	require.True(t, proto.Equal(expected.Proto(), st.Proto()))
+}
+
+func TestFromProtoStatus(t *testing.T) {
+	require.NoError(t, FromProtoStatus(nil))
+	require.NoError(t, FromProtoStatus(status.New(codes.OK, "").Proto()))
+
+	t.Run("round trip", func(t *testing.T) {
+		original := WithMetadata(WithCode(errors.New("item not found"), codes.NotFound), "key", "value", "count", 2)
+		st, ok := status.FromError(original)
+		require.True(t, ok)
+		data, err := proto.Marshal(st.Proto())
+		require.NoError(t, err)
+
+		var received spb.Status
+		require.NoError(t, proto.Unmarshal(data, &received))
+		roundTripped := FromProtoStatus(&received)
+		require.Equal(t, codes.NotFound, CodeOf(roundTripped))
+		require.Equal(t, "item not found", status.Convert(roundTripped).Message())
+		require.Equal(t, GetMetadataMap(original), GetMetadataMap(roundTripped))
+		require.True(t, proto.Equal(st.Proto(), status.Convert(roundTripped).Proto()))
+	})
+
+	t.Run("error info", func(t *testing.T) {
+		st, err := status.New(codes.Unavailable, "try later").WithDetails(&errdetails.ErrorInfo{
+			Reason:   "OVERLOADED",
+			Domain:   "qdrant.io",
+			Metadata: map[string]string{"shard": "3", "key": "from info"},
+		})
+		require.NoError(t, err)
+		withMetadata := status.Convert(WithMetadata(st.Err(), "key", "value"))
+
+		received := FromProtoStatus(withMetadata.Proto())
+		require.Equal(t, codes.Unavailable, CodeOf(received))
+		require.Equal(t, map[string]any{"shard": "3", "key": "value"}, GetMetadataMap(received))
+	})
+}
+
+func TestFromProtoStatusGlobals(t *testing.T) {
+	t.Cleanup(ResetGlobals)
+	SetKeyTransformer(strings.ToUpper)
+
+	st, err := status.New(codes.Unavailable, "try later").WithDetails(&errdetails.ErrorInfo{
+		Reason:   "OVERLOADED",
+		Metadata: map[string]string{"shard": "3", "key": "from info"},
+	})
+	require.NoError(t, err)
+	withMetadata := status.Convert(WithMetadata(st.Err(), "key", "value"))
+	// The global metadata of the receiver.
+	SetGlobalMetadata("shard", "global")
+
+	// Only the keys of the metadata structs are kept, compared before their transformation.
+	received := FromProtoStatus(withMetadata.Proto())
+	require.Equal(t, []any{"global", "3"}, GetMetadataValues(received, "shard"))
+	require.Equal(t, []any{"value"}, GetMetadataValues(received, "key"))
+}
+
+func TestAsErrorInfo(t *testing.T) {
+	info := &errdetails.ErrorInfo{Reason: "QUOTA_EXCEEDED", Domain: "qdrant.tech", Metadata: map[string]string{"limit": "10"}}
+	st, err := status.New(codes.ResourceExhausted, "quota exceeded").WithDetails(info)
+	require.NoError(t, err)
+
+	_, ok := AsErrorInfo(nil)
+	require.False(t, ok)
+	_, ok = AsErrorInfo(errors.New("foo"))
+	require.False(t, ok)
+	_, ok = AsErrorInfo(WithMetadata(status.Error(codes.NotFound, "not found"), "key", "value"))
+	require.False(t, ok)
+
+	testCases := []struct {
+		name string
+		err  error
+	}{
+		{
+			name: "plain status",
+			err:  st.Err(),
+		},
+		{
+			name: "local status through metadata layers",
+			err:  fmt.Errorf("foo: %w", WithMetadata(WithCode(st.Err(), codes.Unavailable), "key", "value")),
+		},
+		{
+			name: "received status",
+			err:  WithMetadata(roundTrip(t, WithMetadata(st.Err(), "key", "value")), "other", 1),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := AsErrorInfo(tc.err)
+			require.True(t, ok)
+			require.True(t, proto.Equal(info, got))
+		})
+	}
+}
+
+func TestAppendToStatus(t *testing.T) {
+	_, err := AppendToStatus(nil, "key", "value")
+	require.Error(t, err)
+	_, err = AppendToStatus(status.New(codes.OK, ""), "key", "value")
+	require.Error(t, err)
+
+	info := &errdetails.ErrorInfo{Reason: "NOT_FOUND", Domain: "qdrant.tech"}
+	st, err := status.New(codes.NotFound, "not found").WithDetails(info)
+	require.NoError(t, err)
+
+	// A new metadata struct is added.
+	appended, err := AppendToStatus(st, "collection", "a", "shard", 1)
+	require.NoError(t, err)
+	require.Len(t, st.Details(), 1)
+	require.Equal(t, codes.NotFound, appended.Code())
+	require.Equal(t, "not found", appended.Message())
+	require.True(t, proto.Equal(info, appended.Details()[0].(proto.Message)))
+	require.Equal(t, map[string]any{"collection": "a", "shard": 1}, GetMetadataMap(appended.Err()))
+
+	// The existing metadata struct is merged, without duplicating the details.
+	appended, err = AppendToStatus(appended, "collection", "b", "cause", errors.New("boom"))
+	require.NoError(t, err)
+	require.Len(t, appended.Details(), 3)
+	require.Equal(t, map[string]any{"collection": "b", "shard": 1, "cause": "boom"}, GetMetadataMap(appended.Err()))
+
+	// The statuses produced by GRPCStatus are merged the same way.
+	sent := status.Convert(WithMetadata(st.Err(), "request_id", "r1"))
+	appended, err = AppendToStatus(sent, "attempt", 2)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"request_id": "r1", "attempt": 2}, GetMetadataMap(appended.Err()))
+
+	unchanged, err := AppendToStatus(st)
+	require.NoError(t, err)
+	require.Same(t, st, unchanged)
+}
+
+func TestAppendToStatusMarkers(t *testing.T) {
+	RegisterMetadataMarker("__legacy_metadata__")
+	t.Cleanup(ClearMetadataMarkers)
+
+	legacy, err := structpb.NewStruct(map[string]any{"__legacy_metadata__": true, "key": "value"})
+	require.NoError(t, err)
+	st, err := status.New(codes.Internal, "internal").WithDetails(legacy)
+	require.NoError(t, err)
+
+	appended, err := AppendToStatus(st, "other", "x")
+	require.NoError(t, err)
+	require.Len(t, appended.Details(), 1)
+	fields := appended.Details()[0].(*structpb.Struct).GetFields()
+	require.Contains(t, fields, "__legacy_metadata__")
+	require.Contains(t, fields, qdrantMetadataMarker)
+	require.Equal(t, map[string]any{"key": "value", "other": "x"}, GetMetadataMap(appended.Err()))
+}
+
+func TestFromStatus(t *testing.T) {
+	require.NoError(t, FromStatus(nil))
+	require.NoError(t, FromStatus(status.New(codes.OK, "")))
+
+	RegisterMetadataMarker("__legacy_metadata__")
+	t.Cleanup(ClearMetadataMarkers)
+	SetGlobalMetadata("version", "1.0")
+	t.Cleanup(func() { SetGlobalMetadata() })
+
+	legacy, err := structpb.NewStruct(map[string]any{"__legacy_metadata__": true, "legacy": 1.5})
+	require.NoError(t, err)
+	info := &errdetails.ErrorInfo{Reason: "NOT_FOUND", Domain: "qdrant.tech", Metadata: map[string]string{"id": "42"}}
+	sent := status.Convert(WithMetadata(status.Error(codes.NotFound, "not found"), "collection", "a", "shard", 1))
+	st, err := sent.WithDetails(info, legacy, &errdetails.DebugInfo{Detail: "debug"})
+	require.NoError(t, err)
+
+	received := FromStatus(st)
+	got, genuine := GRPCStatusOf(received)
+	require.True(t, genuine)
+	require.True(t, proto.Equal(st.Proto(), got.Proto()))
+	got, _ = GRPCStatusOf(fmt.Errorf("proxy: %w", received))
+	require.True(t, proto.Equal(st.Proto(), got.Proto()))
+
+	require.Equal(t, codes.NotFound, CodeOf(received))
+	require.Equal(t, map[string]any{"version": "1.0", "collection": "a", "shard": 1, "legacy": 1.5}, GetMetadataMap(received))
+
+	// Wrapping it builds a new status.
+	wrapped := status.Convert(WithMetadata(received, "key", "value"))
+	require.Equal(t, "value", GetMetadataMap(wrapped.Err())["key"])
+	require.Equal(t, "1.0", GetMetadataMap(wrapped.Err())["version"])
+}
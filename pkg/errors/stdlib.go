@@ -0,0 +1,56 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+
+	"google.golang.org/grpc/codes"
+)
+
+// FromStdError returns the provided error wrapped with the gRPC code matching the well-known errors
+// of the standard library found in its chain, and with the provided metadata, to translate them at the boundary:
+//   - context.DeadlineExceeded and context.Canceled are mapped to codes.DeadlineExceeded and codes.Canceled,
+//   - fs.ErrNotExist, and thus os.ErrNotExist, to codes.NotFound, fs.ErrExist to codes.AlreadyExists
+//     and fs.ErrPermission to codes.PermissionDenied,
+//   - io.EOF and io.ErrUnexpectedEOF to codes.OutOfRange,
+//   - net.Error timeouts to codes.DeadlineExceeded, and other network errors to codes.Unavailable.
+//
+// Other errors keep their code, if any, or codes.Unknown. It returns nil for a nil error.
+func FromStdError(err error, keyValues ...any) error {
+	if err == nil {
+		return nil
+	}
+	if code, ok := stdErrorCode(err); ok {
+		err = WithCode(err, code)
+	}
+	return WithMetadata(err, keyValues...)
+}
+
+// stdErrorCode returns the code of the errors recognized by FromStdError.
+func stdErrorCode(err error) (codes.Code, bool) {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded, true
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled, true
+	case errors.Is(err, fs.ErrNotExist):
+		return codes.NotFound, true
+	case errors.Is(err, fs.ErrExist):
+		return codes.AlreadyExists, true
+	case errors.Is(err, fs.ErrPermission):
+		return codes.PermissionDenied, true
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return codes.OutOfRange, true
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return codes.DeadlineExceeded, true
+		}
+		return codes.Unavailable, true
+	default:
+		return codes.Unknown, false
+	}
+}
@@ -0,0 +1,53 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// timeoutError is a net.Error reporting a timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestFromStdError(t *testing.T) {
+	require.NoError(t, FromStdError(nil, "key", "value"))
+
+	_, statErr := os.Stat("/does/not/exist")
+	testCases := []struct {
+		name         string
+		err          error
+		expectedCode codes.Code
+	}{
+		{name: "not exist", err: statErr, expectedCode: codes.NotFound},
+		{name: "exist", err: fmt.Errorf("create: %w", os.ErrExist), expectedCode: codes.AlreadyExists},
+		{name: "permission", err: &os.PathError{Op: "open", Path: "/root", Err: os.ErrPermission}, expectedCode: codes.PermissionDenied},
+		{name: "EOF", err: fmt.Errorf("read: %w", io.EOF), expectedCode: codes.OutOfRange},
+		{name: "unexpected EOF", err: io.ErrUnexpectedEOF, expectedCode: codes.OutOfRange},
+		{name: "deadline exceeded", err: fmt.Errorf("call: %w", context.DeadlineExceeded), expectedCode: codes.DeadlineExceeded},
+		{name: "canceled", err: context.Canceled, expectedCode: codes.Canceled},
+		{name: "net timeout", err: &net.OpError{Op: "dial", Net: "tcp", Err: timeoutError{}}, expectedCode: codes.DeadlineExceeded},
+		{name: "net error", err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, expectedCode: codes.Unavailable},
+		{name: "unknown error", err: errors.New("foo"), expectedCode: codes.Unknown},
+		{name: "unknown error with code", err: status.Error(codes.Internal, "internal"), expectedCode: codes.Internal},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := FromStdError(tc.err, "key", "value")
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.expectedCode, CodeOf(err))
+			require.Equal(t, map[string]any{"key": "value"}, GetMetadataMap(err))
+		})
+	}
+}
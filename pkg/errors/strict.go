@@ -0,0 +1,61 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrMalformedMetadata is reported in strict mode, see SetStrict, when an error is wrapped with malformed metadata.
+var ErrMalformedMetadata = errors.New("malformed metadata")
+
+// strict holds the mode set with SetStrict.
+var strict atomic.Bool
+
+// SetStrict enables or disables the strict mode, disabled by default, meant for CI builds and tests.
+// In strict mode, WithMetadata, and all the helpers built on it, don't silently fix malformed metadata:
+// a missing value, a key that is not a string, or a key colliding with a metadata marker (see RegisterMetadataMarker),
+// return an error matching ErrMalformedMetadata with errors.Is and wrapping the provided error, which keeps
+// its code and metadata, instead of padding the value or dropping the key. It never panics.
+// Running services should keep the lenient default.
+//
+// It is part of the global configuration, see the package documentation.
+func SetStrict(enabled bool) {
+	strict.Store(enabled)
+}
+
+// malformedMetadataError is the error returned in strict mode for malformed metadata.
+type malformedMetadataError struct {
+	reason string
+	err    error
+}
+
+func (e *malformedMetadataError) Error() string {
+	return ErrMalformedMetadata.Error() + ": " + e.reason + ": " + e.err.Error()
+}
+
+func (e *malformedMetadataError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether the target is ErrMalformedMetadata.
+func (e *malformedMetadataError) Is(target error) bool {
+	return target == ErrMalformedMetadata // nolint: errorlint // errors.Is should not be used here
+}
+
+// checkMetadata returns the reason why the flattened key value pairs are malformed, or an empty string.
+func checkMetadata(keyValues []any) string {
+	if len(keyValues)%2 != 0 {
+		return fmt.Sprintf("missing value for key %v", keyValues[len(keyValues)-1])
+	}
+	for i := 0; i < len(keyValues); i += 2 {
+		key, ok := keyValues[i].(string)
+		if !ok {
+			return fmt.Sprintf("key %v of type %T is not a string", keyValues[i], keyValues[i])
+		}
+		if isMetadataMarker(key) {
+			return fmt.Sprintf("key %q is reserved", key)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package errors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestSetStrict(t *testing.T) {
+	base := WithMetadata(WithCode(errors.New("foo"), codes.NotFound), "inner", 1)
+
+	// Lenient by default.
+	require.Equal(t, []any{"inner", 1, "key", "<missing>"}, GetMetadata(WithMetadata(base, "key")))
+
+	SetStrict(true)
+	t.Cleanup(func() { SetStrict(false) })
+
+	testCases := []struct {
+		name      string
+		keyValues []any
+		expected  string
+	}{
+		{
+			name:      "missing value",
+			keyValues: []any{"key", "value", "other"},
+			expected:  "malformed metadata: missing value for key other: foo",
+		},
+		{
+			name:      "key not a string",
+			keyValues: []any{42, "value"},
+			expected:  "malformed metadata: key 42 of type int is not a string: foo",
+		},
+		{
+			name:      "reserved marker",
+			keyValues: []any{qdrantMetadataMarker, true},
+			expected:  `malformed metadata: key "__qdrant_metadata__" is reserved: foo`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := WithMetadata(base, tc.keyValues...)
+			require.EqualError(t, err, tc.expected)
+			require.ErrorIs(t, err, ErrMalformedMetadata)
+			require.ErrorIs(t, err, base)
+			require.Equal(t, codes.NotFound, CodeOf(err))
+			require.Equal(t, []any{"inner", 1}, GetMetadata(err))
+		})
+	}
+
+	// Well-formed metadata is attached as usual.
+	err := WithMetadata(base, Metadata{"key", "value"})
+	require.NotErrorIs(t, err, ErrMalformedMetadata)
+	require.Equal(t, []any{"inner", 1, "key", "value"}, GetMetadata(err))
+}
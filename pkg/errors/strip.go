@@ -0,0 +1,51 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// fmtWrapErrorType is the type of the errors returned by fmt.Errorf with a single %w verb.
+var fmtWrapErrorType = reflect.TypeOf(fmt.Errorf("%w", errors.New("")))
+
+// StripMessages returns the error without the layers that only add a message, that is the errors
+// created by fmt.Errorf with a single %w verb and by Wrapf, so that its message reverts to the message
+// of the root of the chain, e.g. for a clean re-wrap.
+// The layers created by this package are preserved, with their metadata and codes, so GetMetadata
+// and CodeOf are unchanged. The stripping stops at the first error that is neither a message layer
+// nor one of our layers, which is kept with everything it wraps.
+// If there is no message layer to strip, the error is returned unchanged.
+func StripMessages(err error) error {
+	var layers []*errWithMetadata
+	stripped := false
+	base := err
+	for base != nil {
+		if e, isOurType := base.(*errWithMetadata); isOurType { // nolint: errorlint // errors.As should not be used here
+			layers = append(layers, e)
+		} else if isMessageLayer(base) {
+			stripped = true
+		} else {
+			break
+		}
+		base = errors.Unwrap(base)
+	}
+	if !stripped {
+		return err
+	}
+	// Rebuild our layers on top of the base, from the innermost one.
+	for i := len(layers) - 1; i >= 0; i-- {
+		layer := *layers[i]
+		layer.err = base
+		base = &layer
+	}
+	return base
+}
+
+// isMessageLayer reports whether the error only adds a message to the error it wraps.
+func isMessageLayer(err error) bool {
+	if _, ok := err.(*wrapfError); ok { // nolint: errorlint // errors.As should not be used here
+		return true
+	}
+	return reflect.TypeOf(err) == fmtWrapErrorType
+}
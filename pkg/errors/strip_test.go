@@ -0,0 +1,70 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStripMessages(t *testing.T) {
+	rootError := errors.New("root")
+
+	testCases := []struct {
+		name            string
+		err             error
+		expectedMessage string
+	}{
+		{
+			name:            "message layer on top",
+			err:             fmt.Errorf("foo: %w", WithMetadata(rootError, "k1", "v1")),
+			expectedMessage: "root",
+		},
+		{
+			name:            "message layer between metadata layers",
+			err:             WithMetadata(fmt.Errorf("foo: %w", WithMetadata(rootError, "k1", "v1")), "k2", "v2"),
+			expectedMessage: "root",
+		},
+		{
+			name:            "Wrapf and code",
+			err:             Wrapf("bar: %w", WithCode(fmt.Errorf("foo: %w", WithMetadata(rootError, "k1", "v1")), codes.NotFound)),
+			expectedMessage: "root",
+		},
+		{
+			name:            "status root",
+			err:             WithMetadata(fmt.Errorf("foo: %w", status.Error(codes.Internal, "boom")), "k1", "v1"),
+			expectedMessage: "rpc error: code = Internal desc = boom",
+		},
+		{
+			name:            "multi-error is kept",
+			err:             fmt.Errorf("foo: %w", errors.Join(rootError, errors.New("other"))),
+			expectedMessage: "root\nother",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stripped := StripMessages(tc.err)
+			require.EqualError(t, stripped, tc.expectedMessage)
+			require.Equal(t, GetMetadata(tc.err), GetMetadata(stripped))
+			require.Equal(t, CodeOf(tc.err), CodeOf(stripped))
+		})
+	}
+}
+
+func TestStripMessagesUnchanged(t *testing.T) {
+	require.NoError(t, StripMessages(nil))
+
+	rootError := errors.New("root")
+	require.Same(t, rootError, StripMessages(rootError))
+
+	err := WithMetadata(WithMetadata(rootError, "k1", "v1"), "k2", "v2")
+	require.Same(t, err, StripMessages(err))
+
+	// The original error is not modified.
+	err = WithMetadata(fmt.Errorf("foo: %w", rootError), "k1", "v1")
+	require.EqualError(t, StripMessages(err), "root")
+	require.EqualError(t, err, "foo: root")
+}
@@ -0,0 +1,37 @@
+package errors
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// metaTag is the struct tag setting the metadata keys of the fields of structs passed to WithMetadata.
+const metaTag = "meta"
+
+// appendStructFields appends the exported fields of the struct as key value pairs, following their "meta" tags:
+// the tag sets the key, the field name being used if it's empty or missing, "-" skips the field,
+// and the "omitempty" option skips the field if it has its zero value.
+func appendStructFields(flattened []any, v reflect.Value) []any {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get(metaTag)
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		value := v.Field(i)
+		if slices.Contains(strings.Split(options, ","), "omitempty") && value.IsZero() {
+			continue
+		}
+		flattened = append(flattened, name, value.Interface())
+	}
+	return flattened
+}
@@ -0,0 +1,78 @@
+package errors
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type Tracing struct {
+	Trace string
+}
+
+type requestContext struct {
+	Tracing
+	Collection string `meta:"collection"`
+	Shard      int    `meta:"shard,omitempty"`
+	Token      string `meta:"-"`
+	Region     string
+	Labels     map[string]string `meta:",omitempty"`
+	tenant     string
+}
+
+func TestWithMetadataStruct(t *testing.T) {
+	base := errors.New("foo")
+	rc := requestContext{
+		Tracing:    Tracing{Trace: "t1"},
+		Collection: "books",
+		Token:      "secret",
+		Region:     "eu",
+		tenant:     "t",
+	}
+
+	testCases := []struct {
+		name      string
+		keyValues []any
+		expected  []any
+	}{
+		{
+			name:      "struct",
+			keyValues: []any{rc},
+			expected:  []any{"Tracing", Tracing{Trace: "t1"}, "collection", "books", "Region", "eu"},
+		},
+		{
+			name:      "pointer to struct with other pairs",
+			keyValues: []any{"key", "value", &requestContext{Collection: "a", Shard: 2, Labels: map[string]string{"k": "v"}}},
+			expected: []any{
+				"key", "value", "Tracing", Tracing{}, "collection", "a", "shard", 2,
+				"Region", "", "Labels", map[string]string{"k": "v"},
+			},
+		},
+		{
+			name:      "struct as a value",
+			keyValues: []any{"context", rc},
+			expected:  []any{"context", rc},
+		},
+		{
+			name:      "field",
+			keyValues: []any{Int("shard", 1), Str("collection", "a")},
+			expected:  []any{"shard", 1, "collection", "a"},
+		},
+		{
+			name:      "nil pointer",
+			keyValues: []any{(*requestContext)(nil), "value"},
+			expected:  []any{(*requestContext)(nil), "value"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, GetMetadata(WithMetadata(base, tc.keyValues...)))
+		})
+	}
+
+	// Errors are never expanded.
+	opErr := &net.OpError{Op: "dial"}
+	require.Equal(t, []any{opErr, "<missing>"}, GetMetadata(WithMetadata(base, opErr)))
+}
@@ -0,0 +1,34 @@
+package errors
+
+import "fmt"
+
+// Summary returns a short and stable summary of the error for alert titles: the name of its gRPC code
+// (see CodeName) and the type of its root error, e.g. "NotFound: *errors.errorString" or "Internal: *net.OpError".
+// The message and the metadata are deliberately excluded, as they often hold unique values,
+// so related failures share the same summary. The root error is the first error of the chain that is
+// neither a metadata layer nor a message layer added by fmt.Errorf or Wrapf, so errors wrapping
+// another one for a reason of their own, such as *net.OpError, are kept.
+// It returns an empty string for a nil error.
+func Summary(err error) string {
+	if err == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s: %T", CodeName(err), rootError(err))
+}
+
+// rootError returns the root error of the chain, as defined by Summary.
+func rootError(err error) error {
+	root := err
+	for {
+		var next error
+		if e, ok := root.(*errWithMetadata); ok { // nolint: errorlint // errors.As should not be used here
+			next = e.err
+		} else if isMessageLayer(root) {
+			next = root.(interface{ Unwrap() error }).Unwrap() // nolint: errorlint // errors.As should not be used here
+		}
+		if next == nil {
+			return root
+		}
+		root = next
+	}
+}
@@ -0,0 +1,56 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSummary(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "nil error",
+			err:      nil,
+			expected: "",
+		},
+		{
+			name:     "plain error",
+			err:      errors.New("foo"),
+			expected: "Unknown: *errors.errorString",
+		},
+		{
+			name:     "error with code and metadata",
+			err:      fmt.Errorf("lookup %d: %w", 42, WithMetadata(WithCode(errors.New("item not found"), codes.NotFound), "id", 42)),
+			expected: "NotFound: *errors.errorString",
+		},
+		{
+			name:     "network error",
+			err:      WithCode(&net.OpError{Op: "dial", Err: errors.New("connection refused")}, codes.Internal),
+			expected: "Internal: *net.OpError",
+		},
+		{
+			name:     "gRPC status error",
+			err:      WithMetadata(status.Error(codes.Unavailable, "try later"), "key", "value"),
+			expected: "Unavailable: *status.Error",
+		},
+		{
+			name:     "joined errors",
+			err:      WithCode(errors.Join(errors.New("foo"), errors.New("bar")), codes.Aborted),
+			expected: "Aborted: *errors.joinError",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, Summary(tc.err))
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package errors
+
+import (
+	"errors"
+	"maps"
+	"slices"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Synthesize returns an error equivalent to the one a log entry was produced from, to reproduce incidents
+// in regression tests: its message is the provided one, its code (see CodeOf) the provided one, and its metadata,
+// as returned by GetMetadataMap, the provided map, e.g. pasted from logs:
+//
+//	err := errhelper.Synthesize(codes.NotFound, "collection not found", map[string]any{"collection": "books"})
+//
+// The global metadata, if any, is added with the lowest precedence, as to any other error.
+// The metadata is attached sorted by key, so GetMetadata is deterministic, and the map is not retained.
+// Note that the values are taken as is, e.g. numbers parsed from JSON logs are float64, unless converted first.
+func Synthesize(code codes.Code, message string, metadata map[string]any) error {
+	keyValues := make([]any, 0, len(metadata)*2)
+	for _, key := range slices.Sorted(maps.Keys(metadata)) {
+		keyValues = append(keyValues, key, metadata[key])
+	}
+	return WithMetadata(WithCode(errors.New(message), code), keyValues...)
+}
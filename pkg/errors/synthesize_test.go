@@ -0,0 +1,34 @@
+package errors
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSynthesize(t *testing.T) {
+	original := WithMetadata(status.Error(codes.NotFound, "collection not found"), "collection", "books", "shard", 3, "tags", []string{"a"})
+
+	var logged map[string]any
+	data, err := json.Marshal(GetMetadataMap(original))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &logged))
+
+	synthesized := Synthesize(CodeOf(original), "collection not found", logged)
+	require.EqualError(t, synthesized, "collection not found")
+	require.Equal(t, codes.NotFound, CodeOf(synthesized))
+	require.Equal(t, logged, GetMetadataMap(synthesized))
+	require.Equal(t, []any{"collection", "books", "shard", float64(3), "tags", []any{"a"}}, GetMetadata(synthesized))
+
+	st, ok := status.FromError(synthesized)
+	require.True(t, ok)
+	require.Equal(t, codes.NotFound, st.Code())
+	require.Equal(t, "collection not found", st.Message())
+
+	synthesized = Synthesize(codes.Internal, "foo", nil)
+	require.Equal(t, codes.Internal, CodeOf(synthesized))
+	require.Empty(t, GetMetadata(synthesized))
+}
@@ -0,0 +1,97 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+)
+
+// maxTreeDepth limits the depth of the tree rendered by ToTreeJSON.
+const maxTreeDepth = 100
+
+// treeNode is a node of the JSON tree rendered by ToTreeJSON.
+type treeNode struct {
+	Message  string         `json:"message"`
+	Type     string         `json:"type"`
+	Code     string         `json:"code,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+	Causes   []*treeNode    `json:"causes,omitempty"`
+	// Repeated is set for an error already rendered in another branch, rendered without its causes.
+	Repeated bool `json:"repeated,omitempty"`
+	// Truncated is set for an error too deep in the tree, rendered without its causes.
+	Truncated bool `json:"truncated,omitempty"`
+	// Sequence is the creation order of the layer, see SequenceLayers.
+	Sequence uint64 `json:"sequence,omitempty"`
+}
+
+// ToTreeJSON renders the whole error tree as JSON, for debug endpoints and tools displaying errors to humans.
+// Each error of the tree is a node with its message, Go type, the name of the gRPC code it sets, if any,
+// either with WithCode or as a gRPC status, the metadata attached to it alone, and the errors it wraps
+// under "causes", a single one for Unwrap() error and all the branches for Unwrap() []error, e.g.:
+//
+//	{"message":"lookup: not found","type":"*fmt.wrapError","causes":[
+//		{"message":"not found","type":"*errors.errWithMetadata","code":"NotFound","metadata":{"id":42},"causes":[...]}
+//	]}
+//
+// Metadata values are rendered as they are sent over gRPC, keys being sorted, and the layers with their
+// sequence number if the SequenceLayers option was set when they were created. An error reachable from several
+// branches, or wrapping itself, is rendered in full the first time only, and marked as repeated afterward,
+// and the depth of the tree is limited, so the output is deterministic and finite for any tree.
+// It returns "null" for a nil error.
+func ToTreeJSON(err error) ([]byte, error) {
+	if err == nil {
+		return json.Marshal(nil)
+	}
+	visited := make(visitedErrors)
+	return json.Marshal(newTreeNode(err, &visited, 0))
+}
+
+func newTreeNode(err error, visited *visitedErrors, depth int) *treeNode {
+	node := &treeNode{Message: err.Error(), Type: fmt.Sprintf("%T", err)}
+	if code, ok := layerCode(err); ok {
+		node.Code = code.String()
+	}
+	if e, ok := err.(*errWithMetadata); ok { // nolint: errorlint // errors.As should not be used here
+		node.Sequence = e.sequence
+	}
+	if !visited.visit(err) {
+		node.Repeated = true
+		return node
+	}
+	if md := dedupMetadata(expandErrorValues(resolveLazyValues(layerMetadata(err)))); len(md) > 0 {
+		node.Metadata = make(map[string]any, len(md))
+		for key, value := range md {
+			node.Metadata[key] = toWireValue(value).AsInterface()
+		}
+	}
+	var causes []error
+	switch x := err.(type) { // nolint: errorlint // errors.As should not be used here
+	case interface{ Unwrap() []error }:
+		causes = x.Unwrap()
+	case interface{ Unwrap() error }:
+		causes = []error{x.Unwrap()}
+	}
+	for _, cause := range causes {
+		if cause == nil {
+			continue
+		}
+		if depth+1 >= maxTreeDepth {
+			node.Truncated = true
+			break
+		}
+		node.Causes = append(node.Causes, newTreeNode(cause, visited, depth+1))
+	}
+	return node
+}
+
+// layerCode returns the code set by the error itself, without looking at the errors it wraps.
+func layerCode(err error) (codes.Code, bool) {
+	if e, ok := err.(*errWithMetadata); ok { // nolint: errorlint // errors.As should not be used here
+		return e.code, e.hasCode
+	}
+	if st, ok := foreignStatus(err); ok {
+		return st.Code(), true
+	}
+	return codes.Unknown, false
+}
@@ -0,0 +1,77 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestToTreeJSON(t *testing.T) {
+	data, err := ToTreeJSON(nil)
+	require.NoError(t, err)
+	require.JSONEq(t, `null`, string(data))
+
+	shared := WithMetadata(errors.New("shared"), "id", 42)
+	tree := fmt.Errorf("batch: %w", WithCode(errors.Join(
+		WithMetadata(fmt.Errorf("first: %w", shared), "branch", 1, "cause", errors.New("bar")),
+		fmt.Errorf("second: %w", shared),
+	), codes.Aborted))
+
+	data, err = ToTreeJSON(tree)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"message": "batch: first: shared\nsecond: shared", "type": "*fmt.wrapError",
+		"causes": [{
+			"message": "first: shared\nsecond: shared", "type": "*errors.errWithMetadata", "code": "Aborted",
+			"causes": [{
+				"message": "first: shared\nsecond: shared", "type": "*errors.joinError",
+				"causes": [
+					{
+						"message": "first: shared", "type": "*errors.errWithMetadata",
+						"metadata": {"branch": 1, "cause": "bar"},
+						"causes": [{
+							"message": "first: shared", "type": "*fmt.wrapError",
+							"causes": [{
+								"message": "shared", "type": "*errors.errWithMetadata",
+								"metadata": {"id": 42},
+								"causes": [{"message": "shared", "type": "*errors.errorString"}]
+							}]
+						}]
+					},
+					{
+						"message": "second: shared", "type": "*fmt.wrapError",
+						"causes": [{"message": "shared", "type": "*errors.errWithMetadata", "repeated": true}]
+					}
+				]
+			}]
+		}]
+	}`, string(data))
+
+	again, err := ToTreeJSON(tree)
+	require.NoError(t, err)
+	require.Equal(t, data, again)
+}
+
+// loopError wraps itself, as a buggy Unwrap implementation could.
+type loopError struct{}
+
+func (e *loopError) Error() string {
+	return "loop"
+}
+
+func (e *loopError) Unwrap() error {
+	return e
+}
+
+func TestToTreeJSONCycle(t *testing.T) {
+	data, err := ToTreeJSON(fmt.Errorf("foo: %w", &loopError{}))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"message": "foo: loop", "type": "*fmt.wrapError", "causes": [
+		{"message": "loop", "type": "*errors.loopError", "causes": [
+			{"message": "loop", "type": "*errors.loopError", "repeated": true}
+		]}
+	]}`, string(data))
+}
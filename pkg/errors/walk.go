@@ -0,0 +1,136 @@
+package errors
+
+import "reflect"
+
+// visitedErrors holds the errors of a tree already traversed. It's only needed, and allocated,
+// once a multi-error is found, as a chain can't reach the same error twice.
+type visitedErrors map[error]struct{}
+
+// appendLayers appends the metadata of the tree rooted at err to the layers, in post-order:
+// the metadata of the errors wrapped by an error comes before its own, and the branches
+// of a multi-error are collected in order, so that the later branches take precedence.
+// Both Unwrap() error and Unwrap() []error are followed.
+// An error reachable from multiple branches, such as the root of a diamond where the same error
+// is wrapped by two branches that are then joined, is collected only once, the first time it's reached,
+// so that its metadata is not counted twice and comes before the metadata of all of its wrappers.
+// The lazy values (see lazyValue) are resolved, so every reader built on it sees their current value.
+func appendLayers(layers [][]any, err error, visited *visitedErrors) [][]any {
+	return appendScopedLayers(layers, err, visited, ScopeBoth)
+}
+
+// appendScopedLayers is like appendLayers, only collecting the metadata in the scope (see Scope.includes).
+// Chains are walked iteratively, so their length doesn't grow the stack: it only recurses into the branches
+// of multi-errors and the trees wrapped by WithPrefixedMetadata.
+func appendScopedLayers(layers [][]any, err error, visited *visitedErrors, scope Scope) [][]any {
+	// Collect the chain down to its root, a multi-error or a prefixing layer, then walk it back up.
+	var buf [16]error
+	chain := buf[:0]
+	for err != nil && visited.visit(err) {
+		chain = append(chain, err)
+		if e, ok := err.(*errWithMetadata); ok && e.keyPrefix != "" { // nolint: errorlint // errors.As should not be used here
+			break
+		}
+		x, ok := err.(interface{ Unwrap() error }) // nolint: errorlint // errors.As should not be used here
+		if !ok {
+			break
+		}
+		err = x.Unwrap()
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		err := chain[i]
+		if e, ok := err.(*errWithMetadata); ok && e.keyPrefix != "" { // nolint: errorlint // errors.As should not be used here
+			// The metadata of the wrapped tree is collected apart, to be prefixed.
+			for _, md := range appendScopedLayers(nil, e.err, visited, scope) {
+				layers = append(layers, prefixKeys(md, e.keyPrefix))
+			}
+			continue
+		}
+		if x, ok := err.(interface{ Unwrap() []error }); ok { // nolint: errorlint // errors.As should not be used here
+			if *visited == nil {
+				*visited = make(visitedErrors)
+				visited.visit(err)
+			}
+			for _, branch := range x.Unwrap() {
+				layers = appendScopedLayers(layers, branch, visited, scope)
+			}
+		}
+		if md := layerMetadata(err); len(md) > 0 && scope.includes(layerScope(err)) {
+			layers = append(layers, resolveLazyValues(md))
+		}
+	}
+	return layers
+}
+
+// chainKeys returns the set of the string metadata keys of the error tree, the global metadata excluded.
+func chainKeys(err error) map[string]struct{} {
+	keys := make(map[string]struct{})
+	var visited visitedErrors
+	for _, layer := range appendLayers(nil, err, &visited) {
+		for i := 0; i < len(layer); i += 2 {
+			if key, ok := layer[i].(string); ok {
+				keys[key] = struct{}{}
+			}
+		}
+	}
+	return keys
+}
+
+// visit marks the error as visited, and reports whether it wasn't visited before.
+// Errors that are not pointers have no identity, and are always visited.
+func (v *visitedErrors) visit(err error) bool {
+	if *v == nil || reflect.ValueOf(err).Kind() != reflect.Pointer {
+		return true
+	}
+	if _, ok := (*v)[err]; ok {
+		return false
+	}
+	(*v)[err] = struct{}{}
+	return true
+}
+
+// maxPresenceDepth limits the nesting of the multi-errors searched by HasMetadata, the only recursion of the search.
+// Chains are walked iteratively, whatever their length, like GetMetadata does.
+const maxPresenceDepth = 100
+
+// HasMetadata reports whether the error tree carries any metadata, attached with WithMetadata or received
+// in gRPC status details, e.g. to only take an expensive logging path when needed. The global metadata is ignored,
+// as well as layers without metadata, such as the ones added by WithCode, and metadata structs only holding
+// their marker. Unlike GetMetadata, it stops at the first metadata found and allocates nothing,
+// unless it has to decode the details of a gRPC status or to track the errors shared by the branches
+// of multi-errors, which are searched once.
+func HasMetadata(err error) bool {
+	var visited visitedErrors
+	return hasMetadata(err, &visited, 0)
+}
+
+func hasMetadata(err error, visited *visitedErrors, depth int) bool {
+	for err != nil {
+		if !visited.visit(err) {
+			return false
+		}
+		if len(layerMetadata(err)) > 0 {
+			return true
+		}
+		switch x := err.(type) { // nolint: errorlint // errors.As should not be used here
+		case interface{ Unwrap() []error }:
+			if depth >= maxPresenceDepth {
+				return false
+			}
+			if *visited == nil {
+				*visited = make(visitedErrors)
+				visited.visit(err)
+			}
+			for _, branch := range x.Unwrap() {
+				if hasMetadata(branch, visited, depth+1) {
+					return true
+				}
+			}
+			return false
+		case interface{ Unwrap() error }:
+			err = x.Unwrap()
+		default:
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestHasMetadata(t *testing.T) {
+	SetGlobalMetadata("version", "1.0")
+	t.Cleanup(func() { SetGlobalMetadata() })
+
+	markerOnly, err := structpb.NewStruct(map[string]any{qdrantMetadataMarker: true})
+	require.NoError(t, err)
+	markerOnlyStatus, err := status.New(codes.NotFound, "not found").WithDetails(markerOnly, &errdetails.DebugInfo{Detail: "debug"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "nil error", err: nil, expected: false},
+		{name: "plain error", err: errors.New("foo"), expected: false},
+		{name: "code only", err: fmt.Errorf("bar: %w", WithCode(errors.New("foo"), codes.NotFound)), expected: false},
+		{name: "status without metadata", err: status.Error(codes.NotFound, "not found"), expected: false},
+		{name: "marker only", err: WithCode(markerOnlyStatus.Err(), codes.Internal), expected: false},
+		{name: "metadata", err: fmt.Errorf("bar: %w", WithMetadata(errors.New("foo"), "key", "value")), expected: true},
+		{name: "received metadata", err: roundTrip(t, WithMetadata(status.Error(codes.NotFound, "not found"), "key", "value")), expected: true},
+		{name: "joined branch", err: errors.Join(errors.New("foo"), WithCode(WithMetadata(errors.New("bar"), "key", 1), codes.Internal)), expected: true},
+		{name: "joined without metadata", err: errors.Join(errors.New("foo"), errors.New("bar")), expected: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, HasMetadata(tc.err))
+		})
+	}
+
+	// It allocates nothing for chains without gRPC statuses.
+	withMetadata := fmt.Errorf("bar: %w", WithCode(WithMetadata(errors.New("foo"), "key", "value"), codes.NotFound))
+	withoutMetadata := fmt.Errorf("bar: %w", WithCode(errors.New("foo"), codes.NotFound))
+	require.Zero(t, testing.AllocsPerRun(100, func() {
+		HasMetadata(withMetadata)
+		HasMetadata(withoutMetadata)
+	}))
+}
+
+func TestHasMetadataSharedBranches(t *testing.T) {
+	// Each level joins the previous one twice, so a search without tracking would take 2^depth steps.
+	err := errors.New("foo")
+	for range 40 {
+		err = errors.Join(err, WithCode(err, codes.Internal))
+	}
+	done := make(chan bool)
+	go func() { done <- HasMetadata(err) }()
+	select {
+	case found := <-done:
+		require.False(t, found)
+	case <-time.After(5 * time.Second):
+		t.Fatal("HasMetadata walks shared branches again")
+	}
+	require.True(t, HasMetadata(errors.Join(err, WithMetadata(err, "key", "value"))))
+}
+
+func TestHasMetadataLongChain(t *testing.T) {
+	// Chains are searched whatever their length, like GetMetadata does.
+	err := WithMetadata(errors.New("foo"), "key", "value")
+	for range 1000 {
+		err = fmt.Errorf("bar: %w", err)
+	}
+	require.Equal(t, []any{"key", "value"}, GetMetadata(err))
+	require.True(t, HasMetadata(err))
+	require.True(t, HasMetadata(errors.Join(errors.New("baz"), err)))
+}
+
+func TestGetMetadataLongChain(t *testing.T) {
+	// Long chains don't grow the stack with their length.
+	err := errors.New("foo")
+	for i := range 100_000 {
+		err = WithMetadata(err, "level", i)
+	}
+	metadata := GetMetadata(err)
+	require.Len(t, metadata, 200_000)
+	require.Equal(t, []any{"level", 0}, metadata[:2])
+	require.Equal(t, 99_999, GetMetadataMap(err)["level"])
+}
@@ -0,0 +1,271 @@
+package errors
+
+import "time"
+
+// Well-known metadata keys, reserved for the helpers of this package.
+//
+// The helpers store their values as plain metadata under these keys, so the values are logged like any other
+// metadata and survive gRPC round trips, the receiving side reading them back with the same accessors, e.g. TenantOf.
+// Unless a helper documents otherwise, the outermost value of a key wins, as in GetMetadataMap.
+const (
+	// tenantKey holds the tenant (or partition) the error relates to.
+	tenantKey = "tenant"
+	// attemptKey holds the attempt number of a retried operation.
+	attemptKey = "attempt"
+	// publicMessageKey holds the message safe to show to users.
+	publicMessageKey = "public_message"
+	// severityKey holds the severity of the error.
+	severityKey = "severity"
+	// operationKey holds the name of the operation that failed.
+	operationKey = "operation"
+	// operationMillisKey holds how long the failed operation took, in milliseconds.
+	operationMillisKey = "operation_ms"
+	// externalKey marks errors sanitized for external use.
+	externalKey = "external"
+	// expectedKey marks errors reporting expected outcomes.
+	expectedKey = "expected"
+	// retryAfterMillisKey holds how long to wait before retrying, in milliseconds.
+	retryAfterMillisKey = "retry_after_ms"
+	// logCountKey holds the number of times the error was logged.
+	logCountKey = "log_count"
+	// stepKey holds the index of the failed step of a Chain.
+	stepKey = "step"
+	// ctxErrKey holds the error of the context which was done when the error occurred.
+	ctxErrKey = "ctx_err"
+	// bytesDoneKey holds the number of bytes processed by a partial read or write.
+	bytesDoneKey = "bytes_done"
+	// bytesTotalKey holds the number of bytes a partial read or write was expected to process.
+	bytesTotalKey = "bytes_total"
+	// correlationKey holds the correlation IDs of the error, as a nested object.
+	correlationKey = "correlation"
+	// hintKey holds the actionable hint suggesting how to fix the error.
+	hintKey = "hint"
+	// panicStackKey holds the stack of the goroutine which panicked, set by RecoverToError.
+	panicStackKey = "panic_stack"
+	// goroutineKey holds the name of the goroutine started with Go which returned the error.
+	goroutineKey = "goroutine"
+	// goroutineStartedKey holds when the goroutine started with Go was started.
+	goroutineStartedKey = "goroutine_started"
+)
+
+// reservedKeys holds the keys set by the helpers of this package, which replace their previous values
+// on purpose, e.g. MarkLogged incrementing the log count, so ConflictingKeys doesn't report them.
+var reservedKeys = map[string]struct{}{
+	tenantKey:               {},
+	attemptKey:              {},
+	publicMessageKey:        {},
+	severityKey:             {},
+	operationKey:            {},
+	operationMillisKey:      {},
+	externalKey:             {},
+	expectedKey:             {},
+	retryAfterMillisKey:     {},
+	logCountKey:             {},
+	stepKey:                 {},
+	ctxErrKey:               {},
+	bytesDoneKey:            {},
+	bytesTotalKey:           {},
+	correlationKey:          {},
+	hintKey:                 {},
+	panicStackKey:           {},
+	goroutineKey:            {},
+	goroutineStartedKey:     {},
+	failedCountKey:          {},
+	totalCountKey:           {},
+	detailsTruncatedKey:     {},
+	metadataDroppedBytesKey: {},
+}
+
+// WithTenant returns the provided error wrapped with the tenant it relates to.
+// If the error already carries a tenant, it's returned unchanged, keeping the original tenant.
+func WithTenant(err error, tenant string) error {
+	if _, ok := TenantOf(err); ok {
+		return err
+	}
+	return WithMetadata(err, tenantKey, tenant)
+}
+
+// TenantOf returns the tenant attached to the error with WithTenant, if any.
+func TenantOf(err error) (string, bool) {
+	v, ok := lookup(err, tenantKey)
+	if !ok {
+		return "", false
+	}
+	tenant, ok := v.(string)
+	return tenant, ok
+}
+
+// WithAttempt returns the provided error wrapped with the attempt number of a retried operation.
+// The outermost attempt wins, so wrapping the error of each attempt in a retry loop reports the last one.
+// To keep the metadata of each failed attempt, join their errors, each tagged with its attempt,
+// and attach the context shared by the attempts once, with WithMetadataOnce, so retries don't duplicate it:
+//
+//	var errs []error
+//	for attempt := 1; attempt <= maxAttempts; attempt++ {
+//		err := op()
+//		if err == nil {
+//			return nil
+//		}
+//		errs = append(errs, errhelper.WithAttempt(err, attempt))
+//	}
+//	err := errhelper.WithAttempt(errors.Join(errs...), maxAttempts)
+//	return errhelper.WithMetadataOnce(err, "collection", name)
+//
+// The chain grows by a single layer per attempt, GetMetadataValues(err, "attempt") lists the attempts,
+// and the metadata of the last attempt takes precedence in GetMetadataMap. To only report the last attempt,
+// wrap only the error that is finally returned instead.
+func WithAttempt(err error, attempt int) error {
+	return WithMetadata(err, attemptKey, attempt)
+}
+
+// AttemptOf returns the attempt number attached to the error with WithAttempt, if any.
+func AttemptOf(err error) (int, bool) {
+	v, ok := lookup(err, attemptKey)
+	if !ok {
+		return 0, false
+	}
+	attempt, ok := toInt64(v)
+	return int(attempt), ok
+}
+
+// WithOperation returns the provided error wrapped with the name of the operation that failed
+// and how long it took, for SLO tracking. The elapsed time is stored as an integer number of milliseconds,
+// so OperationOf returns it truncated to the millisecond.
+func WithOperation(err error, name string, elapsed time.Duration) error {
+	return WithMetadata(err, operationKey, name, operationMillisKey, elapsed.Milliseconds())
+}
+
+// OperationOf returns the operation name and elapsed time attached to the error with WithOperation, if any.
+func OperationOf(err error) (name string, elapsed time.Duration, ok bool) {
+	v, ok := lookup(err, operationKey)
+	if !ok {
+		return "", 0, false
+	}
+	if name, ok = v.(string); !ok {
+		return "", 0, false
+	}
+	v, ok = lookup(err, operationMillisKey)
+	if !ok {
+		return "", 0, false
+	}
+	millis, ok := toInt64(v)
+	if !ok {
+		return "", 0, false
+	}
+	return name, time.Duration(millis) * time.Millisecond, true
+}
+
+// WithProgress returns the provided error wrapped with the progress of the partial read or write that failed,
+// so callers can decide whether the partial result is usable. It returns nil for a nil error.
+func WithProgress(err error, done, total int64) error {
+	return WithMetadata(err, bytesDoneKey, done, bytesTotalKey, total)
+}
+
+// ProgressOf returns the numbers of bytes processed and expected attached to the error with WithProgress, if any.
+func ProgressOf(err error) (done, total int64, ok bool) {
+	v, ok := lookup(err, bytesDoneKey)
+	if !ok {
+		return 0, 0, false
+	}
+	if done, ok = toInt64(v); !ok {
+		return 0, 0, false
+	}
+	v, ok = lookup(err, bytesTotalKey)
+	if !ok {
+		return 0, 0, false
+	}
+	if total, ok = toInt64(v); !ok {
+		return 0, 0, false
+	}
+	return done, total, true
+}
+
+// WithHint returns the provided error wrapped with an actionable hint for developers, suggesting how to fix it,
+// e.g. "check that the collection exists", so CLI and API clients can surface it. The outermost hint wins,
+// as outer layers know more about what the caller tried to do. It returns the error unchanged for an empty hint,
+// and nil for a nil error.
+func WithHint(err error, hint string) error {
+	if hint == "" {
+		return err
+	}
+	return WithMetadata(err, hintKey, hint)
+}
+
+// HintOf returns the hint attached to the error with WithHint, if any.
+func HintOf(err error) (string, bool) {
+	v, ok := lookup(err, hintKey)
+	if !ok {
+		return "", false
+	}
+	hint, ok := v.(string)
+	return hint, ok
+}
+
+// lookup returns the value of the key in the error metadata,
+// following the same precedence as GetMetadataMap.
+func lookup(err error, key string) (any, bool) {
+	for k, v := range All(err) {
+		if k == key {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// toInt64 converts an integer metadata value to int64.
+// Numbers received over gRPC are decoded as float64, so integral floats are accepted as well.
+func toInt64(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint:
+		return int64(n), true
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint64:
+		return int64(n), true
+	case float32:
+		return int64(n), float32(int64(n)) == n
+	case float64:
+		return int64(n), float64(int64(n)) == n
+	default:
+		return 0, false
+	}
+}
+
+// MarkLogged returns the provided error wrapped with the number of times it was logged, incremented by one,
+// to find errors logged repeatedly, e.g. in tight loops. As errors are immutable, the provided error is not
+// modified: the returned error must be used in place of it for the count to accumulate, e.g.:
+//
+//	err = errhelper.MarkLogged(err)
+//	logger.Error("request failed", "error", err)
+//
+// For this reason, the log adapters don't mark the errors they log. It returns nil for a nil error.
+func MarkLogged(err error) error {
+	if err == nil {
+		return nil
+	}
+	return WithMetadata(err, logCountKey, LogCountOf(err)+1)
+}
+
+// LogCountOf returns the number of times the error was marked with MarkLogged, 0 if never.
+func LogCountOf(err error) int {
+	v, ok := lookup(err, logCountKey)
+	if !ok {
+		return 0
+	}
+	count, _ := toInt64(v)
+	return int(count)
+}
@@ -0,0 +1,167 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWithTenant(t *testing.T) {
+	require.NoError(t, WithTenant(nil, "tenant-a"))
+
+	_, ok := TenantOf(errors.New("foo"))
+	require.False(t, ok)
+
+	err := WithTenant(errors.New("foo"), "tenant-a")
+	tenant, ok := TenantOf(err)
+	require.True(t, ok)
+	require.Equal(t, "tenant-a", tenant)
+
+	// An existing tenant is not overwritten.
+	outer := WithTenant(fmt.Errorf("bar: %w", err), "tenant-b")
+	tenant, ok = TenantOf(outer)
+	require.True(t, ok)
+	require.Equal(t, "tenant-a", tenant)
+	require.Equal(t, []any{"tenant", "tenant-a"}, GetMetadata(outer))
+
+	// The tenant survives a gRPC round trip.
+	received := roundTrip(t, WithTenant(status.Error(codes.NotFound, "item not found"), "tenant-a"))
+	tenant, ok = TenantOf(received)
+	require.True(t, ok)
+	require.Equal(t, "tenant-a", tenant)
+}
+
+func TestWithAttempt(t *testing.T) {
+	require.NoError(t, WithAttempt(nil, 1))
+
+	_, ok := AttemptOf(errors.New("foo"))
+	require.False(t, ok)
+
+	err := errors.New("foo")
+	for attempt := 1; attempt <= 3; attempt++ {
+		err = WithAttempt(err, attempt)
+	}
+	attempt, ok := AttemptOf(err)
+	require.True(t, ok)
+	require.Equal(t, 3, attempt)
+
+	// The attempt is still an integer after a gRPC round trip.
+	received := roundTrip(t, WithAttempt(status.Error(codes.Unavailable, "unavailable"), 2))
+	attempt, ok = AttemptOf(received)
+	require.True(t, ok)
+	require.Equal(t, 2, attempt)
+
+	// A non-integer value is rejected.
+	_, ok = AttemptOf(WithMetadata(errors.New("foo"), "attempt", 1.5))
+	require.False(t, ok)
+}
+
+func TestWithAttemptRetryLoop(t *testing.T) {
+	var errs []error
+	for attempt := 1; attempt <= 3; attempt++ {
+		errs = append(errs, WithAttempt(WithMetadata(errors.New("unavailable"), "replica", attempt*10), attempt))
+	}
+	err := WithMetadataOnce(WithAttempt(errors.Join(errs...), 3), "collection", "a")
+	require.Same(t, err, WithMetadataOnce(err, "collection", "a"))
+
+	attempt, ok := AttemptOf(err)
+	require.True(t, ok)
+	require.Equal(t, 3, attempt)
+	require.Equal(t, []any{1, 2, 3, 3}, GetMetadataValues(err, "attempt"))
+	require.Equal(t, []any{10, 20, 30}, GetMetadataValues(err, "replica"))
+	require.Equal(t, map[string]any{"attempt": 3, "replica": 30, "collection": "a"}, GetMetadataMap(err))
+}
+
+func TestWithOperation(t *testing.T) {
+	require.NoError(t, WithOperation(nil, "search", time.Second))
+
+	_, _, ok := OperationOf(errors.New("foo"))
+	require.False(t, ok)
+
+	err := WithOperation(errors.New("foo"), "search", 1500*time.Microsecond)
+	name, elapsed, ok := OperationOf(err)
+	require.True(t, ok)
+	require.Equal(t, "search", name)
+	require.Equal(t, time.Millisecond, elapsed)
+	require.Equal(t, []any{"operation", "search", "operation_ms", int64(1)}, GetMetadata(err))
+
+	// The elapsed time survives a gRPC round trip.
+	received := roundTrip(t, WithOperation(status.Error(codes.Unavailable, "unavailable"), "upsert", 250*time.Millisecond))
+	name, elapsed, ok = OperationOf(received)
+	require.True(t, ok)
+	require.Equal(t, "upsert", name)
+	require.Equal(t, 250*time.Millisecond, elapsed)
+
+	// Both fields are required.
+	_, _, ok = OperationOf(WithMetadata(errors.New("foo"), "operation", "search"))
+	require.False(t, ok)
+}
+
+func TestWithProgress(t *testing.T) {
+	require.NoError(t, WithProgress(nil, 1, 2))
+
+	_, _, ok := ProgressOf(errors.New("foo"))
+	require.False(t, ok)
+
+	err := WithProgress(errors.New("short write"), 512, 1024)
+	done, total, ok := ProgressOf(fmt.Errorf("upload: %w", err))
+	require.True(t, ok)
+	require.Equal(t, int64(512), done)
+	require.Equal(t, int64(1024), total)
+	require.Equal(t, []any{"bytes_done", int64(512), "bytes_total", int64(1024)}, GetMetadata(err))
+
+	// The progress survives a gRPC round trip, as integers, and the outermost one wins.
+	received := roundTrip(t, WithProgress(WithProgress(status.Error(codes.Aborted, "aborted"), 1, 10), 1<<40, 1<<41))
+	require.Equal(t, int64(1<<40), GetMetadataMap(received)["bytes_done"])
+	done, total, ok = ProgressOf(received)
+	require.True(t, ok)
+	require.Equal(t, int64(1<<40), done)
+	require.Equal(t, int64(1<<41), total)
+
+	// Both fields are required.
+	_, _, ok = ProgressOf(WithMetadata(errors.New("foo"), "bytes_done", 1))
+	require.False(t, ok)
+}
+
+func TestWithHint(t *testing.T) {
+	require.NoError(t, WithHint(nil, "retry"))
+	base := errors.New("foo")
+	require.Equal(t, base, WithHint(base, ""))
+
+	_, ok := HintOf(base)
+	require.False(t, ok)
+
+	err := WithHint(status.Error(codes.NotFound, "collection not found"), "check that the collection exists")
+	hint, ok := HintOf(fmt.Errorf("search: %w", err))
+	require.True(t, ok)
+	require.Equal(t, "check that the collection exists", hint)
+
+	// The hint survives a gRPC round trip, and the outermost one wins.
+	received := roundTrip(t, WithHint(err, "create the collection first"))
+	hint, ok = HintOf(received)
+	require.True(t, ok)
+	require.Equal(t, "create the collection first", hint)
+
+	_, ok = HintOf(WithMetadata(base, "hint", 1))
+	require.False(t, ok)
+}
+
+func TestMarkLogged(t *testing.T) {
+	require.NoError(t, MarkLogged(nil))
+	require.Zero(t, LogCountOf(nil))
+
+	plain := errors.New("foo")
+	require.Zero(t, LogCountOf(plain))
+
+	err := MarkLogged(plain)
+	require.Equal(t, 1, LogCountOf(err))
+	require.Zero(t, LogCountOf(plain))
+	err = MarkLogged(fmt.Errorf("bar: %w", MarkLogged(err)))
+	require.Equal(t, 3, LogCountOf(err))
+	require.Equal(t, 3, LogCountOf(roundTrip(t, err)))
+}
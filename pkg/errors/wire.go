@@ -0,0 +1,226 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// maxExactFloatInt is the largest integer magnitude a float64 represents exactly.
+const maxExactFloatInt = 1 << 53
+
+// CheckWireSafe returns human-readable warnings about the metadata of the error that
+// won't be received unchanged on the other side of a gRPC call, such as integers too large
+// to be sent as floats, or custom types without an encoder sent as their string representation.
+// It only reports, the error is not modified. It returns no warnings for a nil error.
+// It also reports the details and the metadata keys GRPCStatus drops to honor the MaxDetails
+// and MaxMetadataBytes options, if configured.
+//
+// It is meant to be used in tests or debug endpoints, to find out which types need an encoder
+// registered with RegisterEncoder.
+func CheckWireSafe(err error) []string {
+	if err == nil {
+		return nil
+	}
+	metadata := GetMetadata(err)
+	// Only the outermost value of a key is sent, find it first.
+	lastIndex := make(map[string]int, len(metadata)/2)
+	for i := 0; i+1 < len(metadata); i += 2 {
+		if key, ok := metadata[i].(string); ok {
+			lastIndex[key] = i
+		}
+	}
+	var warnings []string
+	for i := 0; i+1 < len(metadata); i += 2 {
+		key, ok := metadata[i].(string)
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("key %v of type %T is not a string, the pair is not sent", metadata[i], metadata[i]))
+			continue
+		}
+		if lastIndex[key] != i {
+			warnings = append(warnings, fmt.Sprintf("key %q is set multiple times, only the outermost value is sent", key))
+			continue
+		}
+		value := metadata[i+1]
+		if value == "<missing>" {
+			warnings = append(warnings, fmt.Sprintf("key %q has no value, it is sent padded with %q", key, value))
+			continue
+		}
+		if issue := wireValueIssue(value); issue != "" {
+			warnings = append(warnings, fmt.Sprintf("key %q: %s", key, issue))
+		}
+	}
+	return append(warnings, budgetWarnings(err)...)
+}
+
+// budgetWarnings returns the warnings about the details and the metadata keys dropped by GRPCStatus
+// to honor the MaxDetails and MaxMetadataBytes options.
+func budgetWarnings(err error) []string {
+	o := getOptions()
+	if o.maxDetails <= 0 && o.maxMetadataBytes <= 0 {
+		return nil
+	}
+	wire := WireMetadata(err)
+	var warnings []string
+	if truncated, ok := wire[detailsTruncatedKey]; ok {
+		warnings = append(warnings, fmt.Sprintf("%v details of the status are dropped to honor MaxDetails", truncated))
+	}
+	if _, ok := wire[metadataDroppedBytesKey]; ok {
+		// The keys sent before the metadata is truncated, as computed by GRPCStatus.
+		sent := dedupMetadata(expandErrorValues(getScopedMetadata(err, o.statusScope)))
+		for _, key := range slices.Sorted(maps.Keys(sent)) {
+			if _, ok := wire[key]; !ok {
+				warnings = append(warnings, fmt.Sprintf("key %q is dropped to honor MaxMetadataBytes", key))
+			}
+		}
+	}
+	return warnings
+}
+
+// WireMetadata returns the metadata of the error exactly as it's sent over gRPC, i.e. the content
+// of the metadata struct GRPCStatus adds to the status details, without its marker, e.g. for contract tests.
+// Unlike GetMetadataMap, it reflects all the transformations done by GRPCStatus: keys that are not strings
+// are skipped, repeated keys deduplicated, values converted to their protobuf representation, the metadata
+// truncated according to the MaxDetails and MaxMetadataBytes options, and redacted if RedactGRPCStatus is set.
+// The values are the ones of structpb.Value.AsInterface, e.g. numbers are float64 and times strings,
+// before the type hints are applied by the receiver. It returns nil if no metadata is sent.
+func WireMetadata(err error) map[string]any {
+	if err == nil {
+		return nil
+	}
+	st, _ := GRPCStatusOf(err)
+	for _, detail := range st.Details() {
+		s, ok := detail.(*structpb.Struct)
+		if !ok || !isMetadataStruct(s) {
+			continue
+		}
+		metadata := make(map[string]any, len(s.GetFields()))
+		for key, value := range s.GetFields() {
+			if !isMetadataMarker(key) {
+				metadata[key] = value.AsInterface()
+			}
+		}
+		return metadata
+	}
+	return nil
+}
+
+// wireValueIssue describes how the value is altered when sent over the wire,
+// following the conversion done by toWireValue. It returns an empty string
+// if the value is received unchanged.
+func wireValueIssue(v any) string {
+	if v == nil {
+		return ""
+	}
+	if _, ok := v.(error); ok {
+		return fmt.Sprintf("error value of type %T is sent as its message, with its metadata under prefixed keys", v)
+	}
+	t := reflect.TypeOf(v)
+	if enc, ok := codecs.encoder(t); ok {
+		if _, err := enc(v); err != nil {
+			issue := fmt.Sprintf("encoder for type %T fails (%v), the default conversion is used", v, err)
+			if coercion := coercionIssue(v); coercion != "" {
+				issue += ": value " + coercion
+			}
+			return issue
+		}
+		if _, ok := codecs.decoder(typeName(t)); !ok {
+			return fmt.Sprintf("type %T has an encoder but no decoder, the value is received in its encoded form", v)
+		}
+		return ""
+	}
+	if _, ok := typeHints[t]; ok {
+		// The type is restored using a type hint, only large integers are altered.
+		if exceedsFloatPrecision(v) {
+			return fmt.Sprintf("value of type %T loses precision, as it's sent as a float64", v)
+		}
+		return ""
+	}
+	if issue := coercionIssue(v); issue != "" {
+		return "value " + issue
+	}
+	return ""
+}
+
+// coercionIssue describes how coerceWireValue alters the value, or returns an empty string
+// if the value is received unchanged.
+func coercionIssue(v any) string {
+	return nestedCoercionIssue(v, 0)
+}
+
+func nestedCoercionIssue(v any, depth int) string {
+	if _, err := structpb.NewValue(v); err == nil {
+		return nativeIssue(v)
+	}
+	if depth < maxWireDepth {
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Map:
+			if rv.Type().Key().Kind() == reflect.String {
+				m, ok := v.(map[string]any)
+				if !ok {
+					return fmt.Sprintf("of type %T is received as map[string]any", v)
+				}
+				for _, key := range slices.Sorted(maps.Keys(m)) {
+					if issue := nestedCoercionIssue(m[key], depth+1); issue != "" {
+						return fmt.Sprintf("field %q %s", key, issue)
+					}
+				}
+				return ""
+			}
+		case reflect.Slice, reflect.Array:
+			l, ok := v.([]any)
+			if !ok {
+				return fmt.Sprintf("of type %T is received as []any", v)
+			}
+			for i, elem := range l {
+				if issue := nestedCoercionIssue(elem, depth+1); issue != "" {
+					return fmt.Sprintf("element %d %s", i, issue)
+				}
+			}
+			return ""
+		}
+	}
+	return fmt.Sprintf("of type %T has no native representation and is sent as its string representation", v)
+}
+
+// nativeIssue describes how a value having a native protobuf representation is altered
+// by the conversion, or returns an empty string if the value is received unchanged.
+// Values nested in lists and maps are converted natively, without the registered encoders.
+func nativeIssue(v any) string {
+	switch v := v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		if exceedsFloatPrecision(v) {
+			return fmt.Sprintf("of type %T is received as float64 and loses precision", v)
+		}
+		return fmt.Sprintf("of type %T is received as float64", v)
+	case float32, json.Number:
+		return fmt.Sprintf("of type %T is received as float64", v)
+	case []byte:
+		return "of type []byte is received as a base64 encoded string"
+	case []any:
+		for i, elem := range v {
+			if issue := nativeIssue(elem); issue != "" {
+				return fmt.Sprintf("element %d %s", i, issue)
+			}
+		}
+	case map[string]any:
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			if issue := nativeIssue(v[key]); issue != "" {
+				return fmt.Sprintf("field %q %s", key, issue)
+			}
+		}
+	}
+	return ""
+}
+
+// exceedsFloatPrecision reports whether the value is an integer too large to be represented exactly by a float64.
+func exceedsFloatPrecision(v any) bool {
+	rv := reflect.ValueOf(v)
+	return rv.CanInt() && (rv.Int() > maxExactFloatInt || rv.Int() < -maxExactFloatInt) ||
+		rv.CanUint() && rv.Uint() > maxExactFloatInt
+}
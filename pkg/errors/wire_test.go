@@ -0,0 +1,156 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCheckWireSafe(t *testing.T) {
+	testCases := []struct {
+		name             string
+		err              error
+		expectedWarnings []string
+	}{
+		{
+			name: "nil error",
+			err:  nil,
+		},
+		{
+			name: "native values",
+			err: WithMetadata(errors.New("foo"),
+				"string", "value", "bool", true, "float", 1.5, "nil", nil,
+				"list", []any{"a", 1.5}, "map", map[string]any{"key": "value"}),
+		},
+		{
+			name: "types restored with a type hint",
+			err: WithMetadata(errors.New("foo"),
+				"count", 42, "ratio", float32(0.5), "at", time.Unix(0, 0), "took", time.Second, "raw", []byte("raw")),
+		},
+		{
+			name:             "large integer",
+			err:              WithMetadata(errors.New("foo"), "id", uint64(1<<60)),
+			expectedWarnings: []string{`key "id": value of type uint64 loses precision, as it's sent as a float64`},
+		},
+		{
+			name:             "integer nested in a list",
+			err:              WithMetadata(errors.New("foo"), "ids", []any{"a", int32(1)}),
+			expectedWarnings: []string{`key "ids": value element 1 of type int32 is received as float64`},
+		},
+		{
+			name:             "typed map",
+			err:              WithMetadata(errors.New("foo"), "labels", map[string]string{"key": "value"}),
+			expectedWarnings: []string{`key "labels": value of type map[string]string is received as map[string]any`},
+		},
+		{
+			name:             "typed slice nested in a map",
+			err:              WithMetadata(errors.New("foo"), "details", map[string]any{"ids": []string{"a"}}),
+			expectedWarnings: []string{`key "details": value field "ids" of type []string is received as []any`},
+		},
+		{
+			name:             "custom type",
+			err:              WithMetadata(errors.New("foo"), "price", money{cents: 100, currency: "EUR"}),
+			expectedWarnings: []string{`key "price": value of type errors.money has no native representation and is sent as its string representation`},
+		},
+		{
+			name:             "error value",
+			err:              WithMetadata(errors.New("foo"), "cause", errors.New("bar")),
+			expectedWarnings: []string{`key "cause": error value of type *errors.errorString is sent as its message, with its metadata under prefixed keys`},
+		},
+		{
+			name:             "non-string key",
+			err:              WithMetadata(errors.New("foo"), 1, "value"),
+			expectedWarnings: []string{`key 1 of type int is not a string, the pair is not sent`},
+		},
+		{
+			name:             "missing value",
+			err:              WithMetadata(errors.New("foo"), "key"),
+			expectedWarnings: []string{`key "key" has no value, it is sent padded with "<missing>"`},
+		},
+		{
+			name:             "shadowed key",
+			err:              WithMetadata(WithMetadata(errors.New("foo"), "key", "inner"), "key", "outer"),
+			expectedWarnings: []string{`key "key" is set multiple times, only the outermost value is sent`},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expectedWarnings, CheckWireSafe(tc.err))
+		})
+	}
+}
+
+func TestCheckWireSafeWithCodecs(t *testing.T) {
+	price := money{cents: 100, currency: "EUR"}
+	err := WithMetadata(errors.New("foo"), "price", price)
+
+	registerMoneyCodec(t)
+	require.Empty(t, CheckWireSafe(err))
+
+	codecs.mu.Lock()
+	delete(codecs.decoders, typeName(reflect.TypeOf(price)))
+	codecs.mu.Unlock()
+	require.Equal(t, []string{`key "price": type errors.money has an encoder but no decoder, the value is received in its encoded form`}, CheckWireSafe(err))
+
+	RegisterEncoder(reflect.TypeOf(price), func(any) (any, error) {
+		return nil, errors.New("boom")
+	})
+	require.Equal(t, []string{`key "price": encoder for type errors.money fails (boom), the default conversion is used: ` +
+		`value of type errors.money has no native representation and is sent as its string representation`}, CheckWireSafe(err))
+}
+
+func TestCheckWireSafeBudget(t *testing.T) {
+	t.Cleanup(func() { Configure() })
+	st, err := status.New(codes.Internal, "boom").WithDetails(
+		&errdetails.DebugInfo{Detail: "debug"}, &errdetails.Help{}, &errdetails.RequestInfo{RequestId: "r1"})
+	require.NoError(t, err)
+	wrapped := WithMetadata(st.Err(), "request_id", "r1", "payload", strings.Repeat("x", 1000))
+
+	Configure(MaxDetails(3), MaxMetadataBytes(250))
+	require.Equal(t, []string{
+		"2 details of the status are dropped to honor MaxDetails",
+		`key "payload" is dropped to honor MaxMetadataBytes`,
+	}, CheckWireSafe(wrapped))
+
+	// Nothing is dropped without the options.
+	Configure()
+	require.Empty(t, CheckWireSafe(wrapped))
+}
+
+func TestWireMetadata(t *testing.T) {
+	require.Nil(t, WireMetadata(nil))
+	require.Nil(t, WireMetadata(errors.New("foo")))
+	require.Nil(t, WireMetadata(WithCode(errors.New("foo"), codes.NotFound)))
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	err := WithMetadata(
+		WithMetadata(errors.New("foo"), "key", "inner", 42, "not a string key"),
+		"key", "outer", "count", 3, "at", at, "tags", []string{"a"}, "elapsed", time.Second,
+	)
+	expected := map[string]any{
+		"key":     "outer",
+		"count":   float64(3),
+		"at":      "2024-01-02T03:04:05Z",
+		"tags":    []any{"a"},
+		"elapsed": "1s",
+	}
+	require.Equal(t, expected, WireMetadata(err))
+	require.Equal(t, expected, WireMetadata(fmt.Errorf("bar: %w", err)))
+	// The received metadata is sent back unchanged.
+	require.Equal(t, expected, WireMetadata(roundTrip(t, WithCode(err, codes.Internal))))
+
+	// The metadata is truncated like GRPCStatus does.
+	Configure(MaxMetadataBytes(50))
+	t.Cleanup(func() { Configure() })
+	wire := WireMetadata(err)
+	require.Contains(t, wire, metadataDroppedBytesKey)
+	require.Less(t, len(wire), len(expected))
+}
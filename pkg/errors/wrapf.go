@@ -0,0 +1,51 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/status"
+)
+
+// wrapfError is an error created by Wrapf, prefixing the message of the error it wraps.
+type wrapfError struct {
+	msg string
+	err error
+}
+
+// Wrapf formats an error like fmt.Errorf, with a %w verb wrapping another error.
+// Unlike the error returned by fmt.Errorf, the returned error implements GRPCStatus,
+// so the gRPC status of the wrapped error, including its code and details, survives
+// message wrapping at the top of the chain, e.g. when returned from a gRPC handler.
+// The message of the status is the message of the returned error, as status.FromError does.
+// If the format has no %w verb, or more than one, the error returned by fmt.Errorf is returned as is.
+func Wrapf(format string, args ...any) error {
+	err := fmt.Errorf(format, args...)
+	wrapped := errors.Unwrap(err)
+	if wrapped == nil {
+		return err
+	}
+	return &wrapfError{
+		msg: err.Error(),
+		err: wrapped,
+	}
+}
+
+// Error returns the formatted message.
+func (w *wrapfError) Error() string {
+	return w.msg
+}
+
+// Unwrap returns the error wrapped with the %w verb.
+func (w *wrapfError) Unwrap() error {
+	return w.err
+}
+
+// GRPCStatus returns the gRPC status of the wrapped error, as returned by GRPCStatusOf,
+// with the formatted message.
+func (w *wrapfError) GRPCStatus() *status.Status {
+	st, _ := GRPCStatusOf(w.err)
+	stProto := st.Proto()
+	stProto.Message = w.msg
+	return status.FromProto(stProto)
+}
@@ -0,0 +1,62 @@
+package errors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWrapf(t *testing.T) {
+	grpcErr := status.Error(codes.NotFound, "item not found")
+	err := Wrapf("failed to get item %d: %w", 42, WithMetadata(grpcErr, "key", "value"))
+
+	require.EqualError(t, err, "failed to get item 42: rpc error: code = NotFound desc = item not found")
+	require.ErrorIs(t, err, grpcErr)
+	require.Equal(t, codes.NotFound, CodeOf(err))
+
+	// The status is exposed at the top of the chain, with the formatted message.
+	s, ok := err.(interface{ GRPCStatus() *status.Status }) // nolint: errorlint // the status must be exposed by the top of the chain
+	require.True(t, ok)
+	st := s.GRPCStatus()
+	require.Equal(t, codes.NotFound, st.Code())
+	require.Equal(t, err.Error(), st.Message())
+
+	// The metadata survives a gRPC round trip.
+	require.Equal(t, []any{"key", "value"}, GetMetadata(roundTrip(t, err)))
+}
+
+func TestWrapfWithoutStatus(t *testing.T) {
+	err := Wrapf("context: %w", errors.New("foo"))
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.Unknown, st.Code())
+	require.Equal(t, "context: foo", st.Message())
+
+	// The synthesized status doesn't count as a code set on the chain.
+	_, genuine := GRPCStatusOf(err)
+	require.False(t, genuine)
+	require.Equal(t, codes.NotFound, CodeOf(WithCodeIfUnset(err, codes.NotFound)))
+
+	// A code set below is preserved, and metadata above is attached.
+	err = WithMetadata(Wrapf("context: %w", WithCode(errors.New("foo"), codes.Internal)), "key", "value")
+	received := roundTrip(t, err)
+	require.Equal(t, codes.Internal, CodeOf(received))
+	require.Equal(t, []any{"key", "value"}, GetMetadata(received))
+}
+
+func TestWrapfWithoutWrapVerb(t *testing.T) {
+	err := Wrapf("no wrapping: %v", errors.New("foo"))
+	require.EqualError(t, err, "no wrapping: foo")
+	require.NoError(t, errors.Unwrap(err))
+
+	fooError, barError := errors.New("foo"), errors.New("bar")
+	err = Wrapf("%w and %w", fooError, barError)
+	require.EqualError(t, err, "foo and bar")
+	require.ErrorIs(t, err, fooError)
+	require.ErrorIs(t, err, barError)
+	require.Equal(t, codes.Unknown, CodeOf(err))
+}